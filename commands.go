@@ -0,0 +1,1467 @@
+package main
+
+import (
+	incrementalrun "billionRowChallenge/incrementalRun"
+	lineserver "billionRowChallenge/lineServer"
+	multireader "billionRowChallenge/multiReader"
+	outlierscan "billionRowChallenge/outlierScan"
+	"billionRowChallenge/output"
+	quantilesketch "billionRowChallenge/quantileSketch"
+	resultdiff "billionRowChallenge/resultDiff"
+	resultsnapshot "billionRowChallenge/resultSnapshot"
+	rowindex "billionRowChallenge/rowIndex"
+	spooldaemon "billionRowChallenge/spoolDaemon"
+	spreadstatistics "billionRowChallenge/spreadStatistics"
+	stationcardinality "billionRowChallenge/stationCardinality"
+	stationhistograms "billionRowChallenge/stationHistograms"
+	stationmetadata "billionRowChallenge/stationMetadata"
+	tarreader "billionRowChallenge/tarReader"
+	temperaturecounts "billionRowChallenge/temperatureCounts"
+	timebuckets "billionRowChallenge/timeBuckets"
+	"billionRowChallenge/utilities"
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"math"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// runCommand - Handles the sub-commands passed on the command line. Running without any arguments keeps the
+// original challenge run within `main`.
+func runCommand(arguments []string) {
+
+	switch arguments[0] {
+	case "aggregate":
+		aggregateCommand(arguments[1:])
+	case "diff":
+		diffCommand(arguments[1:])
+	case "incremental":
+		incrementalCommand(arguments[1:])
+	case "index":
+		indexCommand(arguments[1:])
+	case "inspect":
+		inspectCommand(arguments[1:])
+	case "merge":
+		mergeCommand(arguments[1:])
+	case "report":
+		reportCommand(arguments[1:])
+	case "outliers":
+		outliersCommand(arguments[1:])
+	case "rollup":
+		rollupCommand(arguments[1:])
+	case "rows":
+		rowsCommand(arguments[1:])
+	case "serve":
+		serveCommand(arguments[1:])
+	case "spool":
+		spoolCommand(arguments[1:])
+	case "tar":
+		tarCommand(arguments[1:])
+	case "timeseries":
+		timeseriesCommand(arguments[1:])
+	case "top":
+		topCommand(arguments[1:])
+	default:
+		panic(fmt.Sprintf(">>> - unknown command %q", arguments[0]))
+	}
+}
+
+// diffCommand - `diff [-tolerance 0.0] <expected> <actual>`
+// Compares two sets of results, each either a snapshot or a file holding the canonical output. Lists the missing and
+// extra stations along with every differing field, and exits with status 1 when anything differs.
+func diffCommand(arguments []string) {
+
+	flags := flag.NewFlagSet("diff", flag.ExitOnError)
+	tolerance := flags.String("tolerance", "0.0", "largest difference in degrees still counted as a match, e.g. 0.1")
+	quiet := flags.Bool("quiet", false, "only set the exit status")
+	filenames := parseInterleaved(flags, arguments)
+
+	if len(filenames) != 2 {
+		panic(">>> - diff expects the expected and the actual results")
+	}
+
+	toleranceTenths, err := resultdiff.ParseTenths(*tolerance)
+	if err != nil || toleranceTenths < 0 {
+		panic(fmt.Sprintf(">>> - tolerance must be a positive temperature with one decimal place, got %q", *tolerance))
+	}
+
+	expected, err := resultdiff.Load(filenames[0])
+	if err != nil {
+		panic(err)
+	}
+	actual, err := resultdiff.Load(filenames[1])
+	if err != nil {
+		panic(err)
+	}
+
+	report := resultdiff.Compare(expected, actual, toleranceTenths)
+	if report.Matches() {
+		if !*quiet {
+			fmt.Printf("Results match across %v stations\n", len(expected))
+		}
+		return
+	}
+
+	if !*quiet {
+		fmt.Print(report)
+		fmt.Printf("%v missing, %v extra, %v differing fields\n", len(report.Missing), len(report.Extra), len(report.Differences))
+	}
+	os.Exit(1)
+}
+
+// incrementalCommand - `incremental [-state path] <measurements file>`
+// Only parses the bytes appended since the last run and merges them into the saved results.
+func incrementalCommand(arguments []string) {
+
+	flags := flag.NewFlagSet("incremental", flag.ExitOnError)
+	statePath := flags.String("state", "", "where the saved state lives (defaults to <file>.state)")
+	resultFlags := addResultFlags(flags)
+	flags.Parse(arguments)
+	resultFlags.check()
+	startedAt := time.Now()
+
+	if flags.NArg() != 1 {
+		panic(">>> - incremental expects a single measurements file")
+	}
+	filename := flags.Arg(0)
+
+	if *statePath == "" {
+		*statePath = incrementalrun.StatePath(filename)
+	}
+
+	outputMap, summary, err := incrementalrun.Run(filename, *statePath)
+	if err != nil {
+		panic(err)
+	}
+
+	if summary.FullRun {
+		fmt.Fprintf(os.Stderr, "Full run (%v): parsed bytes %v-%v\n", summary.Reason, summary.StartOffset, summary.EndOffset)
+	} else {
+		fmt.Fprintf(os.Stderr, "Incremental run: parsed bytes %v-%v\n", summary.StartOffset, summary.EndOffset)
+	}
+
+	resultFlags.printResults("incremental", filename, startedAt, outputMap)
+}
+
+// aggregateCommand - `aggregate [-index path] [-percentiles] [-spread] [-histogram spec] [-sketch accuracy]
+// [-modes [-constant-share share]] <measurements file>`
+// Aggregates the whole file. When a usable row index exists, the sections are planned straight from the index.
+func aggregateCommand(arguments []string) {
+
+	flags := flag.NewFlagSet("aggregate", flag.ExitOnError)
+	indexPath := flags.String("index", "", "row index used to plan the sections (defaults to <file>.idx when present)")
+	statisticFlags := addStatisticFlags(flags)
+	resultFlags := addResultFlags(flags)
+	flags.Parse(arguments)
+	resultFlags.check()
+	statisticFlags.check()
+	startedAt := time.Now()
+
+	if flags.NArg() != 1 {
+		panic(">>> - aggregate expects a single measurements file")
+	}
+	filename := flags.Arg(0)
+
+	outputMap, collector, _ := aggregateMeasurements(filename, *indexPath, statisticFlags.newCollector())
+
+	resultFlags.printResultsWith("aggregate", filename, startedAt, outputMap, statisticFlags.collectorStatistics(collector))
+}
+
+// aggregateDetails - How a measurements file was split up to be read
+type aggregateDetails struct {
+	FileSize   int64
+	Sections   int
+	Boundaries []int64 // Where each section began, finishing with the file size, so a later pass can reuse them
+	UsedIndex  bool
+}
+
+// aggregateMeasurements - Aggregates the whole file across every CPU, planning the sections from the row index when a
+// usable one exists. Every reading is also handed to a collector when `newCollector` is not nil.
+func aggregateMeasurements(filename string, indexPath string, newCollector func() utilities.Collector) (
+	map[string]utilities.OutputValues, utilities.Collector, aggregateDetails) {
+
+	file, err := os.Open(filename)
+	if err != nil {
+		panic(err)
+	}
+	defer file.Close()
+
+	fileInfo, err := file.Stat()
+	if err != nil {
+		panic(err)
+	}
+
+	var details = aggregateDetails{FileSize: fileInfo.Size()}
+	boundaries, usedIndex := planFileSections(file, filename, indexPath, fileInfo.Size())
+	details.UsedIndex = usedIndex
+	details.Sections = len(boundaries) - 1
+	details.Boundaries = boundaries
+
+	outputMap, collector, _, err := multireader.AggregateSectionsCollecting(file, boundaries, newCollector)
+	if err != nil {
+		panic(err)
+	}
+
+	return outputMap, collector, details
+}
+
+// planFileSections - Splits the whole file into a section per CPU, straight from the row index when a usable one exists
+func planFileSections(file *os.File, filename string, indexPath string, fileSize int64) ([]int64, bool) {
+
+	if index, ok := loadUsableIndex(file, indexPath, filename); ok {
+		return index.PlanSections(fileSize, runtime.NumCPU()), true
+	}
+
+	boundaries, err := multireader.PlanSections(file, 0, fileSize, runtime.NumCPU())
+	if err != nil {
+		panic(err)
+	}
+	return boundaries, false
+}
+
+// indexCommand - `index [-block-mb N] [-o path] <measurements file>`
+// Builds the sidecar row index, or extends it when the file has only been appended to since it was built.
+func indexCommand(arguments []string) {
+
+	flags := flag.NewFlagSet("index", flag.ExitOnError)
+	blockMegabytes := flags.Int64("block-mb", rowindex.DefaultBlockSize/(1024*1024), "megabytes between each recorded split")
+	indexPath := flags.String("o", "", "where the index is written (defaults to <file>.idx)")
+	flags.Parse(arguments)
+
+	if flags.NArg() != 1 {
+		panic(">>> - index expects a single measurements file")
+	}
+	if *blockMegabytes < 1 {
+		panic(">>> - block-mb must be at least 1")
+	}
+	filename := flags.Arg(0)
+
+	if *indexPath == "" {
+		*indexPath = rowindex.IndexPath(filename)
+	}
+
+	file, err := os.Open(filename)
+	if err != nil {
+		panic(err)
+	}
+	defer file.Close()
+
+	blockSize := *blockMegabytes * 1024 * 1024
+
+	// Extend the existing index when possible, otherwise scan the whole file again
+	var index rowindex.RowIndex
+	if existing, ok := loadUsableIndex(file, *indexPath, filename); ok && existing.BlockSize == blockSize {
+		index, err = rowindex.Update(file, existing)
+	} else {
+		index, err = rowindex.Build(file, blockSize)
+	}
+	if err != nil {
+		panic(err)
+	}
+
+	if err = rowindex.Save(*indexPath, index); err != nil {
+		panic(err)
+	}
+
+	fmt.Fprintf(os.Stderr, "Indexed %v rows in %v blocks up to byte %v\n", index.TotalRows(), len(index.Blocks), index.IndexedEnd)
+}
+
+// inspectCommand - `inspect -cardinality [-precision 14] [-limit N] [-index path] <measurements file>`
+// Looks over a feed before committing to a full aggregation. With `-cardinality`, estimates the number of distinct
+// stations with a HyperLogLog over the station names alone, and exits with status 1 when the estimate passes `-limit`.
+func inspectCommand(arguments []string) {
+
+	flags := flag.NewFlagSet("inspect", flag.ExitOnError)
+	cardinality := flags.Bool("cardinality", false, "estimate the number of distinct stations")
+	precision := flags.Int("precision", stationcardinality.DefaultPrecision,
+		fmt.Sprintf("bits picking a HyperLogLog register, from %v to %v, using 2^precision bytes", stationcardinality.MinPrecision, stationcardinality.MaxPrecision))
+	limit := flags.Int("limit", 0, "exit with status 1 when the estimate is above this many stations (off when zero)")
+	indexPath := flags.String("index", "", "row index used to plan the sections (defaults to <file>.idx when present)")
+	flags.Parse(arguments)
+
+	if flags.NArg() != 1 {
+		panic(">>> - inspect expects a single measurements file")
+	}
+	if !*cardinality {
+		panic(">>> - inspect expects a mode, such as -cardinality")
+	}
+	filename := flags.Arg(0)
+
+	file, err := os.Open(filename)
+	if err != nil {
+		panic(err)
+	}
+	defer file.Close()
+
+	fileInfo, err := file.Stat()
+	if err != nil {
+		panic(err)
+	}
+
+	boundaries, _ := planFileSections(file, filename, *indexPath, fileInfo.Size())
+	sketch, rows, err := stationcardinality.CountStations(file, boundaries, *precision)
+	if err != nil {
+		panic(err)
+	}
+
+	estimate := int(math.Round(sketch.Estimate()))
+	fmt.Printf("~%v stations across %v rows (standard error %.2f%%)\n", estimate, rows, 100*sketch.StandardError())
+
+	if *limit > 0 && estimate > *limit {
+		fmt.Fprintf(os.Stderr, "Estimate of %v stations is above the limit of %v\n", estimate, *limit)
+		os.Exit(1)
+	}
+}
+
+// mergeCommand - `merge [-modes [-constant-share share]] [-o path] <snapshot> <snapshot> ...`
+// Combines the result snapshots of separate runs, such as regional shards, into a single set of results. Written as a
+// snapshot when `-o` is given without a `-format`, otherwise printed in the requested format.
+func mergeCommand(arguments []string) {
+
+	flags := flag.NewFlagSet("merge", flag.ExitOnError)
+	modeFlags := addModeFlags(flags)
+	resultFlags := addResultFlags(flags)
+	filenames := parseInterleaved(flags, arguments)
+	startedAt := time.Now()
+
+	if !flagWasSet(flags, "format") && *resultFlags.outputPath != "" {
+		*resultFlags.format = "snapshot"
+	}
+	resultFlags.check()
+	modeFlags.check()
+
+	if len(filenames) == 0 {
+		panic(">>> - merge expects at least one snapshot")
+	}
+
+	var snapshots = make([]resultsnapshot.Snapshot, len(filenames))
+	for index, filename := range filenames {
+		snapshot, err := resultsnapshot.Load(filename)
+		if err != nil {
+			panic(err)
+		}
+		snapshots[index] = snapshot
+	}
+
+	merged, err := resultsnapshot.Merge(snapshots...)
+	if err != nil {
+		panic(err)
+	}
+	fmt.Fprintf(os.Stderr, "Merged %v snapshots into %v stations\n", len(snapshots), len(merged.OutputMap))
+
+	// The merged snapshot is written as it stands, keeping its dialect and sections
+	if *resultFlags.format == "snapshot" {
+		if *resultFlags.outputPath != "" {
+			err = resultsnapshot.Save(*resultFlags.outputPath, merged)
+		} else {
+			_, err = os.Stdout.Write(resultsnapshot.Encode(merged))
+		}
+		if err != nil {
+			panic(err)
+		}
+		return
+	}
+
+	resultFlags.printResultsWith("merge", strings.Join(filenames, ","), startedAt, merged.OutputMap, modeFlags.snapshotStatistics(merged))
+}
+
+// timeseriesCommand - `timeseries [-bucket day] [-tz UTC] [-timestamp rfc3339] [-time-column 3] [-format csv] [-o path]
+// [-index path] <measurements file>`
+// Aggregates rows carrying a timestamp, `station;temperature;timestamp` by default, into a time series per station
+// with a min/mean/max/count/sum for every bucket. Rows that cannot be read are skipped and counted. Written as csv
+// unless another `-format` is asked for, out of csv, tsv, json, and ndjson.
+func timeseriesCommand(arguments []string) {
+
+	flags := flag.NewFlagSet("timeseries", flag.ExitOnError)
+	defaults := timebuckets.DefaultOptions()
+	bucket := flags.String("bucket", defaults.Bucket, "size of each bucket: "+strings.Join(timebuckets.BucketSizes, ", "))
+	timeZone := flags.String("tz", "UTC", "time zone the buckets begin within, such as Europe/London or Local")
+	timestampFormat := flags.String("timestamp", defaults.Format,
+		"how the timestamps are written: "+strings.Join(timebuckets.TimestampFormats, ", ")+", or layout:<go layout>")
+	timeColumn := flags.Int("time-column", defaults.TimeColumn, "column holding the timestamp, 2 or 3, with the temperature in the other")
+	indexPath := flags.String("index", "", "row index used to plan the sections (defaults to <file>.idx when present)")
+	resultFlags := addResultFlags(flags)
+	flags.Parse(arguments)
+	startedAt := time.Now()
+
+	if !flagWasSet(flags, "format") {
+		*resultFlags.format = "csv"
+	}
+	resultFlags.check()
+	switch *resultFlags.format {
+	case "csv", "tsv", "json", "ndjson":
+	default:
+		panic(fmt.Sprintf(">>> - timeseries cannot be written as %q, expected csv, tsv, json, or ndjson", *resultFlags.format))
+	}
+
+	if flags.NArg() != 1 {
+		panic(">>> - timeseries expects a single measurements file")
+	}
+	filename := flags.Arg(0)
+
+	location, err := time.LoadLocation(*timeZone)
+	if err != nil {
+		panic(err)
+	}
+	options := timebuckets.Options{Bucket: *bucket, Location: location, Format: *timestampFormat, TimeColumn: *timeColumn}
+	if err = options.Check(); err != nil {
+		panic(err)
+	}
+
+	file, err := os.Open(filename)
+	if err != nil {
+		panic(err)
+	}
+	defer file.Close()
+
+	fileInfo, err := file.Stat()
+	if err != nil {
+		panic(err)
+	}
+
+	boundaries, _ := planFileSections(file, filename, *indexPath, fileInfo.Size())
+	series, skipped, _, err := timebuckets.Aggregate(file, boundaries, options)
+	if err != nil {
+		panic(err)
+	}
+	if skipped > 0 {
+		fmt.Fprintf(os.Stderr, "Skipped %v rows that could not be read\n", skipped)
+	}
+	timeSeries := series.TimeSeries(location)
+
+	writer, closeOutput := resultFlags.createOutput()
+
+	switch *resultFlags.format {
+	case "csv", "tsv":
+		err = output.WriteTimeSeriesTable(writer, timeSeries, resultFlags.tableOptions())
+	case "json":
+		metadata := output.NewRunMetadata("timeseries", filename, startedAt, timeSeries.Totals())
+		err = output.WriteTimeSeriesJSON(writer, timeSeries, metadata, *bucket, location.String())
+	case "ndjson":
+		err = output.WriteTimeSeriesNDJSON(writer, timeSeries)
+	}
+	if err != nil {
+		panic(err)
+	}
+	closeOutput()
+}
+
+// rollupCommand - `rollup -metadata path [-level country] [-missing path] [-modes [-constant-share share]] [-index path]
+// <measurements file or snapshot>`
+// Joins the results of a fresh run, or of a saved snapshot, against the station metadata csv and rolls them up per
+// country or region, or keeps them per station along with the country, region, and coordinates of each, and any
+// statistics the snapshot carries. Stations missing from the metadata are counted under the unknown country and
+// reported on stderr, with the full list written to `-missing` when given.
+func rollupCommand(arguments []string) {
+
+	flags := flag.NewFlagSet("rollup", flag.ExitOnError)
+	metadataPath := flags.String("metadata", "", "station metadata csv, with station and country columns, and optionally region, latitude, and longitude")
+	level := flags.String("level", stationmetadata.LevelCountry, "level the results are rolled up to: "+strings.Join(stationmetadata.Levels, ", "))
+	missingPath := flags.String("missing", "", "write the name of every station missing from the metadata to this file, one per line")
+	indexPath := flags.String("index", "", "row index used to plan the sections (defaults to <file>.idx when present)")
+	modeFlags := addModeFlags(flags)
+	resultFlags := addResultFlags(flags)
+	flags.Parse(arguments)
+	resultFlags.check()
+	modeFlags.check()
+	startedAt := time.Now()
+
+	if flags.NArg() != 1 {
+		panic(">>> - rollup expects a single measurements file or snapshot")
+	}
+	if *metadataPath == "" {
+		panic(">>> - rollup needs the station metadata, given with -metadata")
+	}
+	filename := flags.Arg(0)
+
+	metadata, err := stationmetadata.Load(*metadataPath)
+	if err != nil {
+		panic(err)
+	}
+
+	outputMap, extra := modeFlags.loadResults(filename, *indexPath)
+
+	rollup, missing, err := metadata.Rollup(outputMap, *level)
+	if err != nil {
+		panic(err)
+	}
+
+	if len(missing) > 0 {
+		const listed = 10
+		fmt.Fprintf(os.Stderr, "%v of %v stations are missing from the metadata: %v", len(missing), len(outputMap),
+			strings.Join(missing[:min(len(missing), listed)], ", "))
+		if len(missing) > listed {
+			fmt.Fprint(os.Stderr, ", ...")
+		}
+		fmt.Fprintln(os.Stderr)
+	}
+	if *missingPath != "" {
+		var list strings.Builder
+		for _, station := range missing {
+			list.WriteString(station + "\n")
+		}
+		if err = os.WriteFile(*missingPath, []byte(list.String()), 0o644); err != nil {
+			panic(err)
+		}
+	}
+
+	// The statistics are drawn per station, so they only still apply while the stations are kept apart
+	if *level != stationmetadata.LevelStation {
+		if len(extra.columns) > 0 || len(extra.sections) > 0 {
+			fmt.Fprintf(os.Stderr, "Per station statistics are left out when rolling up to the %v level\n", *level)
+		}
+		resultFlags.printResults("rollup", filename, startedAt, rollup)
+		return
+	}
+
+	stationExtra := extraStatistics{columns: metadataColumns(metadata)}
+	stationExtra.add(extra)
+	resultFlags.printResultsWith("rollup", filename, startedAt, rollup, stationExtra)
+}
+
+// metadataColumns - The country, region, latitude, and longitude of every station, left empty for stations the
+// metadata does not hold, or does not place
+func metadataColumns(metadata stationmetadata.Metadata) []output.ExtraColumn {
+
+	value := func(format func(station stationmetadata.Station) string) func(station string) string {
+		return func(station string) string {
+			if stationMetadata, ok := metadata[station]; ok {
+				return format(stationMetadata)
+			}
+			return ""
+		}
+	}
+	coordinate := func(pick func(station stationmetadata.Station) float64) func(station string) string {
+		return value(func(station stationmetadata.Station) string {
+			if !station.HasCoordinates {
+				return ""
+			}
+			return strconv.FormatFloat(pick(station), 'f', -1, 64)
+		})
+	}
+
+	return []output.ExtraColumn{
+		{Name: "country", Value: value(func(station stationmetadata.Station) string { return station.Country }), Text: true},
+		{Name: "region", Value: value(func(station stationmetadata.Station) string { return station.Region }), Text: true},
+		{Name: "latitude", Value: coordinate(func(station stationmetadata.Station) float64 { return station.Latitude }), Text: true},
+		{Name: "longitude", Value: coordinate(func(station stationmetadata.Station) float64 { return station.Longitude }), Text: true},
+	}
+}
+
+// loadResults - The results held within a snapshot along with its statistics, or those of a fresh run over the
+// measurements file when it is not a snapshot, counting every value along the way when the modes are asked for
+func (modeFlags modeFlags) loadResults(filename string, indexPath string) (map[string]utilities.OutputValues, extraStatistics) {
+
+	isSnapshot, err := resultsnapshot.IsSnapshot(filename)
+	if err != nil {
+		panic(err)
+	}
+
+	if isSnapshot {
+		snapshot, err := resultsnapshot.Load(filename)
+		if err != nil {
+			panic(err)
+		}
+		return snapshot.OutputMap, modeFlags.snapshotStatistics(snapshot)
+	}
+
+	if !*modeFlags.modes {
+		outputMap, _, _ := aggregateMeasurements(filename, indexPath, nil)
+		return outputMap, extraStatistics{}
+	}
+	outputMap, collector, _ := aggregateMeasurements(filename, indexPath, temperaturecounts.NewCollector)
+	return outputMap, countStatistics(collector.(temperaturecounts.Counts), false, true, *modeFlags.constant)
+}
+
+// topCommand - `top [-by mean] [-n 20] [-asc] [-modes [-constant-share share]] [-index path]
+// <measurements file or snapshot>`
+// Ranks the stations of a fresh run, or of a saved snapshot, and prints only the leaders. Printed as a table in rank
+// order unless another `-format` is asked for; csv/tsv also keep the rank order, while the other formats hold the same
+// stations in their usual order.
+func topCommand(arguments []string) {
+
+	flags := flag.NewFlagSet("top", flag.ExitOnError)
+	by := flags.String("by", "mean", "key the stations are ranked by: "+strings.Join(output.TopKeys, ", "))
+	count := flags.Int("n", 20, "number of stations printed")
+	ascending := flags.Bool("asc", false, "rank the lowest first")
+	indexPath := flags.String("index", "", "row index used to plan the sections (defaults to <file>.idx when present)")
+	modeFlags := addModeFlags(flags)
+	resultFlags := addResultFlags(flags)
+	flags.Parse(arguments)
+	startedAt := time.Now()
+
+	if !flagWasSet(flags, "format") {
+		*resultFlags.format = "table"
+	}
+	*resultFlags.sortBy = *by
+	*resultFlags.descending = !*ascending
+	resultFlags.check()
+	modeFlags.check()
+
+	if flags.NArg() != 1 {
+		panic(">>> - top expects a single measurements file or snapshot")
+	}
+	filename := flags.Arg(0)
+
+	outputMap, extra := modeFlags.loadResults(filename, *indexPath)
+
+	leaders, err := output.TopStations(outputMap, *by, *count, *ascending)
+	if err != nil {
+		panic(err)
+	}
+
+	var topMap = make(map[string]utilities.OutputValues, len(leaders))
+	for _, station := range leaders {
+		topMap[station] = outputMap[station]
+	}
+
+	resultFlags.printResultsWith("top", filename, startedAt, topMap, extra)
+}
+
+// outliersCommand - `outliers [-method sigma|iqr] [-k factor] [-o path] [-index path] <measurements file>`
+// Flags the readings far from their station's usual values over two passes. The first pass works out each station's
+// fence, either `k` standard deviations around the mean or `k` interquartile ranges beyond the quartiles, and the
+// second pass writes every row outside its fence to `-o` along with its byte offset. The outlier count of each
+// station is printed to stdout.
+func outliersCommand(arguments []string) {
+
+	flags := flag.NewFlagSet("outliers", flag.ExitOnError)
+	method := flags.String("method", "sigma", "how the fences are drawn: sigma or iqr")
+	k := flags.Float64("k", 3, "standard deviations for sigma, or interquartile ranges for iqr (defaults to 1.5 with iqr)")
+	outliersPath := flags.String("o", "outliers.csv", "where the flagged rows are written")
+	indexPath := flags.String("index", "", "row index used to plan the sections (defaults to <file>.idx when present)")
+	flags.Parse(arguments)
+
+	if flags.NArg() != 1 {
+		panic(">>> - outliers expects a single measurements file")
+	}
+	if *method == "iqr" && !flagWasSet(flags, "k") {
+		*k = 1.5
+	}
+	if *k < 0 {
+		panic(">>> - k cannot be negative")
+	}
+	filename := flags.Arg(0)
+
+	var newCollector func() utilities.Collector
+	switch *method {
+	case "sigma":
+		newCollector = spreadstatistics.NewCollector
+	case "iqr":
+		newCollector = temperaturecounts.NewCollector
+	default:
+		panic(fmt.Sprintf(">>> - unknown outlier method %q, expected sigma or iqr", *method))
+	}
+
+	outputMap, collector, details := aggregateMeasurements(filename, *indexPath, newCollector)
+
+	var fences map[string]outlierscan.Fence
+	switch typedCollector := collector.(type) {
+	case spreadstatistics.Spread:
+		fences = outlierscan.SigmaFences(typedCollector, *k)
+	case temperaturecounts.Counts:
+		fences = outlierscan.IQRFences(typedCollector, *k)
+	}
+
+	file, err := os.Open(filename)
+	if err != nil {
+		panic(err)
+	}
+	defer file.Close()
+
+	outliersFile, err := os.Create(*outliersPath)
+	if err != nil {
+		panic(err)
+	}
+	report, err := outlierscan.Scan(file, details.Boundaries, fences, outliersFile)
+	if err != nil {
+		panic(err)
+	}
+	if err = outliersFile.Close(); err != nil {
+		panic(err)
+	}
+
+	if err = outlierscan.WriteCounts(os.Stdout, report, outputMap, fences); err != nil {
+		panic(err)
+	}
+	fmt.Fprintf(os.Stderr, "Flagged %v rows across %v of %v stations into %v\n",
+		report.Outliers, len(report.Counts), len(outputMap), *outliersPath)
+}
+
+// reportCommand - `report [-index path] [-o path] [-title text] <measurements file>`
+// Aggregates the whole file and writes a self-contained HTML report of the results.
+func reportCommand(arguments []string) {
+
+	flags := flag.NewFlagSet("report", flag.ExitOnError)
+	indexPath := flags.String("index", "", "row index used to plan the sections (defaults to <file>.idx when present)")
+	reportPath := flags.String("o", "report.html", "where the report is written")
+	title := flags.String("title", "Weather station report", "heading of the report")
+	flags.Parse(arguments)
+	startedAt := time.Now()
+
+	if flags.NArg() != 1 {
+		panic(">>> - report expects a single measurements file")
+	}
+	filename := flags.Arg(0)
+
+	outputMap, _, details := aggregateMeasurements(filename, *indexPath, nil)
+
+	options := output.ReportOptions{
+		Title: *title,
+		Run:   output.NewRunMetadata("report", filename, startedAt, outputMap),
+		Settings: []output.ReportSetting{
+			{Name: "File size", Value: strconv.FormatInt(details.FileSize, 10) + " bytes"},
+			{Name: "Sections", Value: strconv.Itoa(details.Sections)},
+			{Name: "CPUs", Value: strconv.Itoa(runtime.NumCPU())},
+			{Name: "Planned from row index", Value: strconv.FormatBool(details.UsedIndex)},
+		},
+		Warnings: output.ValidateResults(outputMap),
+	}
+
+	var report bytes.Buffer
+	if err := output.WriteHTMLReport(&report, outputMap, options); err != nil {
+		panic(err)
+	}
+	if err := os.WriteFile(*reportPath, report.Bytes(), 0o644); err != nil {
+		panic(err)
+	}
+	fmt.Fprintf(os.Stderr, "Wrote %v with %v stations and %v warnings\n", *reportPath, len(outputMap), len(options.Warnings))
+}
+
+// rowsCommand - `rows [-index path] <measurements file> <first row> <row count>`
+// Prints a range of rows, jumping straight to the block holding the first row.
+func rowsCommand(arguments []string) {
+
+	flags := flag.NewFlagSet("rows", flag.ExitOnError)
+	indexPath := flags.String("index", "", "row index to jump through (defaults to <file>.idx)")
+	flags.Parse(arguments)
+
+	if flags.NArg() != 3 {
+		panic(">>> - rows expects a measurements file, the first row, and the row count")
+	}
+	filename := flags.Arg(0)
+
+	firstRow, err := strconv.ParseInt(flags.Arg(1), 10, 64)
+	if err != nil {
+		panic(err)
+	}
+	rowCount, err := strconv.ParseInt(flags.Arg(2), 10, 64)
+	if err != nil {
+		panic(err)
+	}
+
+	file, err := os.Open(filename)
+	if err != nil {
+		panic(err)
+	}
+	defer file.Close()
+
+	index, ok := loadUsableIndex(file, *indexPath, filename)
+	if !ok {
+		panic(">>> - rows needs an up to date index, run `index` first")
+	}
+
+	if err = index.CopyRows(file, firstRow, rowCount, os.Stdout); err != nil {
+		panic(err)
+	}
+}
+
+// serveCommand - `serve [-listen address] [-metrics address]`
+// Accepts measurement lines over TCP until interrupted, then prints the final results.
+func serveCommand(arguments []string) {
+
+	flags := flag.NewFlagSet("serve", flag.ExitOnError)
+	listenAddress := flags.String("listen", "127.0.0.1:7070", "address the line protocol listens on")
+	metricsAddress := flags.String("metrics", "", "address serving the results at /metrics in the Prometheus format (off when empty)")
+	resultFlags := addResultFlags(flags)
+	flags.Parse(arguments)
+	resultFlags.check()
+	startedAt := time.Now()
+
+	listener, err := net.Listen("tcp", *listenAddress)
+	if err != nil {
+		panic(err)
+	}
+	fmt.Fprintf(os.Stderr, "Listening for measurements on %v\n", listener.Addr())
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	server := lineserver.NewServer()
+	startMetricsServer(ctx, *metricsAddress, func() (map[string]utilities.OutputValues, error) {
+		return server.Snapshot(), nil
+	})
+
+	if err = server.Serve(ctx, listener); err != nil {
+		panic(err)
+	}
+	server.Close()
+
+	if rejectedRows := server.RejectedRows(); rejectedRows > 0 {
+		fmt.Fprintf(os.Stderr, "Rejected %v malformed rows\n", rejectedRows)
+	}
+
+	resultFlags.printResults("serve", listener.Addr().String(), startedAt, server.Snapshot())
+}
+
+// spoolCommand - `spool [-interval 2s] [-settle 5s] [-once] [-metrics address] <spool directory>`
+// Watches `<spool directory>/incoming` and merges every new measurements file into the running result.
+func spoolCommand(arguments []string) {
+
+	flags := flag.NewFlagSet("spool", flag.ExitOnError)
+	interval := flags.Duration("interval", 2*time.Second, "how often the incoming directory is checked")
+	settleTime := flags.Duration("settle", 5*time.Second, "files modified more recently than this are left for the next check")
+	once := flags.Bool("once", false, "ingest whatever is waiting, print the running result, and exit")
+	metricsAddress := flags.String("metrics", "", "address serving the running result at /metrics in the Prometheus format (off when empty)")
+	resultFlags := addResultFlags(flags)
+	flags.Parse(arguments)
+	resultFlags.check()
+	startedAt := time.Now()
+
+	if flags.NArg() != 1 {
+		panic(">>> - spool expects a single spool directory")
+	}
+
+	spool := spooldaemon.Spool{Root: flags.Arg(0), SettleTime: *settleTime}
+	if err := spool.Prepare(); err != nil {
+		panic(err)
+	}
+
+	if *once {
+		if err := spool.Recover(); err != nil {
+			panic(err)
+		}
+		if _, err := spool.ProcessIncoming(); err != nil {
+			panic(err)
+		}
+	} else {
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+
+		startMetricsServer(ctx, *metricsAddress, func() (map[string]utilities.OutputValues, error) {
+			result, err := spool.LoadResult()
+			return result.OutputMap, err
+		})
+
+		if err := spool.Watch(ctx, *interval); err != nil {
+			panic(err)
+		}
+	}
+
+	result, err := spool.LoadResult()
+	if err != nil {
+		panic(err)
+	}
+
+	resultFlags.printResults("spool", spool.Root, startedAt, result.OutputMap)
+}
+
+// tarCommand - `tar [-match pattern] [-members] <archive>`
+// Aggregates every matching member of a `.tar` or `.tar.gz` archive without extracting it.
+func tarCommand(arguments []string) {
+
+	flags := flag.NewFlagSet("tar", flag.ExitOnError)
+	pattern := flags.String("match", tarreader.DefaultMemberPattern, "base name pattern members must match (empty matches everything)")
+	showMembers := flags.Bool("members", false, "print the results of every member before the combined results")
+	resultFlags := addResultFlags(flags)
+	flags.Parse(arguments)
+	resultFlags.check()
+	startedAt := time.Now()
+
+	if flags.NArg() != 1 {
+		panic(">>> - tar expects a single archive")
+	}
+	if *showMembers && *resultFlags.outputPath != "" {
+		panic(">>> - -members writes several sets of results, so it cannot be combined with -o")
+	}
+
+	archive, err := os.Open(flags.Arg(0))
+	if err != nil {
+		panic(err)
+	}
+	defer archive.Close()
+
+	result, err := tarreader.AggregateArchive(archive, *pattern)
+	if err != nil {
+		panic(err)
+	}
+
+	for _, member := range result.Members {
+		fmt.Fprintf(os.Stderr, "%v: %v bytes across %v stations\n", member.Name, member.Size, len(member.OutputMap))
+		if *showMembers {
+			resultFlags.printResults("tar", flags.Arg(0)+"/"+member.Name, startedAt, member.OutputMap)
+		}
+	}
+
+	resultFlags.printResults("tar", flags.Arg(0), startedAt, result.OutputMap)
+}
+
+// resultFlags - Flags shared by every command that prints a set of results
+type resultFlags struct {
+	format     *string
+	delimiter  *string
+	quoting    *string
+	noHeader   *bool
+	sortBy     *string
+	descending *bool
+	outputPath *string
+	nameWidth  *int
+	pageSize   *int
+	color      *string
+}
+
+// addResultFlags - Registers the result flags onto the command's flag set
+func addResultFlags(flags *flag.FlagSet) resultFlags {
+	return resultFlags{
+		format:     flags.String("format", "text", "how the results are written: text, json, ndjson, csv, tsv, table, arrow, parquet, prometheus, or snapshot"),
+		delimiter:  flags.String("delimiter", "", "csv/tsv: field delimiter, `\\t` for a tab (defaults to the format's own)"),
+		quoting:    flags.String("quote", "minimal", "csv/tsv: which fields are quoted: minimal, all, or none"),
+		noHeader:   flags.Bool("no-header", false, "csv/tsv: leave out the header row"),
+		sortBy:     flags.String("sort", "station", "csv/tsv/table: column the rows are sorted by: "+strings.Join(output.SortKeys, ", ")),
+		descending: flags.Bool("desc", false, "csv/tsv/table: sort in descending order"),
+		outputPath: flags.String("o", "", "file the results are written to (defaults to stdout)"),
+		nameWidth:  flags.Int("name-width", 30, "table: station names wider than this are truncated (0 keeps them whole)"),
+		pageSize:   flags.Int("page-size", 0, "table: rows per page, repeating the header on every page (0 is a single page)"),
+		color:      flags.String("color", "auto", "table: highlight extreme values: auto, always, or never"),
+	}
+}
+
+// check - Fails fast on bad result flags, before any of the file is read
+func (resultFlags resultFlags) check() {
+
+	switch *resultFlags.format {
+	case "text", "json", "ndjson", "arrow", "parquet", "prometheus", "snapshot":
+	case "csv", "tsv":
+		resultFlags.tableOptions()
+	case "table":
+		resultFlags.terminalTableOptions()
+	default:
+		panic(fmt.Sprintf(">>> - unknown format %q", *resultFlags.format))
+	}
+}
+
+// tableOptions - Builds the csv/tsv layout out of the flags
+func (resultFlags resultFlags) tableOptions() output.TableOptions {
+
+	options := output.CSVOptions()
+	if *resultFlags.format == "tsv" {
+		options = output.TSVOptions()
+	}
+
+	switch *resultFlags.delimiter {
+	case "":
+	case `\t`, "tab":
+		options.Delimiter = '\t'
+	default:
+		delimiter := []rune(*resultFlags.delimiter)
+		if len(delimiter) != 1 {
+			panic(fmt.Sprintf(">>> - delimiter must be a single character, got %q", *resultFlags.delimiter))
+		}
+		options.Delimiter = delimiter[0]
+	}
+
+	quoting, err := output.ParseQuoteMode(*resultFlags.quoting)
+	if err != nil {
+		panic(err)
+	}
+	if !slices.Contains(output.SortKeys, *resultFlags.sortBy) {
+		panic(fmt.Sprintf(">>> - unknown sort key %q", *resultFlags.sortBy))
+	}
+
+	options.Quoting = quoting
+	options.Header = !*resultFlags.noHeader
+	options.SortBy = *resultFlags.sortBy
+	options.Descending = *resultFlags.descending
+
+	return options
+}
+
+// terminalTableOptions - Builds the terminal table layout out of the flags. Colour is only used automatically when
+// writing straight to a terminal and `NO_COLOR` is not set.
+func (resultFlags resultFlags) terminalTableOptions() output.TerminalTableOptions {
+
+	if !slices.Contains(output.SortKeys, *resultFlags.sortBy) {
+		panic(fmt.Sprintf(">>> - unknown sort key %q", *resultFlags.sortBy))
+	}
+	if *resultFlags.nameWidth < 0 || *resultFlags.pageSize < 0 {
+		panic(">>> - name-width and page-size cannot be negative")
+	}
+
+	var color bool
+	switch *resultFlags.color {
+	case "always":
+		color = true
+	case "never":
+	case "auto":
+		if fileInfo, err := os.Stdout.Stat(); err == nil && *resultFlags.outputPath == "" {
+			color = fileInfo.Mode()&os.ModeCharDevice != 0 && os.Getenv("NO_COLOR") == ""
+		}
+	default:
+		panic(fmt.Sprintf(">>> - unknown color mode %q, expected auto, always, or never", *resultFlags.color))
+	}
+
+	return output.TerminalTableOptions{
+		SortBy:       *resultFlags.sortBy,
+		Descending:   *resultFlags.descending,
+		MaxNameWidth: *resultFlags.nameWidth,
+		PageSize:     *resultFlags.pageSize,
+		Color:        color,
+	}
+}
+
+// extraStatistics - Statistics kept on top of the output values, as columns for the readable formats and as sections
+// for snapshots
+type extraStatistics struct {
+	columns    []output.ExtraColumn
+	histograms *output.Histograms
+	sections   []resultsnapshot.Section
+}
+
+// statisticFlags - Flags turning on the extra statistics kept during a parse
+type statisticFlags struct {
+	percentiles *bool
+	spread      *bool
+	histogram   *string
+	sketch      *float64
+	modeFlags
+}
+
+// addStatisticFlags - Registers the statistic flags onto the command's flag set
+func addStatisticFlags(flags *flag.FlagSet) statisticFlags {
+	return statisticFlags{
+		percentiles: flags.Bool("percentiles", false, "also keep a count of every value per station, for the exact median, p90, p95, and p99"),
+		spread:      flags.Bool("spread", false, "also keep the sum of squares per station, for the variance and standard deviation"),
+		histogram:   flags.String("histogram", "", "also keep a histogram per station, with buckets of width:W[,FROM,TO], edges:A,B,..., or log:FACTOR[,START]"),
+		sketch:      flags.Float64("sketch", 0, "also keep a quantile sketch per station with this relative accuracy, e.g. 0.01, for approximate percentiles in bounded memory"),
+		modeFlags:   addModeFlags(flags),
+	}
+}
+
+// modeFlags - Flags asking for the mode columns, drawn from the counts of every value, either kept during a parse or
+// read back out of a snapshot
+type modeFlags struct {
+	modes    *bool
+	constant *float64
+}
+
+// addModeFlags - Registers the mode flags onto the command's flag set
+func addModeFlags(flags *flag.FlagSet) modeFlags {
+	return modeFlags{
+		modes:    flags.Bool("modes", false, "also keep a count of every value per station, for the mode, the distinct values, and whether the readings are constant"),
+		constant: flags.Float64("constant-share", 1, "with -modes, flag a station as constant once its mode is at least this share of its readings, above 0 and at most 1"),
+	}
+}
+
+// check - Makes sure the constant share is a share of the readings
+func (modeFlags modeFlags) check() {
+	if !(*modeFlags.constant > 0 && *modeFlags.constant <= 1) {
+		panic(fmt.Sprintf(">>> - -constant-share must be above 0 and at most 1, not %v", *modeFlags.constant))
+	}
+}
+
+// newCollector - Makes the collectors for every statistic that was turned on, or nil when there are none
+func (statisticFlags statisticFlags) newCollector() func() utilities.Collector {
+
+	var makers []func() utilities.Collector
+	if *statisticFlags.percentiles || *statisticFlags.modes {
+		makers = append(makers, temperaturecounts.NewCollector)
+	}
+	if *statisticFlags.spread {
+		makers = append(makers, spreadstatistics.NewCollector)
+	}
+	if *statisticFlags.histogram != "" {
+		buckets, err := stationhistograms.ParseSpec(*statisticFlags.histogram)
+		if err != nil {
+			panic(err)
+		}
+		makers = append(makers, stationhistograms.NewCollector(buckets))
+	}
+	if *statisticFlags.sketch != 0 {
+		mapping, err := quantilesketch.NewMapping(*statisticFlags.sketch)
+		if err != nil {
+			panic(err)
+		}
+		makers = append(makers, quantilesketch.NewCollector(mapping))
+	}
+
+	if len(makers) == 0 {
+		return nil
+	}
+	return func() utilities.Collector {
+		var collectors = make(utilities.Collectors, len(makers))
+		for index, maker := range makers {
+			collectors[index] = maker()
+		}
+		return collectors
+	}
+}
+
+// add - Appends the columns and sections of the other statistics
+func (extra *extraStatistics) add(other extraStatistics) {
+	extra.columns = append(extra.columns, other.columns...)
+	extra.sections = append(extra.sections, other.sections...)
+	if other.histograms != nil {
+		extra.histograms = other.histograms
+	}
+}
+
+// collectorStatistics - Turns whatever the collectors gathered into the columns that were asked for and snapshot
+// sections
+func (statisticFlags statisticFlags) collectorStatistics(collector utilities.Collector) extraStatistics {
+
+	var extra extraStatistics
+
+	switch typedCollector := collector.(type) {
+	case utilities.Collectors:
+		for _, each := range typedCollector {
+			extra.add(statisticFlags.collectorStatistics(each))
+		}
+	case temperaturecounts.Counts:
+		extra.add(countStatistics(typedCollector, *statisticFlags.percentiles, *statisticFlags.modes, *statisticFlags.constant))
+	case spreadstatistics.Spread:
+		extra.add(spreadColumns(typedCollector))
+	case stationhistograms.Histograms:
+		extra.add(histogramStatistics(typedCollector))
+	case quantilesketch.Sketches:
+		extra.add(sketchStatistics(typedCollector))
+	}
+
+	return extra
+}
+
+// countStatistics - The statistics drawn from the counts of every value, carried within a single snapshot section
+func countStatistics(counts temperaturecounts.Counts, percentiles bool, modes bool, constantShare float64) extraStatistics {
+
+	var extra = extraStatistics{sections: []resultsnapshot.Section{counts.Section()}}
+	if percentiles {
+		extra.columns = append(extra.columns, percentileColumns(counts)...)
+	}
+	if modes {
+		extra.columns = append(extra.columns, modeColumns(counts, constantShare)...)
+	}
+
+	return extra
+}
+
+// percentileColumns - The exact median, p90, p95, and p99 of every station
+func percentileColumns(counts temperaturecounts.Counts) []output.ExtraColumn {
+
+	var names = []string{"median", "p90", "p95", "p99"}
+	var percentiles = make(map[string][]int, len(counts))
+	for station, stationCounts := range counts {
+		percentiles[station] = stationCounts.Percentiles(50, 90, 95, 99)
+	}
+
+	var columns []output.ExtraColumn
+	for index, name := range names {
+		columns = append(columns, output.ExtraColumn{Name: name, Value: func(station string) string {
+			if values, ok := percentiles[station]; ok {
+				return output.FormatTemperature(values[index])
+			}
+			return ""
+		}})
+	}
+
+	return columns
+}
+
+// modeColumns - The most frequent reading of every station, how many times it was read, the number of distinct
+// readings, and 1 for a station whose readings look stuck: more than one reading, with the mode making up at least
+// `constantShare` of them. A share of 1 only flags stations that never read anything but a single value.
+func modeColumns(counts temperaturecounts.Counts, constantShare float64) []output.ExtraColumn {
+
+	type stationModes struct {
+		mode      int
+		modeCount int
+		distinct  int
+		constant  bool
+	}
+
+	var modes = make(map[string]stationModes, len(counts))
+	for station, stationCounts := range counts {
+		mode, modeCount := stationCounts.Mode()
+		modes[station] = stationModes{
+			mode:      mode,
+			modeCount: modeCount,
+			distinct:  stationCounts.Distinct(),
+			constant:  stationCounts.Total() > 1 && float64(modeCount) >= constantShare*float64(stationCounts.Total()),
+		}
+	}
+
+	value := func(format func(stationModes stationModes) string) func(station string) string {
+		return func(station string) string {
+			if stationModes, ok := modes[station]; ok {
+				return format(stationModes)
+			}
+			return ""
+		}
+	}
+
+	return []output.ExtraColumn{
+		{Name: "mode", Value: value(func(stationModes stationModes) string { return output.FormatTemperature(stationModes.mode) })},
+		{Name: "mode_count", Value: value(func(stationModes stationModes) string { return strconv.Itoa(stationModes.modeCount) })},
+		{Name: "distinct", Value: value(func(stationModes stationModes) string { return strconv.Itoa(stationModes.distinct) })},
+		{Name: "constant", Value: value(func(stationModes stationModes) string {
+			if stationModes.constant {
+				return "1"
+			}
+			return "0"
+		})},
+	}
+}
+
+// spreadColumns - The population variance and standard deviation of every station, to two decimal places
+func spreadColumns(spread spreadstatistics.Spread) extraStatistics {
+
+	value := func(measure func(spreadValues utilities.SpreadValues) float64) func(station string) string {
+		return func(station string) string {
+			if spreadValues, ok := spread[station]; ok {
+				return strconv.FormatFloat(measure(*spreadValues), 'f', 2, 64)
+			}
+			return ""
+		}
+	}
+
+	return extraStatistics{
+		columns: []output.ExtraColumn{
+			{Name: "variance", Value: value(utilities.SpreadValues.Variance)},
+			{Name: "stddev", Value: value(utilities.SpreadValues.StandardDeviation)},
+		},
+		sections: []resultsnapshot.Section{spread.Section()},
+	}
+}
+
+// histogramStatistics - The histogram of every station
+func histogramStatistics(histograms stationhistograms.Histograms) extraStatistics {
+	return extraStatistics{
+		histograms: &output.Histograms{
+			Edges: histograms.Buckets.Edges,
+			Counts: func(station string) []int {
+				return histograms.Stations[station]
+			},
+		},
+		sections: []resultsnapshot.Section{histograms.Section()},
+	}
+}
+
+// sketchStatistics - The approximate median, p90, p95, and p99 of every station, rounded to a tenth of a degree. The
+// columns are prefixed so they can sit alongside the exact percentiles.
+func sketchStatistics(sketches quantilesketch.Sketches) extraStatistics {
+
+	var names = []string{"sketch_median", "sketch_p90", "sketch_p95", "sketch_p99"}
+	var quantiles = make(map[string][]float64, len(sketches.Stations))
+	for station, sketch := range sketches.Stations {
+		quantiles[station] = sketch.Quantiles(50, 90, 95, 99)
+	}
+
+	var extra = extraStatistics{sections: []resultsnapshot.Section{sketches.Section()}}
+	for index, name := range names {
+		extra.columns = append(extra.columns, output.ExtraColumn{Name: name, Value: func(station string) string {
+			if values, ok := quantiles[station]; ok {
+				return output.FormatTemperature(int(math.Round(values[index])))
+			}
+			return ""
+		}})
+	}
+
+	return extra
+}
+
+// snapshotStatistics - Rebuilds the extra statistics out of the sections a snapshot carries. The counts always give
+// the percentiles, and also the mode columns when they are asked for.
+func (modeFlags modeFlags) snapshotStatistics(snapshot resultsnapshot.Snapshot) extraStatistics {
+
+	var extra extraStatistics
+
+	for _, section := range snapshot.Sections {
+		var err error
+
+		switch section.Tag {
+		case temperaturecounts.SnapshotSectionTag:
+			var counts temperaturecounts.Counts
+			if counts, err = temperaturecounts.FromSection(section.Data); err == nil {
+				extra.add(countStatistics(counts, true, *modeFlags.modes, *modeFlags.constant))
+			}
+		case spreadstatistics.SnapshotSectionTag:
+			var spread spreadstatistics.Spread
+			if spread, err = spreadstatistics.FromSection(section.Data); err == nil {
+				extra.add(spreadColumns(spread))
+			}
+		case stationhistograms.SnapshotSectionTag:
+			var histograms stationhistograms.Histograms
+			if histograms, err = stationhistograms.FromSection(section.Data); err == nil {
+				extra.add(histogramStatistics(histograms))
+			}
+		case quantilesketch.SnapshotSectionTag:
+			var sketches quantilesketch.Sketches
+			if sketches, err = quantilesketch.FromSection(section.Data); err == nil {
+				extra.add(sketchStatistics(sketches))
+			}
+		}
+
+		if err != nil {
+			panic(err)
+		}
+	}
+
+	return extra
+}
+
+// printResults - Writes the results out to stdout, or the `-o` file, in the requested format
+func (resultFlags resultFlags) printResults(command string, source string, startedAt time.Time, outputMap map[string]utilities.OutputValues) {
+	resultFlags.printResultsWith(command, source, startedAt, outputMap, extraStatistics{})
+}
+
+// printResultsWith - The same as `printResults`, along with the extra statistics. The canonical text output and the
+// binary columnar formats leave the extra statistics out, as does csv/tsv for histograms.
+func (resultFlags resultFlags) printResultsWith(command string, source string, startedAt time.Time,
+	outputMap map[string]utilities.OutputValues, extra extraStatistics) {
+
+	writer, closeOutput := resultFlags.createOutput()
+
+	var err error
+	extras := output.Extras{Columns: extra.columns, Histograms: extra.histograms}
+
+	switch *resultFlags.format {
+	case "json":
+		err = output.WriteJSON(writer, outputMap, output.NewRunMetadata(command, source, startedAt, outputMap), extras)
+	case "ndjson":
+		err = output.WriteNDJSON(writer, outputMap, extras)
+	case "csv", "tsv":
+		err = output.WriteTable(writer, outputMap, resultFlags.tableOptions(), extras)
+	case "table":
+		err = output.WriteTerminalTable(writer, outputMap, resultFlags.terminalTableOptions(), extras)
+	case "arrow":
+		err = output.WriteArrowStream(writer, outputMap)
+	case "parquet":
+		err = output.WriteParquet(writer, outputMap)
+	case "prometheus":
+		err = output.WritePrometheus(writer, outputMap, extras)
+	case "snapshot":
+		snapshot := resultsnapshot.New(outputMap)
+		snapshot.Sections = extra.sections
+		_, err = writer.Write(resultsnapshot.Encode(snapshot))
+	default:
+		_, err = fmt.Fprintln(writer, output.FormatResults(outputMap))
+	}
+
+	if err != nil {
+		panic(err)
+	}
+	closeOutput()
+}
+
+// createOutput - The writer the results go to, either stdout or the `-o` file, along with the function to call once
+// everything is written. Closing the file is checked, as a failed close can lose the end of the results.
+func (resultFlags resultFlags) createOutput() (io.Writer, func()) {
+
+	if *resultFlags.outputPath == "" {
+		return os.Stdout, func() {}
+	}
+
+	file, err := os.Create(*resultFlags.outputPath)
+	if err != nil {
+		panic(err)
+	}
+	return file, func() {
+		if err := file.Close(); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// startMetricsServer - Serves the snapshot at `/metrics` on the address until the context is cancelled. Does nothing
+// when the address is empty.
+func startMetricsServer(ctx context.Context, address string, snapshot func() (map[string]utilities.OutputValues, error)) {
+
+	if address == "" {
+		return
+	}
+
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		panic(err)
+	}
+	fmt.Fprintf(os.Stderr, "Serving metrics on http://%v/metrics\n", listener.Addr())
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", output.MetricsHandler(snapshot))
+	metricsServer := &http.Server{Handler: mux}
+
+	context.AfterFunc(ctx, func() {
+		metricsServer.Close()
+	})
+
+	go func() {
+		if err := metricsServer.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			panic(err)
+		}
+	}()
+}
+
+// parseInterleaved - Parses the flags wherever they sit among the positional arguments, so `merge a b -o c` works the
+// same as `merge -o c a b`. Returns the positional arguments in order.
+func parseInterleaved(flags *flag.FlagSet, arguments []string) []string {
+
+	var positional []string
+	for {
+		flags.Parse(arguments)
+		if flags.NArg() == 0 {
+			return positional
+		}
+		positional = append(positional, flags.Arg(0))
+		arguments = flags.Args()[1:]
+	}
+}
+
+// flagWasSet - Whether the flag was given on the command line, rather than left at its default
+func flagWasSet(flags *flag.FlagSet, name string) bool {
+
+	var set bool
+	flags.Visit(func(visited *flag.Flag) {
+		if visited.Name == name {
+			set = true
+		}
+	})
+
+	return set
+}
+
+// loadUsableIndex - Loads the row index for the file and checks it still describes the file. Returns false when no
+// index exists or it is out of date, so the caller can fall back to scanning.
+func loadUsableIndex(file *os.File, indexPath string, filename string) (rowindex.RowIndex, bool) {
+
+	if indexPath == "" {
+		indexPath = rowindex.IndexPath(filename)
+	}
+
+	index, err := rowindex.Load(indexPath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return index, false
+	} else if err != nil {
+		panic(err)
+	}
+
+	reason, err := index.Check(file)
+	if err != nil {
+		panic(err)
+	}
+	if reason != "" {
+		fmt.Fprintf(os.Stderr, "Ignoring row index %v: %v\n", indexPath, reason)
+		return index, false
+	}
+
+	return index, true
+}
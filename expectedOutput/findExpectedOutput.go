@@ -4,6 +4,7 @@ import (
 	"bufio"
 	"fmt"
 	"log"
+	"math"
 	"os"
 	"slices"
 	"strconv"
@@ -53,13 +54,15 @@ func CalculateExpectedOutput(filename string) string {
 
 	slices.Sort(keyMap)
 
+	// Rounds the mean half up to the nearest tenth, following the challenge rules, so the answer can be compared byte
+	// for byte. Kept apart from the output package so the two can check one another.
 	answer := "{"
 	for _, key := range keyMap {
 		answer += fmt.Sprintf(
 			"%v=%.1f/%.1f/%.1f, ",
 			key,
 			float64(cityTemperatures[key].minTemp)/10,
-			float64(cityTemperatures[key].runningTotal)/float64(cityTemperatures[key].count)/10,
+			math.Floor(float64(cityTemperatures[key].runningTotal)/float64(cityTemperatures[key].count)+0.5)/10,
 			float64(cityTemperatures[key].maxTemp)/10,
 		)
 	}
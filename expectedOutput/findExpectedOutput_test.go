@@ -0,0 +1,44 @@
+package expectedOutput
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCalculateExpectedOutputRounding(t *testing.T) {
+
+	// Means sitting exactly on a half, or just below zero. `%.1f` rounds the exact binary value half to even, so it
+	// printed 0.25 as 0.2 and -0.03 as -0.0, where the challenge rounds half up and never prints a negative zero.
+	tests := []struct {
+		name         string
+		temperatures []string
+		expected     string
+	}{
+		{"tie rounds up", []string{"0.2", "0.3"}, "0.2/0.3/0.3"},
+		{"negative tie rounds up", []string{"-0.2", "-0.3"}, "-0.3/-0.2/-0.2"},
+		{"larger tie rounds up", []string{"12.3", "12.4"}, "12.3/12.4/12.4"},
+		{"just below zero", []string{"-0.1", "0.0", "0.0"}, "-0.1/0.0/0.0"},
+		{"negative half rounds to zero", []string{"-0.1", "0.0"}, "-0.1/0.0/0.0"},
+		{"below a negative half", []string{"-0.1", "-0.1", "0.0"}, "-0.1/-0.1/0.0"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var rows strings.Builder
+			for _, temperature := range test.temperatures {
+				rows.WriteString("Abha;" + temperature + "\n")
+			}
+
+			path := filepath.Join(t.TempDir(), "measurements.txt")
+			if err := os.WriteFile(path, []byte(rows.String()), 0o644); err != nil {
+				t.Fatal(err)
+			}
+
+			if answer, expected := CalculateExpectedOutput(path), "{Abha="+test.expected+"}"; answer != expected {
+				t.Errorf("CalculateExpectedOutput = %v, expected %v", answer, expected)
+			}
+		})
+	}
+}
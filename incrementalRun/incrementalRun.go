@@ -0,0 +1,142 @@
+package incrementalrun
+
+import (
+	multireader "billionRowChallenge/multiReader"
+	"billionRowChallenge/utilities"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+)
+
+const StateVersion = 1 // Bumped whenever the layout of the saved state changes, older states trigger a full run
+
+// RunState - Everything saved between runs. `ProcessedOffset` sits directly after the last complete row that was
+// added into the output map, so the next run can begin parsing right there.
+type RunState struct {
+	Version         int                               `json:"version"`
+	Fingerprint     utilities.FileFingerprint         `json:"fingerprint"`
+	ProcessedOffset int64                             `json:"processedOffset"`
+	OutputMap       map[string]utilities.OutputValues `json:"outputMap"`
+}
+
+// RunSummary - Describes what a run ended up doing, so the caller can report it
+type RunSummary struct {
+	FullRun     bool
+	Reason      string
+	StartOffset int64
+	EndOffset   int64
+}
+
+// StatePath - Default location of the saved state, sitting right beside the measurements file
+func StatePath(filename string) string {
+	return filename + ".state"
+}
+
+// Run - Aggregates the measurements file, re-using the saved state whenever the file has only had bytes appended
+// since the last run. Only the new tail is parsed and merged into the saved output map. Falls back to a full run
+// when no usable state exists, the file shrank, or the hashed prefix no longer matches. A final row without a newline
+// may still be being written, so it is left for the next run.
+//
+// The updated state is saved back to `statePath` before returning.
+func Run(filename string, statePath string) (map[string]utilities.OutputValues, RunSummary, error) {
+
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, RunSummary{}, err
+	}
+	defer file.Close()
+
+	fileInfo, err := file.Stat()
+	if err != nil {
+		return nil, RunSummary{}, err
+	}
+
+	outputMap, summary, err := resumeFromState(file, fileInfo, statePath)
+	if err != nil {
+		return nil, summary, err
+	}
+
+	// Parse everything that has not been seen yet
+	tailMap, processedOffset, err := multireader.AggregateCompleteRows(file, summary.StartOffset, fileInfo.Size())
+	if err != nil {
+		return nil, summary, err
+	}
+	utilities.MergeOutputMaps(outputMap, tailMap)
+	summary.EndOffset = processedOffset
+
+	fingerprint, err := utilities.FingerprintFile(file, fileInfo, min(utilities.PrefixHashSize, processedOffset))
+	if err != nil {
+		return nil, summary, err
+	}
+
+	err = SaveState(statePath, RunState{
+		Version:         StateVersion,
+		Fingerprint:     fingerprint,
+		ProcessedOffset: processedOffset,
+		OutputMap:       outputMap,
+	})
+
+	return outputMap, summary, err
+}
+
+// resumeFromState - Loads the saved state and checks it still describes the file. Returns the saved output map with
+// the offset to continue from, or an empty map with a zero offset when a full run is required.
+func resumeFromState(file *os.File, fileInfo os.FileInfo, statePath string) (map[string]utilities.OutputValues, RunSummary, error) {
+
+	fullRun := func(reason string) (map[string]utilities.OutputValues, RunSummary, error) {
+		return make(map[string]utilities.OutputValues), RunSummary{FullRun: true, Reason: reason}, nil
+	}
+
+	state, err := LoadState(statePath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return fullRun("no saved state")
+	} else if err != nil {
+		return fullRun(fmt.Sprintf("saved state is unreadable: %v", err))
+	}
+
+	if state.Version != StateVersion {
+		return fullRun(fmt.Sprintf("saved state is version %v, expected %v", state.Version, StateVersion))
+	}
+
+	reason, err := utilities.CheckFingerprint(file, fileInfo, state.Fingerprint, state.ProcessedOffset)
+	if err != nil {
+		return nil, RunSummary{}, err
+	}
+	if reason != "" {
+		return fullRun(reason)
+	}
+
+	if state.OutputMap == nil {
+		state.OutputMap = make(map[string]utilities.OutputValues)
+	}
+
+	return state.OutputMap, RunSummary{StartOffset: state.ProcessedOffset}, nil
+}
+
+// LoadState - Reads a previously saved state
+func LoadState(statePath string) (RunState, error) {
+
+	var state RunState
+
+	stateBytes, err := os.ReadFile(statePath)
+	if err != nil {
+		return state, err
+	}
+
+	err = json.Unmarshal(stateBytes, &state)
+	return state, err
+}
+
+// SaveState - Writes the state over any previously saved state, atomically so a crash part way through never leaves a
+// half written state behind
+func SaveState(statePath string, state RunState) error {
+
+	stateBytes, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+
+	return utilities.WriteFileAtomic(statePath, stateBytes)
+}
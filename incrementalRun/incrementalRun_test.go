@@ -0,0 +1,153 @@
+package incrementalrun
+
+import (
+	multireader "billionRowChallenge/multiReader"
+	"billionRowChallenge/utilities"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func testRows(first int, count int) []byte {
+
+	var rows []byte
+	for row := first; row < first+count; row++ {
+		rows = fmt.Appendf(rows, "Station %v;%.1f\n", row%97, float64(row*7919%1999-999)/10)
+	}
+	return rows
+}
+
+// appendFile - Adds the bytes onto the end of the file, the way a logger would
+func appendFile(t *testing.T, path string, data []byte) {
+
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY|os.O_CREATE, 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = file.Write(data); err != nil {
+		t.Fatal(err)
+	}
+	if err = file.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// fullRun - The results of parsing the whole file from scratch
+func fullRun(t *testing.T, path string) map[string]utilities.OutputValues {
+
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	fileInfo, err := file.Stat()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	outputMap, _, err := multireader.AggregateFile(file, 0, fileInfo.Size())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return outputMap
+}
+
+func TestRunOnlyParsesAppendedBytes(t *testing.T) {
+
+	path := filepath.Join(t.TempDir(), "measurements.txt")
+	statePath := StatePath(path)
+
+	// The second append finishes the partial row the first one left behind
+	appends := [][]byte{testRows(0, 5_000), []byte("Abha;-1"), append([]byte("2.3\n"), testRows(5_000, 2_000)...), nil}
+
+	var previousEnd int64
+	for appendIndex, appended := range appends {
+		appendFile(t, path, appended)
+
+		outputMap, summary, err := Run(path, statePath)
+		if err != nil {
+			t.Fatalf("Run: %v", err)
+		}
+
+		if summary.FullRun != (appendIndex == 0) {
+			t.Errorf("run %v: full run %v, %q", appendIndex+1, summary.FullRun, summary.Reason)
+		}
+		if summary.StartOffset != previousEnd {
+			t.Errorf("run %v began at %v, expected %v", appendIndex+1, summary.StartOffset, previousEnd)
+		}
+		if !maps.Equal(outputMap, fullRun(t, path)) {
+			t.Errorf("run %v differs from a full run over the file", appendIndex+1)
+		}
+
+		previousEnd = summary.EndOffset
+	}
+}
+
+func TestRunFallsBackToFullRun(t *testing.T) {
+
+	rows := testRows(0, 1_000)
+
+	tests := []struct {
+		name   string
+		change func(t *testing.T, path string, statePath string)
+	}{
+		{"rewritten", func(t *testing.T, path string, statePath string) {
+			if err := os.WriteFile(path, append([]byte("Accra"), rows[7:]...), 0o644); err != nil {
+				t.Fatal(err)
+			}
+		}},
+		{"truncated", func(t *testing.T, path string, statePath string) {
+			if err := os.WriteFile(path, rows[:len(rows)/2], 0o644); err != nil {
+				t.Fatal(err)
+			}
+		}},
+		{"older state", func(t *testing.T, path string, statePath string) {
+			state, err := LoadState(statePath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			state.Version--
+			if err = SaveState(statePath, state); err != nil {
+				t.Fatal(err)
+			}
+		}},
+		{"unreadable state", func(t *testing.T, path string, statePath string) {
+			if err := os.WriteFile(statePath, []byte("{"), 0o644); err != nil {
+				t.Fatal(err)
+			}
+		}},
+		{"missing state", func(t *testing.T, path string, statePath string) {
+			if err := os.Remove(statePath); err != nil {
+				t.Fatal(err)
+			}
+		}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "measurements.txt")
+			statePath := StatePath(path)
+
+			appendFile(t, path, rows)
+			if _, _, err := Run(path, statePath); err != nil {
+				t.Fatalf("Run: %v", err)
+			}
+
+			test.change(t, path, statePath)
+
+			outputMap, summary, err := Run(path, statePath)
+			if err != nil {
+				t.Fatalf("Run: %v", err)
+			}
+			if !summary.FullRun || summary.StartOffset != 0 || summary.Reason == "" {
+				t.Errorf("summary = %+v, expected a full run with its reason", summary)
+			}
+			if !maps.Equal(outputMap, fullRun(t, path)) {
+				t.Error("results differ from a full run over the file")
+			}
+		})
+	}
+}
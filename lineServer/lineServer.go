@@ -0,0 +1,289 @@
+package lineserver
+
+import (
+	"billionRowChallenge/parsers"
+	"billionRowChallenge/utilities"
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"net"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+const ReadBufferSize = 64 * 1024 // Per connection buffer, which is also the longest line a sensor can send
+
+const CommandHex = 0x3f // `?` - Lines beginning with this byte are queries rather than measurements
+
+// snapshotRequest - Asks the aggregator for a copy of the shared output map
+type snapshotRequest struct {
+	reply chan map[string]utilities.OutputValues
+}
+
+// Server - Accepts `station;temperature\n` lines over TCP and folds them into one shared output map.
+//
+// Each connection parses its reads into its own small batch map, and those batches are handed over a channel to a
+// single aggregator routine that owns the shared map, the same as `output.AggregateEntryOutputs`. Snapshot queries
+// travel through that same routine, so a connection always sees its own earlier measurements. Rows without a valid
+// `-99.9` to `99.9` reading are dropped rather than counted, and answered with a `!rejected <n> malformed rows` line.
+//
+// Queries a connection can send:
+//   - `?SNAPSHOT` replies with `station;min;max;total;count` for every station (tenths of a degree), then a `.` line
+//   - `?STATION <name>` replies with the line for that one station, if it exists, then a `.` line
+type Server struct {
+	batchChannel    chan map[string]utilities.OutputValues
+	snapshotChannel chan snapshotRequest
+	closeChannel    chan struct{}
+	aggregatorDone  chan struct{}
+	outputMap       map[string]utilities.OutputValues
+	rejectedRows    atomic.Int64 // Malformed rows dropped across every connection
+}
+
+// NewServer - Creates the server and starts its aggregator routine. Call `Close` once the server is no longer needed.
+func NewServer() *Server {
+
+	server := &Server{
+		batchChannel:    make(chan map[string]utilities.OutputValues),
+		snapshotChannel: make(chan snapshotRequest),
+		closeChannel:    make(chan struct{}),
+		aggregatorDone:  make(chan struct{}),
+		outputMap:       make(map[string]utilities.OutputValues),
+	}
+
+	go server.aggregate()
+
+	return server
+}
+
+// aggregate - Routine that owns the shared output map. Merges incoming batches and answers snapshot requests one at a time.
+func (server *Server) aggregate() {
+
+	defer close(server.aggregatorDone)
+
+	for {
+		select {
+		case batch := <-server.batchChannel:
+			utilities.MergeOutputMaps(server.outputMap, batch)
+		case request := <-server.snapshotChannel:
+			request.reply <- maps.Clone(server.outputMap)
+		case <-server.closeChannel:
+			return
+		}
+	}
+}
+
+// Snapshot - Returns a copy of the shared output map as it currently stands
+func (server *Server) Snapshot() map[string]utilities.OutputValues {
+
+	// Once closed, nothing else can write into the map
+	select {
+	case <-server.aggregatorDone:
+		return maps.Clone(server.outputMap)
+	default:
+	}
+
+	request := snapshotRequest{reply: make(chan map[string]utilities.OutputValues, 1)}
+	select {
+	case server.snapshotChannel <- request:
+		return <-request.reply
+	case <-server.aggregatorDone:
+		return maps.Clone(server.outputMap)
+	}
+}
+
+// RejectedRows - Number of malformed rows dropped so far across every connection
+func (server *Server) RejectedRows() int64 {
+	return server.rejectedRows.Load()
+}
+
+// Close - Stops the aggregator routine. Any later snapshot returns the final state of the map.
+func (server *Server) Close() {
+	close(server.closeChannel)
+	<-server.aggregatorDone
+}
+
+// Serve - Accepts connections until the context is cancelled. Every connection is handled within its own routine, and
+// Serve only returns once all of them have finished.
+func (server *Server) Serve(ctx context.Context, listener net.Listener) error {
+
+	var waitGroup sync.WaitGroup
+	defer waitGroup.Wait()
+
+	stopListening := context.AfterFunc(ctx, func() {
+		listener.Close()
+	})
+	defer stopListening()
+
+	for {
+		connection, err := listener.Accept()
+		if err != nil {
+			if ctx.Err() != nil || errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return err
+		}
+
+		waitGroup.Add(1)
+		go func() {
+			defer waitGroup.Done()
+
+			stopConnection := context.AfterFunc(ctx, func() {
+				connection.Close()
+			})
+			defer stopConnection()
+
+			server.handleConnection(connection)
+		}()
+	}
+}
+
+// handleConnection - Reads measurements off the connection until it closes. Runs of measurement rows are parsed
+// straight out of the read buffer, while any query line first flushes the batch so the reply includes it.
+func (server *Server) handleConnection(connection net.Conn) {
+
+	defer connection.Close()
+
+	var readBuffer = make([]byte, ReadBufferSize)
+	var carriedBytes int // Partial line left over from the previous read
+	var batch = make(map[string]utilities.OutputValues)
+
+	writer := bufio.NewWriter(connection)
+
+	for {
+		n, err := connection.Read(readBuffer[carriedBytes:])
+		bufferedBytes := carriedBytes + n
+
+		var position int
+		var rejectedRows int // Rows within this read, less the ones added into the batch
+		for position < bufferedBytes {
+
+			// Queries sit on their own line
+			if readBuffer[position] == CommandHex {
+				newLineIndex := bytes.IndexByte(readBuffer[position:bufferedBytes], utilities.NewLineHex)
+				if newLineIndex < 0 {
+					break
+				}
+
+				batch = server.flush(batch)
+				server.answerQuery(writer, readBuffer[position+1:position+newLineIndex])
+				if writer.Flush() != nil {
+					return
+				}
+
+				position += newLineIndex + 1
+				continue
+			}
+
+			// Measurement rows run up until the next query line, or the end of the read
+			rowsEnd := bufferedBytes
+			if commandIndex := bytes.Index(readBuffer[position:bufferedBytes], []byte{utilities.NewLineHex, CommandHex}); commandIndex >= 0 {
+				rowsEnd = position + commandIndex + 1
+			}
+
+			rowBytes, validRows := server.parseRows(readBuffer[position:rowsEnd], batch)
+			if rowBytes == 0 {
+				break
+			}
+			rejectedRows += bytes.Count(readBuffer[position:position+rowBytes], []byte{utilities.NewLineHex}) - validRows
+			position += rowBytes
+		}
+
+		batch = server.flush(batch)
+
+		// Let the sensor know some of its rows were never counted
+		if rejectedRows > 0 {
+			server.rejectedRows.Add(int64(rejectedRows))
+			fmt.Fprintf(writer, "!rejected %v malformed rows\n", rejectedRows)
+			if writer.Flush() != nil {
+				return
+			}
+		}
+
+		if err != nil {
+			return
+		}
+
+		// A line that fills the whole buffer can never be completed
+		if position == 0 && bufferedBytes == len(readBuffer) {
+			fmt.Fprintf(writer, "!line longer than %v bytes\n", ReadBufferSize)
+			writer.Flush()
+			return
+		}
+
+		// Move the partial line to the front of the buffer for the next read
+		carriedBytes = copy(readBuffer, readBuffer[position:bufferedBytes])
+	}
+}
+
+// parseRows - Adds every complete row with a valid temperature into the batch, the same as `parsers.ParseRows`, but
+// checks each reading first, as the bytes come from outside. A carriage return before the newline is allowed for sensors
+// sending `\r\n` line endings. Returns the bytes consumed along with the number of rows that were valid.
+func (server *Server) parseRows(rowData []byte, batch map[string]utilities.OutputValues) (int, int) {
+
+	var validRows int
+	consumedBytes := parsers.ScanRows(rowData, func(_ int, station []byte, temperatureWhole []byte, temperatureDecimal []byte) {
+		temperatureDecimal = bytes.TrimSuffix(temperatureDecimal, []byte{utilities.CarriageReturnHex})
+
+		if len(station) == 0 || !parsers.ValidTemperature(temperatureWhole, temperatureDecimal) {
+			return
+		}
+		utilities.AddTemperature(batch, string(station), parsers.ParseTemperature(temperatureWhole, temperatureDecimal[0]))
+		validRows++
+	})
+
+	return consumedBytes, validRows
+}
+
+// flush - Hands a non-empty batch over to the aggregator and returns a fresh batch
+func (server *Server) flush(batch map[string]utilities.OutputValues) map[string]utilities.OutputValues {
+
+	if len(batch) == 0 {
+		return batch
+	}
+
+	select {
+	case server.batchChannel <- batch:
+	case <-server.aggregatorDone:
+	}
+
+	return make(map[string]utilities.OutputValues)
+}
+
+// answerQuery - Writes the reply for a single query line, without the leading `?`
+func (server *Server) answerQuery(writer *bufio.Writer, query []byte) {
+
+	command, argument, _ := strings.Cut(strings.TrimSpace(string(query)), " ")
+
+	switch strings.ToUpper(command) {
+	case "SNAPSHOT":
+		snapshot := server.Snapshot()
+
+		stations := make([]string, 0, len(snapshot))
+		for station := range snapshot {
+			stations = append(stations, station)
+		}
+		slices.Sort(stations)
+
+		for _, station := range stations {
+			writeStation(writer, station, snapshot[station])
+		}
+	case "STATION":
+		if outputValues, ok := server.Snapshot()[argument]; ok {
+			writeStation(writer, argument, outputValues)
+		}
+	default:
+		fmt.Fprintf(writer, "!unknown query %q\n", command)
+	}
+
+	writer.WriteString(".\n")
+}
+
+// writeStation - Writes one station line of a query reply
+func writeStation(writer *bufio.Writer, station string, outputValues utilities.OutputValues) {
+	fmt.Fprintf(writer, "%v;%v;%v;%v;%v\n", station, outputValues.Min, outputValues.Max, outputValues.Total, outputValues.Count)
+}
@@ -0,0 +1,156 @@
+package lineserver
+
+import (
+	"billionRowChallenge/utilities"
+	"bufio"
+	"context"
+	"io"
+	"maps"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// startServer - A server listening on a free local port, shut down once the test finishes
+func startServer(t *testing.T) (*Server, string) {
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server := NewServer()
+	ctx, cancel := context.WithCancel(context.Background())
+	served := make(chan error)
+	go func() { served <- server.Serve(ctx, listener) }()
+
+	t.Cleanup(func() {
+		cancel()
+		if err := <-served; err != nil {
+			t.Errorf("Serve: %v", err)
+		}
+		server.Close()
+	})
+
+	return server, listener.Addr().String()
+}
+
+// exchange - Sends everything, closes the sending side, and returns every line the server replied with
+func exchange(t *testing.T, address string, lines string) []string {
+
+	connection, err := net.Dial("tcp", address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer connection.Close()
+
+	if _, err = io.WriteString(connection, lines); err != nil {
+		t.Fatal(err)
+	}
+	if err = connection.(*net.TCPConn).CloseWrite(); err != nil {
+		t.Fatal(err)
+	}
+
+	var replies []string
+	scanner := bufio.NewScanner(connection)
+	for scanner.Scan() {
+		replies = append(replies, scanner.Text())
+	}
+	if err = scanner.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	return replies
+}
+
+// rejectedCount - Adds up every `!rejected` line, as a read may have been split over several
+func rejectedCount(t *testing.T, replies []string) int {
+
+	var rejected int
+	for _, reply := range replies {
+		if count, ok := strings.CutPrefix(reply, "!rejected "); ok {
+			value, err := strconv.Atoi(strings.TrimSuffix(count, " malformed rows"))
+			if err != nil {
+				t.Fatalf("reply %q: %v", reply, err)
+			}
+			rejected += value
+		}
+	}
+	return rejected
+}
+
+func TestServerRejectsMalformedRows(t *testing.T) {
+
+	server, address := startServer(t)
+
+	rows := strings.Join([]string{
+		"Abha;12.3",
+		"Abha;-4.5\r",
+		"Zürich;99.9",
+		"Zürich;-99.9",
+		"Abha;123.4",   // Three whole digits
+		"Abha;1.23",    // Two decimal digits
+		"Abha;x.1",     // Not a digit
+		";1.0",         // No station
+		"Abha",         // No temperature
+		"Abha;12",      // No decimal
+		"Abha;1.2;3",   // Trailing bytes
+		"Accra;-0.0\r", // Carriage return with a negative zero
+	}, "\n") + "\n"
+
+	replies := exchange(t, address, rows+"?SNAPSHOT\n")
+
+	if rejected := rejectedCount(t, replies); rejected != 7 {
+		t.Errorf("rejected %v rows, expected 7, replies %q", rejected, replies)
+	}
+	if server.RejectedRows() != 7 {
+		t.Errorf("RejectedRows = %v, expected 7", server.RejectedRows())
+	}
+
+	expected := map[string]utilities.OutputValues{
+		"Abha":   {Min: -45, Max: 123, Total: 78, Count: 2},
+		"Accra":  {Min: 0, Max: 0, Total: 0, Count: 1},
+		"Zürich": {Min: -999, Max: 999, Total: 0, Count: 2},
+	}
+	if snapshot := server.Snapshot(); !maps.Equal(snapshot, expected) {
+		t.Errorf("Snapshot = %v, expected %v", snapshot, expected)
+	}
+
+	// The query is answered after the rows before it, in station order
+	var snapshotLines []string
+	for _, reply := range replies {
+		if !strings.HasPrefix(reply, "!") {
+			snapshotLines = append(snapshotLines, reply)
+		}
+	}
+	expectedLines := []string{"Abha;-45;123;78;2", "Accra;0;0;0;1", "Zürich;-999;999;0;2", "."}
+	if strings.Join(snapshotLines, "\n") != strings.Join(expectedLines, "\n") {
+		t.Errorf("snapshot reply = %q, expected %q", snapshotLines, expectedLines)
+	}
+}
+
+func TestServerQueries(t *testing.T) {
+
+	_, address := startServer(t)
+
+	// Rows sent over one connection are seen by queries over every other
+	exchange(t, address, "Abha;1.0\nAbha;2.0\n")
+
+	tests := []struct {
+		query   string
+		replies []string
+	}{
+		{"?STATION Abha\n", []string{"Abha;10;20;30;2", "."}},
+		{"?station Abha\n", []string{"Abha;10;20;30;2", "."}},
+		{"?STATION Accra\n", []string{"."}},
+		{"?SNAPSHOT\r\n", []string{"Abha;10;20;30;2", "."}},
+		{"?RESET\n", []string{`!unknown query "RESET"`, "."}},
+	}
+
+	for _, test := range tests {
+		if replies := exchange(t, address, test.query); strings.Join(replies, "\n") != strings.Join(test.replies, "\n") {
+			t.Errorf("%q replied %q, expected %q", test.query, replies, test.replies)
+		}
+	}
+}
@@ -14,6 +14,12 @@ import (
 // main - Core entry point to the Billion Row Challenge
 func main() {
 
+	// Any arguments switch over to one of the sub-commands
+	if len(os.Args) > 1 {
+		runCommand(os.Args[1:])
+		return
+	}
+
 	// start := time.Now()
 
 	var waitGroup sync.WaitGroup
@@ -92,7 +98,7 @@ func main() {
 	// Wait until all entries have been parsed and placed into the output map
 	waitGroup.Wait()
 
-	fmt.Println(utilities.OutputMap)
+	fmt.Println(output.FormatResults(utilities.OutputMap))
 
 	// Get the current working directory
 	// TODO: Strip this out before the competition and hard-code the path to the file to speed up execution.
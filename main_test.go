@@ -0,0 +1,676 @@
+package main
+
+import (
+	"archive/tar"
+	"billionRowChallenge/output"
+	resultsnapshot "billionRowChallenge/resultSnapshot"
+	"billionRowChallenge/utilities"
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// runCommandVariable - Set when the test binary is re-run as the program, so its arguments are taken as a command
+const runCommandVariable = "BRC_TEST_RUN_COMMAND"
+
+// TestMain - Runs a single command instead of the tests when re-run by `runProgram`
+func TestMain(m *testing.M) {
+
+	if os.Getenv(runCommandVariable) != "" {
+		runCommand(os.Args[1:])
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// runProgram - Runs a command in a fresh process, as it would be from the command line, returning stdout and stderr
+func runProgram(t *testing.T, arguments ...string) (string, string) {
+
+	t.Helper()
+
+	stdout, stderr, exitCode := runProgramStatus(t, arguments...)
+	if exitCode != 0 {
+		t.Fatalf("%v: exit status %v\n%v", strings.Join(arguments, " "), exitCode, stderr)
+	}
+	return stdout, stderr
+}
+
+// runProgramStatus - Same as `runProgram`, but hands back the exit status rather than failing on a non-zero one
+func runProgramStatus(t *testing.T, arguments ...string) (string, string, int) {
+
+	t.Helper()
+
+	command := exec.Command(os.Args[0], arguments...)
+	command.Env = append(os.Environ(), runCommandVariable+"=1")
+
+	var stdout, stderr bytes.Buffer
+	command.Stdout, command.Stderr = &stdout, &stderr
+
+	var exitError *exec.ExitError
+	if err := command.Run(); errors.As(err, &exitError) {
+		return stdout.String(), stderr.String(), exitError.ExitCode()
+	} else if err != nil {
+		t.Fatalf("%v: %v", strings.Join(arguments, " "), err)
+	}
+	return stdout.String(), stderr.String(), 0
+}
+
+// writeMeasurements - Writes rows to a measurements file in a fresh directory, returning its path
+func writeMeasurements(t *testing.T, rows string) string {
+
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "measurements.txt")
+	if err := os.WriteFile(path, []byte(rows), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// appendMeasurements - Appends rows to an existing measurements file
+func appendMeasurements(t *testing.T, path string, rows string) {
+
+	t.Helper()
+
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	if _, err := file.WriteString(rows); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// testMeasurements - A handful of rows covering negative, single-digit and multi-byte station readings
+const testMeasurements = "Abha;12.5\nZürich;-3.2\nAbha;-0.5\nCork;9.0\nZürich;4.1\n"
+
+func TestIncrementalCommand(t *testing.T) {
+
+	path := writeMeasurements(t, testMeasurements)
+
+	stdout, stderr := runProgram(t, "incremental", path)
+	if !strings.HasPrefix(stderr, "Full run") {
+		t.Errorf("first run reported %q, expected a full run", stderr)
+	}
+	if !strings.Contains(stdout, "Zürich") || !strings.Contains(stdout, "Cork") {
+		t.Errorf("first run printed %q, expected every station", stdout)
+	}
+
+	appended := "Dakar;30.1\n"
+	appendMeasurements(t, path, appended)
+
+	stdout, stderr = runProgram(t, "incremental", path)
+	expected := fmt.Sprintf("Incremental run: parsed bytes %v-%v\n", len(testMeasurements), len(testMeasurements)+len(appended))
+	if stderr != expected {
+		t.Errorf("second run reported %q, expected %q", stderr, expected)
+	}
+	if !strings.Contains(stdout, "Dakar") || !strings.Contains(stdout, "Abha") {
+		t.Errorf("second run printed %q, expected the appended station merged with the saved ones", stdout)
+	}
+}
+
+func TestAggregateCommand(t *testing.T) {
+
+	path := writeMeasurements(t, testMeasurements)
+
+	// The same file aggregated without and then with a row index, and through a full incremental run
+	withoutIndex, _ := runProgram(t, "aggregate", path)
+	runProgram(t, "index", path)
+	withIndex, _ := runProgram(t, "aggregate", path)
+	incremental, _ := runProgram(t, "incremental", path)
+
+	if expected := "{Abha=-0.5/6.0/12.5, Cork=9.0/9.0/9.0, Zürich=-3.2/0.5/4.1}\n"; withoutIndex != expected {
+		t.Errorf("aggregate printed %q, expected %q", withoutIndex, expected)
+	}
+	if withIndex != withoutIndex {
+		t.Errorf("aggregate with an index printed %q, expected %q", withIndex, withoutIndex)
+	}
+	if incremental != withoutIndex {
+		t.Errorf("incremental printed %q, expected %q", incremental, withoutIndex)
+	}
+
+	// A final row without a newline still counts towards a full aggregate
+	unterminated, _ := runProgram(t, "aggregate", writeMeasurements(t, strings.TrimSuffix(testMeasurements, "\n")))
+	if unterminated != withoutIndex {
+		t.Errorf("aggregate without a final newline printed %q, expected %q", unterminated, withoutIndex)
+	}
+}
+
+func TestIndexAndRowsCommands(t *testing.T) {
+
+	path := writeMeasurements(t, testMeasurements)
+
+	_, stderr := runProgram(t, "index", path)
+	if expected := fmt.Sprintf("Indexed 5 rows in 1 blocks up to byte %v\n", len(testMeasurements)); stderr != expected {
+		t.Errorf("index reported %q, expected %q", stderr, expected)
+	}
+
+	stdout, _ := runProgram(t, "rows", path, "1", "3")
+	if expected := "Zürich;-3.2\nAbha;-0.5\nCork;9.0\n"; stdout != expected {
+		t.Errorf("rows printed %q, expected %q", stdout, expected)
+	}
+}
+
+func TestSpoolCommand(t *testing.T) {
+
+	spoolRoot := t.TempDir()
+	runProgram(t, "spool", "-once", spoolRoot)
+
+	// Dropped in after the spool directories are prepared, and picked up by the next check
+	incoming := filepath.Join(spoolRoot, "incoming", "measurements.txt")
+	if err := os.WriteFile(incoming, []byte(testMeasurements), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	stdout, _ := runProgram(t, "spool", "-once", "-settle", "0s", spoolRoot)
+	expected, _ := runProgram(t, "aggregate", writeMeasurements(t, testMeasurements))
+	if stdout != expected {
+		t.Errorf("spool printed %q, expected %q", stdout, expected)
+	}
+	if _, err := os.Stat(incoming); err == nil {
+		t.Error("the ingested file was left within the incoming directory")
+	}
+}
+
+func TestServeCommand(t *testing.T) {
+
+	command := exec.Command(os.Args[0], "serve", "-listen", "127.0.0.1:0", "-metrics", "127.0.0.1:0")
+	command.Env = append(os.Environ(), runCommandVariable+"=1")
+
+	var stdout bytes.Buffer
+	command.Stdout = &stdout
+	stderr, err := command.StderrPipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = command.Start(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { command.Process.Kill() })
+
+	// The first lines name the ports that were picked
+	stderrReader := bufio.NewReader(stderr)
+	listening, err := stderrReader.ReadString('\n')
+	if err != nil {
+		t.Fatalf("reading the listen address: %v", err)
+	}
+	address := strings.TrimSpace(strings.TrimPrefix(listening, "Listening for measurements on "))
+	serving, err := stderrReader.ReadString('\n')
+	if err != nil {
+		t.Fatalf("reading the metrics address: %v", err)
+	}
+	metricsURL := strings.TrimSpace(strings.TrimPrefix(serving, "Serving metrics on "))
+
+	connection, err := net.Dial("tcp", address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer connection.Close()
+
+	if _, err = io.WriteString(connection, testMeasurements+"Abha;1.23\n?STATION Abha\n"); err != nil {
+		t.Fatal(err)
+	}
+	reply, err := bufio.NewReader(connection).ReadString('.')
+	if err != nil {
+		t.Fatalf("reading the query reply: %v", err)
+	}
+	if expected := "Abha;-5;125;120;2\n."; !strings.HasSuffix(reply, expected) {
+		t.Errorf("?STATION Abha replied %q, expected %q", reply, expected)
+	}
+
+	response, err := http.Get(metricsURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	exposition, err := io.ReadAll(response.Body)
+	response.Body.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if line := `brc_station_readings_total{station="Abha"} 2`; !strings.Contains(string(exposition), line+"\n") {
+		t.Errorf("%v served %q, expected %q", metricsURL, exposition, line)
+	}
+
+	// Interrupting the server prints everything it took in
+	if err = command.Process.Signal(os.Interrupt); err != nil {
+		t.Fatal(err)
+	}
+	remaining, err := io.ReadAll(stderrReader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = command.Wait(); err != nil {
+		t.Fatalf("serve: %v", err)
+	}
+	if string(remaining) != "Rejected 1 malformed rows\n" {
+		t.Errorf("serve reported %q on exit, expected the malformed row", remaining)
+	}
+
+	expected, _ := runProgram(t, "aggregate", writeMeasurements(t, testMeasurements))
+	if stdout.String() != expected {
+		t.Errorf("serve printed %q, expected %q", stdout.String(), expected)
+	}
+}
+
+func TestTarCommand(t *testing.T) {
+
+	// The measurements split over two members, with a member the default pattern skips
+	members := []struct{ name, data string }{
+		{"first.csv", testMeasurements[:23]},
+		{"notes.txt", "Nowhere;1.0\n"},
+		{"nested/second.csv", testMeasurements[23:]},
+	}
+
+	var archive bytes.Buffer
+	tarWriter := tar.NewWriter(&archive)
+	for _, member := range members {
+		header := &tar.Header{Name: member.name, Mode: 0o644, Size: int64(len(member.data)), Typeflag: tar.TypeReg}
+		if err := tarWriter.WriteHeader(header); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tarWriter.Write([]byte(member.data)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tarWriter.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(t.TempDir(), "measurements.tar")
+	if err := os.WriteFile(path, archive.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	stdout, stderr := runProgram(t, "tar", path)
+	expected, _ := runProgram(t, "aggregate", writeMeasurements(t, testMeasurements))
+	if stdout != expected {
+		t.Errorf("tar printed %q, expected %q", stdout, expected)
+	}
+	if strings.Count(stderr, "\n") != 2 || strings.Contains(stderr, "notes.txt") {
+		t.Errorf("tar reported %q, expected only the two matching members", stderr)
+	}
+}
+
+func TestFormatFlag(t *testing.T) {
+
+	path := writeMeasurements(t, testMeasurements)
+
+	stdout, _ := runProgram(t, "aggregate", "-format", "json", path)
+	var results output.JSONResults
+	if err := json.Unmarshal([]byte(stdout), &results); err != nil {
+		t.Fatalf("aggregate -format json printed %q: %v", stdout, err)
+	}
+	if results.Run.Command != "aggregate" || results.Run.Rows != 5 || results.Stations["Zürich"].Mean != "0.5" {
+		t.Errorf("aggregate -format json = %+v", results)
+	}
+
+	stdout, _ = runProgram(t, "aggregate", "-format", "ndjson", path)
+	lines := strings.Split(strings.TrimSuffix(stdout, "\n"), "\n")
+	if len(lines) != 3 || !strings.Contains(lines[0], `"station":"Abha"`) {
+		t.Errorf("aggregate -format ndjson printed %q, expected a line per station", stdout)
+	}
+
+	stdout, _ = runProgram(t, "aggregate", "-format", "csv", "-sort", "mean", "-desc", "-no-header", path)
+	if expected := "Cork,9.0,9.0,9.0,1,9.0\nAbha,-0.5,6.0,12.5,2,12.0\nZürich,-3.2,0.5,4.1,2,0.9\n"; stdout != expected {
+		t.Errorf("aggregate -format csv printed %q, expected %q", stdout, expected)
+	}
+
+	stdout, _ = runProgram(t, "aggregate", "-format", "table", "-color", "always", "-sort", "max", path)
+	lines = strings.Split(strings.TrimSuffix(stdout, "\n"), "\n")
+	if len(lines) != 5 || !strings.HasPrefix(lines[2], "Zürich ") || !strings.Contains(stdout, "\x1b[") {
+		t.Errorf("aggregate -format table printed %q, expected 3 rows sorted by max and highlighted", stdout)
+	}
+
+	// The binary formats are written to a file, with the leading and trailing magic checked
+	parquetPath := filepath.Join(t.TempDir(), "results.parquet")
+	runProgram(t, "aggregate", "-format", "parquet", "-o", parquetPath, path)
+	parquet, err := os.ReadFile(parquetPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(parquet, []byte("PAR1")) || !bytes.HasSuffix(parquet, []byte("PAR1")) {
+		t.Errorf("aggregate -format parquet wrote %q, expected a parquet file", parquet)
+	}
+
+	snapshotPath := filepath.Join(t.TempDir(), "results.brcs")
+	runProgram(t, "aggregate", "-format", "snapshot", "-o", snapshotPath, path)
+	snapshot, err := resultsnapshot.Load(snapshotPath)
+	if err != nil {
+		t.Fatalf("loading the aggregate -format snapshot file: %v", err)
+	}
+	if expected := (utilities.OutputValues{Min: -32, Max: 41, Total: 9, Count: 2}); snapshot.OutputMap["Zürich"] != expected {
+		t.Errorf("Zürich = %+v, expected %+v", snapshot.OutputMap["Zürich"], expected)
+	}
+
+	stdout, _ = runProgram(t, "aggregate", "-format", "arrow", path)
+	if !strings.HasPrefix(stdout, "\xff\xff\xff\xff") || !strings.HasSuffix(stdout, "\xff\xff\xff\xff\x00\x00\x00\x00") {
+		t.Errorf("aggregate -format arrow printed %q, expected an arrow stream", stdout)
+	}
+
+	stdout, _ = runProgram(t, "aggregate", "-format", "prometheus", path)
+	if line := `brc_station_mean_celsius{station="Zürich"} 0.5`; !strings.Contains(stdout, line+"\n") {
+		t.Errorf("aggregate -format prometheus printed %q, expected %q", stdout, line)
+	}
+}
+
+func TestReportCommand(t *testing.T) {
+
+	path := writeMeasurements(t, testMeasurements)
+	reportPath := filepath.Join(t.TempDir(), "report.html")
+
+	_, stderr := runProgram(t, "report", "-o", reportPath, "-title", "Smoke test", path)
+	if expected := "Wrote " + reportPath + " with 3 stations and 0 warnings\n"; stderr != expected {
+		t.Errorf("report reported %q, expected %q", stderr, expected)
+	}
+
+	report, err := os.ReadFile(reportPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, fragment := range []string{"<!DOCTYPE html>", "Smoke test", "Zürich", "<svg"} {
+		if !bytes.Contains(report, []byte(fragment)) {
+			t.Errorf("report is missing %q", fragment)
+		}
+	}
+}
+
+func TestMergeCommand(t *testing.T) {
+
+	// The measurements split over two snapshots, merged back together
+	directory := t.TempDir()
+	var snapshotPaths []string
+	for index, rows := range []string{testMeasurements[:23], testMeasurements[23:]} {
+		snapshotPath := filepath.Join(directory, fmt.Sprintf("part%v.brcs", index))
+		runProgram(t, "aggregate", "-format", "snapshot", "-o", snapshotPath, writeMeasurements(t, rows))
+		snapshotPaths = append(snapshotPaths, snapshotPath)
+	}
+
+	stdout, stderr := runProgram(t, append([]string{"merge"}, snapshotPaths...)...)
+	expected, _ := runProgram(t, "aggregate", writeMeasurements(t, testMeasurements))
+	if stdout != expected {
+		t.Errorf("merge printed %q, expected %q", stdout, expected)
+	}
+	if stderr != "Merged 2 snapshots into 3 stations\n" {
+		t.Errorf("merge reported %q", stderr)
+	}
+
+	// Flags may follow the snapshots, and `-o` on its own writes a snapshot
+	mergedPath := filepath.Join(directory, "merged.brcs")
+	runProgram(t, "merge", snapshotPaths[0], snapshotPaths[1], "-o", mergedPath)
+	if _, err := resultsnapshot.Load(mergedPath); err != nil {
+		t.Errorf("loading the merged snapshot: %v", err)
+	}
+}
+
+func TestDiffCommand(t *testing.T) {
+
+	directory := t.TempDir()
+	snapshotPath := filepath.Join(directory, "expected.brcs")
+	runProgram(t, "aggregate", "-format", "snapshot", "-o", snapshotPath, writeMeasurements(t, testMeasurements))
+
+	// The canonical output compared against the snapshot it came from
+	matchingPath := filepath.Join(directory, "matching.txt")
+	runProgram(t, "aggregate", "-o", matchingPath, writeMeasurements(t, testMeasurements))
+
+	stdout, _, exitCode := runProgramStatus(t, "diff", snapshotPath, matchingPath)
+	if exitCode != 0 || stdout != "Results match across 3 stations\n" {
+		t.Errorf("diff of matching results = %q, exit status %v", stdout, exitCode)
+	}
+
+	differingPath := filepath.Join(directory, "differing.txt")
+	differing := "{Abha=-0.5/6.0/12.5, Cork=9.0/9.0/9.0, Zürich=-3.2/0.7/4.1}\n"
+	if err := os.WriteFile(differingPath, []byte(differing), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	stdout, _, exitCode = runProgramStatus(t, "diff", "-tolerance", "0.1", snapshotPath, differingPath)
+	if exitCode != 1 || !strings.HasSuffix(stdout, "0 missing, 0 extra, 1 differing fields\n") {
+		t.Errorf("diff of differing results = %q, exit status %v", stdout, exitCode)
+	}
+
+	// Within the tolerance the results match, and -quiet leaves only the exit status
+	stdout, _, exitCode = runProgramStatus(t, "diff", "-tolerance", "0.2", "-quiet", snapshotPath, differingPath)
+	if exitCode != 0 || stdout != "" {
+		t.Errorf("diff within the tolerance = %q, exit status %v", stdout, exitCode)
+	}
+}
+
+func TestAggregatePercentiles(t *testing.T) {
+
+	path := writeMeasurements(t, testMeasurements)
+
+	stdout, _ := runProgram(t, "aggregate", "-percentiles", "-format", "csv", path)
+	lines := strings.Split(strings.TrimSuffix(stdout, "\n"), "\n")
+	if len(lines) != 4 || !strings.HasSuffix(lines[0], ",median,p90,p95,p99") || lines[2] != "Cork,9.0,9.0,9.0,1,9.0,9.0,9.0,9.0,9.0" {
+		t.Errorf("aggregate -percentiles printed %q", stdout)
+	}
+
+	// The counts travel within the snapshot, so the percentiles survive a merge
+	snapshotPath := filepath.Join(t.TempDir(), "results.brcs")
+	runProgram(t, "aggregate", "-percentiles", "-format", "snapshot", "-o", snapshotPath, path)
+
+	merged, _ := runProgram(t, "merge", "-format", "csv", snapshotPath)
+	if merged != stdout {
+		t.Errorf("merge printed %q, expected %q", merged, stdout)
+	}
+
+	// The mode columns are drawn from the same counts, so merge can add them when asked
+	expected, _ := runProgram(t, "aggregate", "-percentiles", "-modes", "-format", "csv", path)
+	merged, _ = runProgram(t, "merge", "-modes", "-format", "csv", snapshotPath)
+	if merged != expected {
+		t.Errorf("merge -modes printed %q, expected %q", merged, expected)
+	}
+
+	_, stderr, status := runProgramStatus(t, "merge", "-modes", "-constant-share", "1.5", snapshotPath)
+	if status == 0 || !strings.Contains(stderr, "-constant-share") {
+		t.Errorf("merge -constant-share 1.5 exited with %d and printed %q", status, stderr)
+	}
+}
+
+func TestAggregateSpread(t *testing.T) {
+
+	path := writeMeasurements(t, testMeasurements)
+
+	stdout, _ := runProgram(t, "aggregate", "-spread", "-format", "csv", path)
+	expected := "station,min,mean,max,count,sum,variance,stddev\n" +
+		"Abha,-0.5,6.0,12.5,2,12.0,42.25,6.50\nCork,9.0,9.0,9.0,1,9.0,0.00,0.00\nZürich,-3.2,0.5,4.1,2,0.9,13.32,3.65\n"
+	if stdout != expected {
+		t.Errorf("aggregate -spread printed %q, expected %q", stdout, expected)
+	}
+
+	snapshotPath := filepath.Join(t.TempDir(), "results.brcs")
+	runProgram(t, "aggregate", "-spread", "-format", "snapshot", "-o", snapshotPath, path)
+
+	merged, _ := runProgram(t, "merge", "-format", "csv", snapshotPath)
+	if merged != expected {
+		t.Errorf("merge printed %q, expected %q", merged, expected)
+	}
+}
+
+func TestAggregateHistogram(t *testing.T) {
+
+	path := writeMeasurements(t, testMeasurements)
+
+	stdout, _ := runProgram(t, "aggregate", "-histogram", "edges:0,10", "-format", "ndjson", path)
+	if line := `"histogram":{"edges":[0.0,10.0],"counts":[1,1,0]}`; !strings.Contains(stdout, line) {
+		t.Errorf("aggregate -histogram printed %q, expected Zürich's %q", stdout, line)
+	}
+
+	// The histograms travel within the snapshot, so they survive a merge
+	snapshotPath := filepath.Join(t.TempDir(), "results.brcs")
+	runProgram(t, "aggregate", "-histogram", "edges:0,10", "-format", "snapshot", "-o", snapshotPath, path)
+
+	merged, _ := runProgram(t, "merge", "-format", "ndjson", snapshotPath)
+	if merged != stdout {
+		t.Errorf("merge printed %q, expected %q", merged, stdout)
+	}
+}
+
+func TestAggregateSketch(t *testing.T) {
+
+	path := writeMeasurements(t, testMeasurements)
+
+	stdout, _ := runProgram(t, "aggregate", "-sketch", "0.01", "-format", "csv", path)
+	lines := strings.Split(strings.TrimSuffix(stdout, "\n"), "\n")
+	if len(lines) != 4 || !strings.HasSuffix(lines[0], ",sketch_median,sketch_p90,sketch_p95,sketch_p99") {
+		t.Errorf("aggregate -sketch printed %q", stdout)
+	}
+
+	// The sketches travel within the snapshot, so they survive a merge
+	snapshotPath := filepath.Join(t.TempDir(), "results.brcs")
+	runProgram(t, "aggregate", "-sketch", "0.01", "-format", "snapshot", "-o", snapshotPath, path)
+
+	merged, _ := runProgram(t, "merge", "-format", "csv", snapshotPath)
+	if merged != stdout {
+		t.Errorf("merge printed %q, expected %q", merged, stdout)
+	}
+}
+
+func TestAggregateModes(t *testing.T) {
+
+	path := writeMeasurements(t, "Cork;9.0\nAbha;1.0\nCork;9.0\nAbha;2.0\nAbha;1.0\nDakar;30.1\n")
+
+	stdout, _ := runProgram(t, "aggregate", "-modes", "-format", "csv", path)
+	expected := "station,min,mean,max,count,sum,mode,mode_count,distinct,constant\n" +
+		"Abha,1.0,1.3,2.0,3,4.0,1.0,2,2,0\nCork,9.0,9.0,9.0,2,18.0,9.0,2,1,1\nDakar,30.1,30.1,30.1,1,30.1,30.1,1,1,0\n"
+	if stdout != expected {
+		t.Errorf("aggregate -modes printed %q, expected %q", stdout, expected)
+	}
+
+	// Two thirds of Abha's readings share its mode
+	stdout, _ = runProgram(t, "aggregate", "-modes", "-constant-share", "0.6", "-format", "csv", path)
+	if !strings.Contains(stdout, "\nAbha,1.0,1.3,2.0,3,4.0,1.0,2,2,1\n") {
+		t.Errorf("aggregate -modes -constant-share 0.6 printed %q, expected Abha to be flagged", stdout)
+	}
+}
+
+func TestTopCommand(t *testing.T) {
+
+	path := writeMeasurements(t, testMeasurements)
+
+	stdout, _ := runProgram(t, "top", "-by", "max", "-n", "2", "-format", "csv", "-no-header", path)
+	if expected := "Abha,-0.5,6.0,12.5,2,12.0\nCork,9.0,9.0,9.0,1,9.0\n"; stdout != expected {
+		t.Errorf("top printed %q, expected %q", stdout, expected)
+	}
+
+	// A snapshot is ranked the same as the file it came from
+	snapshotPath := filepath.Join(t.TempDir(), "results.brcs")
+	runProgram(t, "aggregate", "-format", "snapshot", "-o", snapshotPath, path)
+
+	stdout, _ = runProgram(t, "top", "-by", "mean", "-n", "1", "-asc", "-format", "csv", "-no-header", snapshotPath)
+	if expected := "Zürich,-3.2,0.5,4.1,2,0.9\n"; stdout != expected {
+		t.Errorf("top of a snapshot printed %q, expected %q", stdout, expected)
+	}
+
+	// Printed as a table unless another format is asked for
+	stdout, _ = runProgram(t, "top", "-n", "1", path)
+	if lines := strings.Split(strings.TrimSuffix(stdout, "\n"), "\n"); len(lines) != 3 || !strings.HasPrefix(lines[2], "Cork ") {
+		t.Errorf("top printed %q, expected a table holding Cork", stdout)
+	}
+}
+
+func TestOutliersCommand(t *testing.T) {
+
+	// Nine steady readings at Abha and one far above them
+	path := writeMeasurements(t, strings.Repeat("Abha;1.0\nCork;9.0\n", 9)+"Abha;30.0\n")
+	outliersPath := filepath.Join(t.TempDir(), "outliers.csv")
+
+	stdout, stderr := runProgram(t, "outliers", "-method", "iqr", "-o", outliersPath, path)
+	if expected := "station,outliers,count,low,high\nAbha,1,10,1.0,1.0\n"; stdout != expected {
+		t.Errorf("outliers printed %q, expected %q", stdout, expected)
+	}
+	if expected := "Flagged 1 rows across 1 of 2 stations into " + outliersPath + "\n"; stderr != expected {
+		t.Errorf("outliers reported %q, expected %q", stderr, expected)
+	}
+
+	outliers, err := os.ReadFile(outliersPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := "offset,station,temperature\n162,Abha,30.0\n"; string(outliers) != expected {
+		t.Errorf("outliers wrote %q, expected %q", outliers, expected)
+	}
+}
+
+func TestInspectCommand(t *testing.T) {
+
+	path := writeMeasurements(t, testMeasurements)
+
+	stdout, _, exitCode := runProgramStatus(t, "inspect", "-cardinality", path)
+	if exitCode != 0 || !strings.HasPrefix(stdout, "~3 stations across 5 rows (standard error ") {
+		t.Errorf("inspect -cardinality printed %q, exit status %v", stdout, exitCode)
+	}
+
+	_, stderr, exitCode := runProgramStatus(t, "inspect", "-cardinality", "-limit", "2", path)
+	if exitCode != 1 || stderr != "Estimate of 3 stations is above the limit of 2\n" {
+		t.Errorf("inspect -limit 2 reported %q, exit status %v", stderr, exitCode)
+	}
+}
+
+func TestTimeseriesCommand(t *testing.T) {
+
+	path := writeMeasurements(t, "Abha;12.5;2024-03-01T10:00:00Z\nAbha;-0.5;2024-03-01T23:00:00Z\n"+
+		"Abha;1.0;2024-03-02T01:00:00Z\nCork;9.0;2024-03-01T05:00:00+01:00\nbad row\n")
+
+	stdout, stderr := runProgram(t, "timeseries", "-bucket", "day", path)
+	expected := "station,bucket,min,mean,max,count,sum\nAbha,2024-03-01T00:00:00Z,-0.5,6.0,12.5,2,12.0\n" +
+		"Abha,2024-03-02T00:00:00Z,1.0,1.0,1.0,1,1.0\nCork,2024-03-01T00:00:00Z,9.0,9.0,9.0,1,9.0\n"
+	if stdout != expected {
+		t.Errorf("timeseries printed %q, expected %q", stdout, expected)
+	}
+	if stderr != "Skipped 1 rows that could not be read\n" {
+		t.Errorf("timeseries reported %q", stderr)
+	}
+
+	// Buckets begin at midnight within the time zone asked for
+	stdout, _ = runProgram(t, "timeseries", "-bucket", "day", "-tz", "Asia/Tokyo", "-format", "ndjson", path)
+	if lines := strings.Split(strings.TrimSuffix(stdout, "\n"), "\n"); len(lines) != 3 ||
+		!strings.Contains(lines[0], `"start":"2024-03-01T00:00:00+09:00"`) || !strings.Contains(lines[1], `"start":"2024-03-02T00:00:00+09:00"`) {
+		t.Errorf("timeseries -tz Asia/Tokyo printed %q", stdout)
+	}
+}
+
+func TestRollupCommand(t *testing.T) {
+
+	path := writeMeasurements(t, testMeasurements)
+	directory := t.TempDir()
+
+	metadataPath := filepath.Join(directory, "stations.csv")
+	metadata := "station,country,region\nAbha,Saudi Arabia,Asia\nZürich,Switzerland,Europe\n"
+	if err := os.WriteFile(metadataPath, []byte(metadata), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	missingPath := filepath.Join(directory, "missing.txt")
+
+	stdout, stderr := runProgram(t, "rollup", "-metadata", metadataPath, "-missing", missingPath, path)
+	if expected := "{(unknown)=9.0/9.0/9.0, Saudi Arabia=-0.5/6.0/12.5, Switzerland=-3.2/0.5/4.1}\n"; stdout != expected {
+		t.Errorf("rollup printed %q, expected %q", stdout, expected)
+	}
+	if expected := "1 of 3 stations are missing from the metadata: Cork\n"; stderr != expected {
+		t.Errorf("rollup reported %q, expected %q", stderr, expected)
+	}
+	if missing, err := os.ReadFile(missingPath); err != nil || string(missing) != "Cork\n" {
+		t.Errorf("missing list = %q, %v", missing, err)
+	}
+
+	// A snapshot rolls up the same as the file it came from
+	snapshotPath := filepath.Join(directory, "results.brcs")
+	runProgram(t, "aggregate", "-format", "snapshot", "-o", snapshotPath, path)
+
+	stdout, _ = runProgram(t, "rollup", "-metadata", metadataPath, "-level", "region", snapshotPath)
+	if expected := "{(unknown)/(no region)=9.0/9.0/9.0, Saudi Arabia/Asia=-0.5/6.0/12.5, Switzerland/Europe=-3.2/0.5/4.1}\n"; stdout != expected {
+		t.Errorf("rollup -level region printed %q, expected %q", stdout, expected)
+	}
+}
@@ -1,6 +1,7 @@
 package movetoroutines
 
 import (
+	formatter "billionRowChallenge/output"
 	"billionRowChallenge/utilities"
 	"errors"
 	"fmt"
@@ -11,14 +12,7 @@ import (
 	"sync"
 )
 
-type outputValues struct {
-	min   int
-	max   int
-	total int
-	count int
-}
-
-var output = make(map[string]outputValues)
+var output = make(map[string]utilities.OutputValues)
 
 // main - Core entry point to the Billion Row Challenge
 func BuildRoutinesMain() {
@@ -34,9 +28,20 @@ func BuildRoutinesMain() {
 	}
 	executablePath := filepath.Dir(goExecutable)
 
+	readMeasurements(filepath.Join(executablePath, "m.csv"), &entryWaitGroup)
+
+	fmt.Println(formatter.FormatResults(output))
+
+	// parserWaitGroup.Wait()
+	entryWaitGroup.Wait()
+}
+
+// readMeasurements - Reads the whole measurements file in `BufferSize` chunks, adding every row into the output map
+func readMeasurements(filename string, entryWaitGroup *sync.WaitGroup) {
+
 	// Check the current file size in number of bytes
 	// TODO: See if this step is faster than monitoring for an EOF during the parsing
-	f, err := os.Stat(filepath.Join(executablePath, "m.csv"))
+	f, err := os.Stat(filename)
 	if err != nil {
 		panic(err)
 	}
@@ -46,7 +51,7 @@ func BuildRoutinesMain() {
 	finalBufferSize := bytesInFile - (numberOfRoutineCalls * utilities.BufferSize)
 
 	// file, err := os.Open(filepath.Join(executablePath, "measurements.csv"))
-	file, err := os.Open(filepath.Join(executablePath, "m.csv"))
+	file, err := os.Open(filename)
 	if err != nil {
 		panic(err)
 	}
@@ -58,23 +63,18 @@ func BuildRoutinesMain() {
 
 	// Start up the channel calls
 	for i := range int64(numberOfRoutineCalls) {
-		partialReader(file, utilities.BufferSize, i*utilities.BufferSize, i, &entryWaitGroup)
+		partialReader(file, utilities.BufferSize, i*utilities.BufferSize, i, entryWaitGroup)
 	}
 
 	if finalBufferSize > 0 {
-		finalReader(file, finalBufferSize, numberOfRoutineCalls*utilities.BufferSize, int64(numberOfRoutineCalls), &entryWaitGroup)
+		finalReader(file, finalBufferSize, numberOfRoutineCalls*utilities.BufferSize, int64(numberOfRoutineCalls), entryWaitGroup)
 	}
-
-	fmt.Println(output)
-
-	// parserWaitGroup.Wait()
-	entryWaitGroup.Wait()
 }
 
 func partialReader(file *os.File, bufferSize int64, offset int64, index int64, entryWaitGroup *sync.WaitGroup) {
 
 	// Set a consistent buffer that will last through the entirety of the go routine running.
-	var buffer = make([]byte, 100)
+	var buffer = make([]byte, bufferSize)
 
 	// Move the reader to the offset value and read in the specified number of bytes
 	reader := io.NewSectionReader(file, offset, bufferSize)
@@ -370,20 +370,20 @@ func addOutput(city string, temperature int, entryWaitGroup *sync.WaitGroup) {
 	value, ok := output[city]
 	if !ok {
 
-		output[city] = outputValues{
-			min:   temperature,
-			max:   temperature,
-			total: temperature,
-			count: 1,
+		output[city] = utilities.OutputValues{
+			Min:   temperature,
+			Max:   temperature,
+			Total: temperature,
+			Count: 1,
 		}
 	} else {
-		if value.min > temperature {
-			value.min = temperature
-		} else if value.max < temperature {
-			value.max = temperature
+		if value.Min > temperature {
+			value.Min = temperature
+		} else if value.Max < temperature {
+			value.Max = temperature
 		}
-		value.total += temperature
-		value.count++
+		value.Total += temperature
+		value.Count++
 
 		output[city] = value
 	}
@@ -0,0 +1,27 @@
+package movetoroutines
+
+import (
+	"billionRowChallenge/expectedOutput"
+	formatter "billionRowChallenge/output"
+	"billionRowChallenge/utilities"
+	"sync"
+	"testing"
+)
+
+// fixturePath - The measurements shared with the other strategies, so each is checked against the same answer
+const fixturePath = "../expectedOutput/testdata/measurements.txt"
+
+func TestReadMeasurementsMatchesExpectedOutput(t *testing.T) {
+
+	// The results are kept at the package level, so start over in case the test is run more than once
+	output = make(map[string]utilities.OutputValues)
+	partialReadMap = make(map[int64]CombinedReadFields)
+
+	var entryWaitGroup sync.WaitGroup
+	readMeasurements(fixturePath, &entryWaitGroup)
+	entryWaitGroup.Wait()
+
+	if answer, expected := formatter.FormatResults(output), expectedOutput.CalculateExpectedOutput(fixturePath); answer != expected {
+		t.Errorf("readMeasurements = %v, expected %v", answer, expected)
+	}
+}
@@ -0,0 +1,264 @@
+package multireader
+
+import (
+	"billionRowChallenge/parsers"
+	"billionRowChallenge/utilities"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"runtime"
+	"sync"
+)
+
+// AggregateSection - Reads the `[offset, offset+length)` range out of the file and adds every row into the output map.
+// The range must begin at the start of a row. Reads happen in `SectionBufferSize` passes, with any partial row at the
+// end of a pass carried over to the front of the buffer for the next pass. A final row without a newline is still
+// parsed, the same as the stream reader does.
+//
+// Returns the number of bytes that were consumed, which is the whole range unless a read fails.
+func AggregateSection(file io.ReaderAt, offset int64, length int64, outputMap map[string]utilities.OutputValues) (int64, error) {
+	return AggregateSectionCollecting(file, offset, length, outputMap, nil)
+}
+
+// AggregateSectionCollecting - The same as `AggregateSection`, while also handing every reading to the collector.
+// A nil collector falls back to the plain parse.
+func AggregateSectionCollecting(file io.ReaderAt, offset int64, length int64, outputMap map[string]utilities.OutputValues,
+	collector utilities.Collector) (int64, error) {
+	return aggregateSection(file, offset, length, outputMap, collector, true)
+}
+
+// aggregateSection - `AggregateSectionCollecting`, with `finalRow` choosing whether an unterminated row at the end of
+// the range is parsed or left unread
+func aggregateSection(file io.ReaderAt, offset int64, length int64, outputMap map[string]utilities.OutputValues,
+	collector utilities.Collector, finalRow bool) (int64, error) {
+
+	parseRows := func(byteData []byte, bufferOffset int64) int {
+		return parsers.ParseRows(byteData, outputMap)
+	}
+	if collector != nil {
+		parseRows = func(byteData []byte, bufferOffset int64) int {
+			return parsers.ParseRowsFunc(byteData, func(station string, temperature int) {
+				utilities.AddTemperature(outputMap, station, temperature)
+				collector.Add(station, temperature)
+			})
+		}
+	}
+
+	return readSection(file, offset, length, finalRow, parseRows)
+}
+
+// ScanSection - Reads the `[offset, offset+length)` range the same as `AggregateSection`, handing every complete row to
+// `record` along with the file offset the row begins at, rather than aggregating it. Used by passes that need to point
+// back at individual rows. The station points into the read buffer, so it must be copied before it is kept.
+func ScanSection(file io.ReaderAt, offset int64, length int64, record func(rowOffset int64, station []byte, temperature int)) (int64, error) {
+	return ReadSection(file, offset, length, func(byteData []byte, bufferOffset int64) int {
+		return parsers.ParseRowsAt(byteData, func(rowStart int, station []byte, temperature int) {
+			record(bufferOffset+int64(rowStart), station, temperature)
+		})
+	})
+}
+
+// ScanSectionStations - Reads the `[offset, offset+length)` range the same as `AggregateSection`, handing only the
+// station name of every complete row to `record`. The name points into the read buffer, so it must not be kept.
+func ScanSectionStations(file io.ReaderAt, offset int64, length int64, record func(station []byte)) (int64, error) {
+	return ReadSection(file, offset, length, func(byteData []byte, bufferOffset int64) int {
+		return parsers.ParseStations(byteData, record)
+	})
+}
+
+// ReadSection - Feeds the `[offset, offset+length)` range through `parseRows` in `SectionBufferSize` passes, along with
+// the file offset each pass begins at. `parseRows` returns the bytes it consumed, with the partial row left over carried
+// into the next pass. A row left over once the range runs out is given a newline and handed over one last time. The
+// range must begin at the start of a row. Lets other row layouts reuse the same reading.
+func ReadSection(file io.ReaderAt, offset int64, length int64, parseRows func(byteData []byte, bufferOffset int64) int) (int64, error) {
+	return readSection(file, offset, length, true, parseRows)
+}
+
+// readSection - `ReadSection`, with `finalRow` choosing whether an unterminated row at the end of the range is parsed
+// or left unread for a later run
+func readSection(file io.ReaderAt, offset int64, length int64, finalRow bool,
+	parseRows func(byteData []byte, bufferOffset int64) int) (int64, error) {
+
+	var readBuffer = make([]byte, min(utilities.SectionBufferSize, length))
+	var carriedBytes int    // Number of bytes at the front of the buffer left over from the previous pass
+	var bytesRead int64     // Bytes that have been read out of the range so far
+	var bytesConsumed int64 // Bytes that have been fully parsed into the output map
+
+	reader := io.NewSectionReader(file, offset, length)
+
+	for {
+
+		// Fill the remainder of the buffer
+		n, err := io.ReadFull(reader, readBuffer[carriedBytes:])
+		if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+			return bytesConsumed, err
+		}
+		bufferedBytes := carriedBytes + n
+		bytesRead += int64(n)
+
+		rowBytes := parseRows(readBuffer[:bufferedBytes], offset+bytesConsumed)
+		bytesConsumed += int64(rowBytes)
+
+		// Nothing else to read, so whatever remains is an unterminated row. The full slice expression makes the append
+		// copy the row, rather than writing the newline over the buffer.
+		if n == 0 || err != nil || bytesRead == length {
+			if finalRow && rowBytes < bufferedBytes {
+				finalBytes := parseRows(append(readBuffer[rowBytes:bufferedBytes:bufferedBytes], utilities.NewLineHex), offset+bytesConsumed)
+				bytesConsumed += int64(min(finalBytes, bufferedBytes-rowBytes))
+			}
+			return bytesConsumed, nil
+		}
+
+		// A single row is larger than the whole buffer and can never be completed
+		if rowBytes == 0 && bufferedBytes == len(readBuffer) {
+			return bytesConsumed, fmt.Errorf("row at offset %v is longer than the %v byte buffer", offset+bytesConsumed, len(readBuffer))
+		}
+
+		// Move the partial row to the front of the buffer for the next pass
+		carriedBytes = copy(readBuffer, readBuffer[rowBytes:bufferedBytes])
+	}
+}
+
+// PlanSections - Splits the `[start, end)` range of the file into roughly equal sections. Every split is moved forward to
+// sit directly after a newline, so each section can be parsed on its own without linking partial reads together.
+// Returns the boundaries of the sections, beginning with `start` and finishing with `end`.
+func PlanSections(file io.ReaderAt, start int64, end int64, numberOfSections int) ([]int64, error) {
+
+	var boundaries = []int64{start}
+	var searchBuffer = make([]byte, 4096)
+
+	sectionSize := (end - start) / int64(max(numberOfSections, 1))
+
+	for sectionIndex := 1; sectionIndex < numberOfSections && sectionSize > 0; sectionIndex++ {
+
+		// Never step backwards over a split that was already pushed forward by a long row
+		splitOffset := max(start+int64(sectionIndex)*sectionSize, boundaries[len(boundaries)-1])
+
+		// Scan forward until the next newline is found
+		for splitOffset < end {
+			n, err := file.ReadAt(searchBuffer[:min(int64(len(searchBuffer)), end-splitOffset)], splitOffset)
+			if n == 0 && err != nil {
+				return nil, err
+			}
+
+			newLineIndex := bytes.IndexByte(searchBuffer[:n], utilities.NewLineHex)
+			if newLineIndex >= 0 {
+				splitOffset += int64(newLineIndex) + 1
+				break
+			}
+			splitOffset += int64(n)
+		}
+
+		if splitOffset >= end {
+			break
+		}
+		if splitOffset > boundaries[len(boundaries)-1] {
+			boundaries = append(boundaries, splitOffset)
+		}
+	}
+
+	return append(boundaries, end), nil
+}
+
+// AggregateFile - Aggregates the `[start, end)` range of the file, which must begin at the start of a row. The range is
+// planned into one section per core, each section builds its own map within a routine, and once every routine is done
+// those maps are combined into a singular output map. A final row without a newline is parsed along with the rest.
+//
+// Returns the output map and the offset directly after the last row that was parsed.
+func AggregateFile(file io.ReaderAt, start int64, end int64) (map[string]utilities.OutputValues, int64, error) {
+	return aggregateFile(file, start, end, true)
+}
+
+// AggregateCompleteRows - The same as `AggregateFile`, except a final row without a newline is left unread, as it may
+// still be being written. Returns the offset directly after the last complete row, so an incremental run can pick the
+// partial row back up once it has been finished.
+func AggregateCompleteRows(file io.ReaderAt, start int64, end int64) (map[string]utilities.OutputValues, int64, error) {
+	return aggregateFile(file, start, end, false)
+}
+
+// aggregateFile - Plans the range into a section per core and aggregates them, parsing the unterminated final row
+// only when `finalRow` is set
+func aggregateFile(file io.ReaderAt, start int64, end int64, finalRow bool) (map[string]utilities.OutputValues, int64, error) {
+
+	boundaries, err := PlanSections(file, start, end, runtime.NumCPU())
+	if err != nil {
+		return nil, start, err
+	}
+
+	outputMap, _, processedEnd, err := aggregateSections(file, boundaries, nil, finalRow)
+	return outputMap, processedEnd, err
+}
+
+// AggregateSections - Parses each section between the given boundaries within its own routine and combines the
+// results. Returns the output map and the offset directly after the last row that was parsed.
+func AggregateSections(file io.ReaderAt, boundaries []int64) (map[string]utilities.OutputValues, int64, error) {
+	outputMap, _, processedEnd, err := AggregateSectionsCollecting(file, boundaries, nil)
+	return outputMap, processedEnd, err
+}
+
+// AggregateSectionsCollecting - The same as `AggregateSections`, while every section also fills in its own collector
+// made by `newCollector`. The collectors are merged into the first one, which is returned. A nil `newCollector` skips
+// the collecting and returns a nil collector.
+func AggregateSectionsCollecting(file io.ReaderAt, boundaries []int64, newCollector func() utilities.Collector) (
+	map[string]utilities.OutputValues, utilities.Collector, int64, error) {
+	return aggregateSections(file, boundaries, newCollector, true)
+}
+
+// aggregateSections - `AggregateSectionsCollecting`, with `finalRow` choosing whether an unterminated row at the end of
+// the final section is parsed or left unread
+func aggregateSections(file io.ReaderAt, boundaries []int64, newCollector func() utilities.Collector, finalRow bool) (
+	map[string]utilities.OutputValues, utilities.Collector, int64, error) {
+
+	var waitGroup sync.WaitGroup
+	var sectionMaps = make([]map[string]utilities.OutputValues, len(boundaries)-1)
+	var sectionConsumed = make([]int64, len(boundaries)-1)
+	var sectionErrors = make([]error, len(boundaries)-1)
+	var sectionCollectors = make([]utilities.Collector, len(boundaries)-1)
+
+	for sectionIndex := range len(boundaries) - 1 {
+		waitGroup.Add(1)
+
+		go func() {
+			defer waitGroup.Done()
+
+			sectionMaps[sectionIndex] = make(map[string]utilities.OutputValues)
+			if newCollector != nil {
+				sectionCollectors[sectionIndex] = newCollector()
+			}
+			sectionConsumed[sectionIndex], sectionErrors[sectionIndex] = aggregateSection(
+				file,
+				boundaries[sectionIndex],
+				boundaries[sectionIndex+1]-boundaries[sectionIndex],
+				sectionMaps[sectionIndex],
+				sectionCollectors[sectionIndex],
+				finalRow,
+			)
+		}()
+	}
+
+	waitGroup.Wait()
+
+	var outputMap = make(map[string]utilities.OutputValues)
+	var collector utilities.Collector
+	if newCollector != nil {
+		collector = newCollector()
+	}
+
+	for sectionIndex := range sectionMaps {
+		if sectionErrors[sectionIndex] != nil {
+			return nil, nil, boundaries[0], sectionErrors[sectionIndex]
+		}
+		utilities.MergeOutputMaps(outputMap, sectionMaps[sectionIndex])
+		if collector != nil {
+			collector.Merge(sectionCollectors[sectionIndex])
+		}
+	}
+
+	// Only the final section can finish on a partial row, as every other section ends on a newline
+	lastSection := len(boundaries) - 2
+	if lastSection < 0 {
+		return outputMap, collector, boundaries[0], nil
+	}
+	return outputMap, collector, boundaries[lastSection] + sectionConsumed[lastSection], nil
+}
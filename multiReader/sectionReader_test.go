@@ -0,0 +1,223 @@
+package multireader
+
+import (
+	"billionRowChallenge/expectedOutput"
+	"billionRowChallenge/output"
+	"billionRowChallenge/utilities"
+	"bytes"
+	"fmt"
+	"maps"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// testRows - Rows spread over a few hundred stations, some with multi-byte names, covering every temperature shape
+func testRows(count int) []byte {
+
+	var rows []byte
+	for row := range count {
+		rows = fmt.Appendf(rows, "Station %v%v;%.1f\n", row%413, string([]rune("aåé")[row%3]), float64(row*7919%1999-999)/10)
+	}
+	return rows
+}
+
+// aggregateLines - The results worked out a line at a time, to check the section readers against. A final line without
+// a newline counts the same as every other line.
+func aggregateLines(data []byte) map[string]utilities.OutputValues {
+
+	outputMap := make(map[string]utilities.OutputValues)
+	for _, line := range strings.SplitAfter(string(data), "\n") {
+		if line == "" {
+			continue
+		}
+		station, temperature, _ := strings.Cut(strings.TrimSuffix(line, "\n"), ";")
+		value, err := strconv.ParseFloat(temperature, 64)
+		if err != nil {
+			panic(err)
+		}
+		utilities.AddTemperature(outputMap, station, int(math.Round(value*10)))
+	}
+	return outputMap
+}
+
+func writeTestFile(t *testing.T, data []byte) *os.File {
+
+	path := filepath.Join(t.TempDir(), "measurements.txt")
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { file.Close() })
+
+	return file
+}
+
+// countingCollector - Counts the readings of every station, to check the collectors are fed and merged
+type countingCollector map[string]int
+
+func (collector countingCollector) Add(station string, temperature int) {
+	collector[station]++
+}
+
+func (collector countingCollector) Merge(other utilities.Collector) {
+	for station, count := range other.(countingCollector) {
+		collector[station] += count
+	}
+}
+
+func TestAggregateSectionsEqualsSinglePass(t *testing.T) {
+
+	data := testRows(20_000)
+	file := writeTestFile(t, data)
+	expected := aggregateLines(data)
+
+	for _, numberOfSections := range []int{1, 2, 3, 7, 64, 100_000} {
+		t.Run(strconv.Itoa(numberOfSections), func(t *testing.T) {
+			boundaries, err := PlanSections(file, 0, int64(len(data)), numberOfSections)
+			if err != nil {
+				t.Fatalf("PlanSections: %v", err)
+			}
+			for _, boundary := range boundaries[1 : len(boundaries)-1] {
+				if data[boundary-1] != '\n' {
+					t.Fatalf("boundary %v does not sit directly after a newline", boundary)
+				}
+			}
+
+			outputMap, collector, processedEnd, err := AggregateSectionsCollecting(file, boundaries, func() utilities.Collector {
+				return countingCollector{}
+			})
+			if err != nil {
+				t.Fatalf("AggregateSectionsCollecting: %v", err)
+			}
+			if processedEnd != int64(len(data)) {
+				t.Errorf("processed up to %v, expected %v", processedEnd, len(data))
+			}
+			if !maps.Equal(outputMap, expected) {
+				t.Errorf("merged sections differ from a single pass over the file")
+			}
+
+			for station, outputValues := range expected {
+				if count := collector.(countingCollector)[station]; count != outputValues.Count {
+					t.Errorf("collector counted %v readings of %v, expected %v", count, station, outputValues.Count)
+				}
+			}
+		})
+	}
+}
+
+func TestAggregateSectionCarriesRows(t *testing.T) {
+
+	// More than one read buffer, so rows are carried over between passes
+	data := testRows(int(utilities.SectionBufferSize/16) + 1_000)
+	if int64(len(data)) <= utilities.SectionBufferSize {
+		t.Fatalf("only %v bytes of rows, expected more than %v", len(data), utilities.SectionBufferSize)
+	}
+	data = append(data, "Abha;12.5"...)
+	file := writeTestFile(t, data)
+
+	outputMap := make(map[string]utilities.OutputValues)
+	consumed, err := AggregateSection(file, 0, int64(len(data)), outputMap)
+	if err != nil {
+		t.Fatalf("AggregateSection: %v", err)
+	}
+
+	// The unterminated row is parsed along with the rest
+	if consumed != int64(len(data)) {
+		t.Errorf("consumed %v bytes, expected %v", consumed, len(data))
+	}
+	if !maps.Equal(outputMap, aggregateLines(data)) {
+		t.Error("section differs from a single pass over the file")
+	}
+}
+
+func TestAggregateFileUnterminatedRow(t *testing.T) {
+
+	terminated := testRows(20_000)
+	data := append(terminated, "Abha;-12.3"...)
+	file := writeTestFile(t, data)
+
+	outputMap, processedEnd, err := AggregateFile(file, 0, int64(len(data)))
+	if err != nil {
+		t.Fatalf("AggregateFile: %v", err)
+	}
+	if processedEnd != int64(len(data)) || !maps.Equal(outputMap, aggregateLines(data)) {
+		t.Errorf("AggregateFile processed up to %v, expected the final row to be counted up to %v", processedEnd, len(data))
+	}
+
+	// Only an incremental run leaves the row for later, as it may still be being written
+	outputMap, processedEnd, err = AggregateCompleteRows(file, 0, int64(len(data)))
+	if err != nil {
+		t.Fatalf("AggregateCompleteRows: %v", err)
+	}
+	if processedEnd != int64(len(terminated)) || !maps.Equal(outputMap, aggregateLines(terminated)) {
+		t.Errorf("AggregateCompleteRows processed up to %v, expected the final row to be left at %v", processedEnd, len(terminated))
+	}
+}
+
+func TestReadSectionRejectsLongRows(t *testing.T) {
+
+	data := append(bytes.Repeat([]byte("a"), int(utilities.SectionBufferSize)+10), ";1.0\n"...)
+	file := writeTestFile(t, data)
+
+	if _, err := AggregateSection(file, 0, int64(len(data)), make(map[string]utilities.OutputValues)); err == nil {
+		t.Error("a row longer than the read buffer was accepted")
+	}
+}
+
+func TestScanSectionOffsets(t *testing.T) {
+
+	data := testRows(5_000)
+	file := writeTestFile(t, data)
+
+	boundaries, err := PlanSections(file, 0, int64(len(data)), 4)
+	if err != nil {
+		t.Fatalf("PlanSections: %v", err)
+	}
+
+	for sectionIndex := range len(boundaries) - 1 {
+		offset, length := boundaries[sectionIndex], boundaries[sectionIndex+1]-boundaries[sectionIndex]
+
+		_, err := ScanSection(file, offset, length, func(rowOffset int64, station []byte, temperature int) {
+			rowStation, _, _ := bytes.Cut(data[rowOffset:], []byte{';'})
+			if !bytes.Equal(rowStation, station) || (rowOffset > 0 && data[rowOffset-1] != '\n') {
+				t.Fatalf("row of %q reported at %v, which is not where it begins", station, rowOffset)
+			}
+		})
+		if err != nil {
+			t.Fatalf("ScanSection: %v", err)
+		}
+	}
+}
+
+func TestAggregateFileMatchesExpectedOutput(t *testing.T) {
+
+	// The measurements shared with the other strategies, so each is checked against the same answer
+	const fixturePath = "../expectedOutput/testdata/measurements.txt"
+
+	file, err := os.Open(fixturePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	fileInfo, err := file.Stat()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	outputMap, _, err := AggregateFile(file, 0, fileInfo.Size())
+	if err != nil {
+		t.Fatalf("AggregateFile: %v", err)
+	}
+	if answer, expected := output.FormatResults(outputMap), expectedOutput.CalculateExpectedOutput(fixturePath); answer != expected {
+		t.Errorf("AggregateFile = %v, expected %v", answer, expected)
+	}
+}
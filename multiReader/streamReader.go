@@ -0,0 +1,107 @@
+package multireader
+
+import (
+	"billionRowChallenge/parsers"
+	"billionRowChallenge/utilities"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"runtime"
+	"sync"
+)
+
+// AggregateStream - Aggregates measurements from a reader that can only be read front to back, such as a member of a
+// compressed archive. The stream is read in `SectionBufferSize` chunks, each chunk is cut at its final newline, and the
+// complete rows are handed off to one parsing routine per core. Every routine builds its own map, which are combined
+// once the stream ends.
+//
+// The final row of the stream does not need a trailing newline. Returns the output map and the number of bytes read.
+func AggregateStream(reader io.Reader) (map[string]utilities.OutputValues, int64, error) {
+
+	var numberOfRoutines = runtime.NumCPU()
+	var waitGroup sync.WaitGroup
+
+	// Buffers travel from the reader, to a parsing routine, and back again once parsed
+	var chunkChannel = make(chan []byte, numberOfRoutines)
+	var freeBuffers = make(chan []byte, numberOfRoutines+1)
+	for range numberOfRoutines + 1 {
+		freeBuffers <- make([]byte, utilities.SectionBufferSize)
+	}
+
+	var routineMaps = make([]map[string]utilities.OutputValues, numberOfRoutines)
+	for routineIndex := range numberOfRoutines {
+		routineMaps[routineIndex] = make(map[string]utilities.OutputValues)
+
+		waitGroup.Add(1)
+		go func() {
+			defer waitGroup.Done()
+
+			for chunk := range chunkChannel {
+				parsers.ParseRows(chunk, routineMaps[routineIndex])
+				freeBuffers <- chunk[:cap(chunk)]
+			}
+		}()
+	}
+
+	bytesRead, err := feedChunks(reader, chunkChannel, freeBuffers)
+
+	close(chunkChannel)
+	waitGroup.Wait()
+
+	if err != nil {
+		return nil, bytesRead, err
+	}
+
+	var outputMap = make(map[string]utilities.OutputValues)
+	for _, routineMap := range routineMaps {
+		utilities.MergeOutputMaps(outputMap, routineMap)
+	}
+
+	return outputMap, bytesRead, nil
+}
+
+// feedChunks - Reads the stream into free buffers and sends every run of complete rows off to the parsing routines.
+// The partial row at the end of each chunk is copied to the front of the next buffer.
+func feedChunks(reader io.Reader, chunkChannel chan<- []byte, freeBuffers chan []byte) (int64, error) {
+
+	var bytesRead int64
+	var carriedRow []byte // Partial row left at the end of the previous chunk
+
+	for {
+		readBuffer := <-freeBuffers
+		carriedBytes := copy(readBuffer, carriedRow)
+
+		n, err := io.ReadFull(reader, readBuffer[carriedBytes:])
+		bytesRead += int64(n)
+		bufferedBytes := carriedBytes + n
+
+		// The stream is finished, so send everything that is left. A missing final newline is added back on, the same
+		// as `ReadSection` does, so a stream and a file holding the same bytes agree.
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			if bufferedBytes > 0 && readBuffer[bufferedBytes-1] != utilities.NewLineHex {
+				if bufferedBytes == len(readBuffer) {
+					readBuffer = append(readBuffer, utilities.NewLineHex)
+				} else {
+					readBuffer[bufferedBytes] = utilities.NewLineHex
+				}
+				bufferedBytes++
+			}
+			chunkChannel <- readBuffer[:bufferedBytes]
+			return bytesRead, nil
+		} else if err != nil {
+			freeBuffers <- readBuffer
+			return bytesRead, err
+		}
+
+		rowsEnd := bytes.LastIndexByte(readBuffer, utilities.NewLineHex) + 1
+		if rowsEnd == 0 {
+			freeBuffers <- readBuffer
+			return bytesRead, fmt.Errorf("row at byte %v is longer than the %v byte buffer", bytesRead-int64(bufferedBytes), len(readBuffer))
+		}
+
+		// Hold onto the partial row before the buffer is handed over
+		carriedRow = append(carriedRow[:0], readBuffer[rowsEnd:]...)
+		chunkChannel <- readBuffer[:rowsEnd]
+	}
+}
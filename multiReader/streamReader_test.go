@@ -0,0 +1,79 @@
+package multireader
+
+import (
+	"billionRowChallenge/utilities"
+	"bytes"
+	"maps"
+	"testing"
+)
+
+func TestAggregateStream(t *testing.T) {
+
+	data := testRows(int(utilities.SectionBufferSize/16) + 1_000)
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"empty", nil},
+		{"terminated", data},
+		{"unterminated", append(data, "Abha;-12.3"...)},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			outputMap, bytesRead, err := AggregateStream(bytes.NewReader(test.data))
+			if err != nil {
+				t.Fatalf("AggregateStream: %v", err)
+			}
+			if bytesRead != int64(len(test.data)) {
+				t.Errorf("read %v bytes, expected %v", bytesRead, len(test.data))
+			}
+
+			// The final row of a stream counts even without its newline
+			rows := test.data
+			if len(rows) > 0 && rows[len(rows)-1] != '\n' {
+				rows = append(rows, '\n')
+			}
+			if !maps.Equal(outputMap, aggregateLines(rows)) {
+				t.Error("stream differs from a single pass over the rows")
+			}
+		})
+	}
+}
+
+func TestAggregateStreamMatchesFile(t *testing.T) {
+
+	data := testRows(int(utilities.SectionBufferSize/16) + 1_000)
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"empty", nil},
+		{"terminated", data},
+		{"unterminated", append(data[:len(data):len(data)], "Abha;-12.3"...)},
+		{"unterminated malformed", append(data[:len(data):len(data)], "Abha;12"...)},
+		{"malformed rows", append([]byte("Abha\nCork;1\n;\n"), "Cork;9.0"...)},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			streamMap, _, err := AggregateStream(bytes.NewReader(test.data))
+			if err != nil {
+				t.Fatalf("AggregateStream: %v", err)
+			}
+
+			fileMap, processedEnd, err := AggregateFile(bytes.NewReader(test.data), 0, int64(len(test.data)))
+			if err != nil {
+				t.Fatalf("AggregateFile: %v", err)
+			}
+			if processedEnd != int64(len(test.data)) {
+				t.Errorf("AggregateFile processed up to %v, expected %v", processedEnd, len(test.data))
+			}
+			if !maps.Equal(streamMap, fileMap) {
+				t.Errorf("AggregateStream = %v, expected the same as AggregateFile %v", streamMap, fileMap)
+			}
+		})
+	}
+}
@@ -1,6 +1,7 @@
 package noroutines
 
 import (
+	formatter "billionRowChallenge/output"
 	"billionRowChallenge/utilities"
 	"errors"
 	"fmt"
@@ -10,14 +11,7 @@ import (
 	"strconv"
 )
 
-type outputValues struct {
-	min   int
-	max   int
-	total int
-	count int
-}
-
-var output = make(map[string]outputValues)
+var output = make(map[string]utilities.OutputValues)
 
 // main - Core entry point to the Billion Row Challenge
 func NoRoutineMain() {
@@ -30,9 +24,17 @@ func NoRoutineMain() {
 	}
 	executablePath := filepath.Dir(goExecutable)
 
+	readMeasurements(filepath.Join(executablePath, "measurements.csv"))
+
+	fmt.Println(formatter.FormatResults(output))
+}
+
+// readMeasurements - Reads the whole measurements file in `BufferSize` chunks, adding every row into the output map
+func readMeasurements(filename string) {
+
 	// Check the current file size in number of bytes
 	// TODO: See if this step is faster than monitoring for an EOF during the parsing
-	f, err := os.Stat(filepath.Join(executablePath, "measurements.csv"))
+	f, err := os.Stat(filename)
 	if err != nil {
 		panic(err)
 	}
@@ -42,7 +44,7 @@ func NoRoutineMain() {
 	finalBufferSize := bytesInFile - (numberOfRoutineCalls * utilities.BufferSize)
 
 	// file, err := os.Open(filepath.Join(executablePath, "measurements.csv"))
-	file, err := os.Open(filepath.Join(executablePath, "measurements.csv"))
+	file, err := os.Open(filename)
 	if err != nil {
 		panic(err)
 	}
@@ -60,14 +62,12 @@ func NoRoutineMain() {
 	if finalBufferSize > 0 {
 		finalReader(file, finalBufferSize, numberOfRoutineCalls*utilities.BufferSize, int64(numberOfRoutineCalls))
 	}
-
-	fmt.Println(output)
 }
 
 func partialReader(file *os.File, bufferSize int64, offset int64, index int64) {
 
 	// Set a consistent buffer that will last through the entirety of the go routine running.
-	var buffer = make([]byte, 100)
+	var buffer = make([]byte, bufferSize)
 
 	// Move the reader to the offset value and read in the specified number of bytes
 	reader := io.NewSectionReader(file, offset, bufferSize)
@@ -375,20 +375,20 @@ func addOutput(city string, temperature int) {
 	value, ok := output[city]
 	if !ok {
 
-		output[city] = outputValues{
-			min:   temperature,
-			max:   temperature,
-			total: temperature,
-			count: 1,
+		output[city] = utilities.OutputValues{
+			Min:   temperature,
+			Max:   temperature,
+			Total: temperature,
+			Count: 1,
 		}
 	} else {
-		if value.min > temperature {
-			value.min = temperature
-		} else if value.max < temperature {
-			value.max = temperature
+		if value.Min > temperature {
+			value.Min = temperature
+		} else if value.Max < temperature {
+			value.Max = temperature
 		}
-		value.total += temperature
-		value.count++
+		value.Total += temperature
+		value.Count++
 
 		output[city] = value
 	}
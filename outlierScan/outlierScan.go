@@ -0,0 +1,145 @@
+package outlierscan
+
+import (
+	multireader "billionRowChallenge/multiReader"
+	spreadstatistics "billionRowChallenge/spreadStatistics"
+	temperaturecounts "billionRowChallenge/temperatureCounts"
+	"io"
+	"math"
+	"os"
+	"sync"
+)
+
+// Fence - The readings a station is expected to stay within, in tenths of a degree. Anything strictly below `Low` or
+// strictly above `High` is an outlier.
+type Fence struct {
+	Low  float64
+	High float64
+}
+
+// Outside - Whether the reading falls beyond the fence
+func (fence Fence) Outside(temperature int) bool {
+	return float64(temperature) < fence.Low || float64(temperature) > fence.High
+}
+
+// Within - The lowest and highest whole tenths that stay within the fence
+func (fence Fence) Within() (low int, high int) {
+	return int(math.Ceil(fence.Low)), int(math.Floor(fence.High))
+}
+
+// SigmaFences - Fences `k` standard deviations either side of every station's mean
+func SigmaFences(spread spreadstatistics.Spread, k float64) map[string]Fence {
+
+	var fences = make(map[string]Fence, len(spread))
+	for station, spreadValues := range spread {
+		mean := float64(spreadValues.Total) / float64(spreadValues.Count)
+		reach := k * spreadValues.StandardDeviation() * 10 // Degrees over to tenths
+		fences[station] = Fence{Low: mean - reach, High: mean + reach}
+	}
+
+	return fences
+}
+
+// IQRFences - Tukey's fences: `k` interquartile ranges below the first quartile and above the third, using the exact
+// quartiles of every station
+func IQRFences(counts temperaturecounts.Counts, k float64) map[string]Fence {
+
+	var fences = make(map[string]Fence, len(counts))
+	for station, stationCounts := range counts {
+		quartiles := stationCounts.Percentiles(25, 75)
+		reach := k * float64(quartiles[1]-quartiles[0])
+		fences[station] = Fence{Low: float64(quartiles[0]) - reach, High: float64(quartiles[1]) + reach}
+	}
+
+	return fences
+}
+
+// Report - How many rows each station had beyond its fence, along with the total across every station
+type Report struct {
+	Counts   map[string]int
+	Outliers int
+}
+
+// Scan - The second pass. Reads each section between the boundaries within its own routine and writes every row beyond
+// its station's fence to the writer as csv, in file order, with the columns offset, station, and temperature.
+// Stations without a fence are never flagged.
+//
+// Each section streams its rows into its own temporary file, which are copied over to the writer one after another once
+// every section is done, so the outliers are never all held in memory however many there are.
+func Scan(file io.ReaderAt, boundaries []int64, fences map[string]Fence, writer io.Writer) (Report, error) {
+
+	var waitGroup sync.WaitGroup
+	var sectionFiles = make([]*os.File, len(boundaries)-1)
+	var sectionCounts = make([]map[string]int, len(boundaries)-1)
+	var sectionErrors = make([]error, len(boundaries)-1)
+
+	defer func() {
+		for _, sectionFile := range sectionFiles {
+			if sectionFile != nil {
+				sectionFile.Close()
+				os.Remove(sectionFile.Name())
+			}
+		}
+	}()
+
+	// Every file is created up front, so a failure never leaves routines behind
+	for sectionIndex := range sectionFiles {
+		sectionFile, err := os.CreateTemp("", "outliers-*.csv")
+		if err != nil {
+			return Report{}, err
+		}
+		sectionFiles[sectionIndex] = sectionFile
+		sectionCounts[sectionIndex] = make(map[string]int)
+	}
+
+	for sectionIndex := range len(boundaries) - 1 {
+		waitGroup.Add(1)
+
+		go func() {
+			defer waitGroup.Done()
+
+			outlierWriter := newOutlierWriter(sectionFiles[sectionIndex])
+			_, sectionErrors[sectionIndex] = multireader.ScanSection(
+				file,
+				boundaries[sectionIndex],
+				boundaries[sectionIndex+1]-boundaries[sectionIndex],
+				func(rowOffset int64, station []byte, temperature int) {
+					// Looking up with the bytes converted in place never copies the station name
+					if fence, ok := fences[string(station)]; ok && fence.Outside(temperature) {
+						sectionCounts[sectionIndex][string(station)]++
+						outlierWriter.write(rowOffset, string(station), temperature)
+					}
+				},
+			)
+			if err := outlierWriter.flush(); sectionErrors[sectionIndex] == nil {
+				sectionErrors[sectionIndex] = err
+			}
+		}()
+	}
+
+	waitGroup.Wait()
+
+	var report = Report{Counts: make(map[string]int)}
+	if err := writeOutlierHeader(writer); err != nil {
+		return Report{}, err
+	}
+
+	for sectionIndex, sectionFile := range sectionFiles {
+		if sectionErrors[sectionIndex] != nil {
+			return Report{}, sectionErrors[sectionIndex]
+		}
+		for station, count := range sectionCounts[sectionIndex] {
+			report.Counts[station] += count
+			report.Outliers += count
+		}
+
+		if _, err := sectionFile.Seek(0, io.SeekStart); err != nil {
+			return Report{}, err
+		}
+		if _, err := io.Copy(writer, sectionFile); err != nil {
+			return Report{}, err
+		}
+	}
+
+	return report, nil
+}
@@ -0,0 +1,117 @@
+package outlierscan
+
+import (
+	multireader "billionRowChallenge/multiReader"
+	spreadstatistics "billionRowChallenge/spreadStatistics"
+	temperaturecounts "billionRowChallenge/temperatureCounts"
+	"billionRowChallenge/utilities"
+	"bytes"
+	"fmt"
+	"maps"
+	"math"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestFence(t *testing.T) {
+
+	fence := Fence{Low: -10.5, High: 20}
+	for temperature, outside := range map[int]bool{-11: true, -10: false, 0: false, 20: false, 21: true} {
+		if fence.Outside(temperature) != outside {
+			t.Errorf("Outside(%v) = %v, expected %v", temperature, !outside, outside)
+		}
+	}
+	if low, high := fence.Within(); low != -10 || high != 20 {
+		t.Errorf("Within = %v, %v, expected -10, 20", low, high)
+	}
+}
+
+func TestFences(t *testing.T) {
+
+	spread := spreadstatistics.Spread{}
+	counts := temperaturecounts.Counts{}
+	for _, reading := range []int{10, 20, 30, 40, 50, 60, 70, 80} {
+		spread.Add("Abha", reading)
+		counts.Add("Abha", reading)
+	}
+
+	// A mean of 45 with a standard deviation of √525 tenths
+	sigma := SigmaFences(spread, 2)["Abha"]
+	if low, high := sigma.Within(); low != 0 || high != 90 {
+		t.Errorf("sigma fence within %v, %v, expected 0, 90", low, high)
+	}
+
+	// Quartiles of 20 and 60
+	if iqr := IQRFences(counts, 1.5)["Abha"]; iqr != (Fence{Low: -40, High: 120}) {
+		t.Errorf("IQR fence = %v, expected {-40 120}", iqr)
+	}
+}
+
+func TestScanMatchesEveryRow(t *testing.T) {
+
+	var data []byte
+	for row := range 20_000 {
+		data = fmt.Appendf(data, "Station %v;%.1f\n", row%7, float64(row*7919%1999-999)/10)
+	}
+	fences := map[string]Fence{}
+	for station := range 6 {
+		fences[fmt.Sprintf("Station %v", station)] = Fence{Low: float64(-900 + 100*station), High: float64(800 - 50*station)}
+	}
+
+	// Every row beyond its fence, worked out a line at a time
+	var expectedRows strings.Builder
+	expectedRows.WriteString("offset,station,temperature\n")
+	expectedCounts := map[string]int{}
+	var offset int
+	for _, line := range strings.SplitAfter(string(data), "\n") {
+		station, temperature, _ := strings.Cut(strings.TrimSuffix(line, "\n"), ";")
+		if value, err := strconv.ParseFloat(temperature, 64); err == nil {
+			if fence, ok := fences[station]; ok && fence.Outside(int(math.Round(value*10))) {
+				fmt.Fprintf(&expectedRows, "%v,%v,%v\n", offset, station, temperature)
+				expectedCounts[station]++
+			}
+		}
+		offset += len(line)
+	}
+
+	reader := bytes.NewReader(data)
+	for _, sectionCount := range []int{1, 3, 16} {
+		boundaries, err := multireader.PlanSections(reader, 0, int64(len(data)), sectionCount)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var buffer bytes.Buffer
+		report, err := Scan(reader, boundaries, fences, &buffer)
+		if err != nil {
+			t.Fatalf("Scan: %v", err)
+		}
+		if buffer.String() != expectedRows.String() {
+			t.Errorf("%v sections wrote different outliers to a line at a time", sectionCount)
+		}
+		if !maps.Equal(report.Counts, expectedCounts) || report.Outliers != strings.Count(expectedRows.String(), "\n")-1 {
+			t.Errorf("%v sections reported %v outliers, %v", sectionCount, report.Outliers, report.Counts)
+		}
+	}
+}
+
+func TestWriteCounts(t *testing.T) {
+
+	outputMap := map[string]utilities.OutputValues{
+		"Zürich": {Min: -50, Max: 300, Total: 300, Count: 4},
+		"Abha":   {Min: 0, Max: 100, Total: 100, Count: 2},
+		"Accra":  {Min: 0, Max: 0, Total: 0, Count: 1},
+	}
+	fences := map[string]Fence{"Zürich": {Low: -40.5, High: 250.5}, "Abha": {Low: 1, High: 99}, "Accra": {Low: -1, High: 1}}
+	report := Report{Counts: map[string]int{"Zürich": 2, "Abha": 1}, Outliers: 3}
+
+	var buffer bytes.Buffer
+	if err := WriteCounts(&buffer, report, outputMap, fences); err != nil {
+		t.Fatalf("WriteCounts: %v", err)
+	}
+	expected := "station,outliers,count,low,high\nAbha,1,2,0.1,9.9\nZürich,2,4,-4.0,25.0\n"
+	if buffer.String() != expected {
+		t.Errorf("counts = %q, expected %q", buffer.String(), expected)
+	}
+}
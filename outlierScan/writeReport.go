@@ -0,0 +1,78 @@
+package outlierscan
+
+import (
+	"billionRowChallenge/output"
+	"billionRowChallenge/utilities"
+	"bufio"
+	"encoding/csv"
+	"io"
+	"strconv"
+)
+
+// outlierColumns - Columns of the csv the outliers are written to
+var outlierColumns = []string{"offset", "station", "temperature"}
+
+// writeOutlierHeader - Writes the header row of the outliers csv
+func writeOutlierHeader(writer io.Writer) error {
+	csvWriter := csv.NewWriter(writer)
+	csvWriter.Write(outlierColumns)
+	csvWriter.Flush()
+	return csvWriter.Error()
+}
+
+// outlierWriter - Writes flagged rows as csv, without a header, so the rows of every section can be joined together
+type outlierWriter struct {
+	bufferedWriter *bufio.Writer
+	csvWriter      *csv.Writer
+	fields         []string
+}
+
+// newOutlierWriter - Creates the writer over the destination
+func newOutlierWriter(writer io.Writer) *outlierWriter {
+	bufferedWriter := bufio.NewWriter(writer)
+	return &outlierWriter{bufferedWriter: bufferedWriter, csvWriter: csv.NewWriter(bufferedWriter), fields: make([]string, 3)}
+}
+
+// write - Writes a single flagged row. Errors are held by the csv writer until `flush`.
+func (outlierWriter *outlierWriter) write(offset int64, station string, temperature int) {
+	outlierWriter.fields[0] = strconv.FormatInt(offset, 10)
+	outlierWriter.fields[1] = station
+	outlierWriter.fields[2] = output.FormatTemperature(temperature)
+	outlierWriter.csvWriter.Write(outlierWriter.fields)
+}
+
+// flush - Writes out everything still buffered, returning the first error hit along the way
+func (outlierWriter *outlierWriter) flush() error {
+	outlierWriter.csvWriter.Flush()
+	if err := outlierWriter.csvWriter.Error(); err != nil {
+		return err
+	}
+	return outlierWriter.bufferedWriter.Flush()
+}
+
+// WriteCounts - Writes every station holding an outlier as csv with the columns station, outliers, count, low, and
+// high, sorted by station. Low and high are the readings the fence let through.
+func WriteCounts(writer io.Writer, report Report, outputMap map[string]utilities.OutputValues, fences map[string]Fence) error {
+
+	csvWriter := csv.NewWriter(writer)
+	csvWriter.Write([]string{"station", "outliers", "count", "low", "high"})
+
+	for _, station := range output.SortedStations(outputMap) {
+		outliers, ok := report.Counts[station]
+		if !ok {
+			continue
+		}
+
+		low, high := fences[station].Within()
+		csvWriter.Write([]string{
+			station,
+			strconv.Itoa(outliers),
+			strconv.Itoa(outputMap[station].Count),
+			output.FormatTemperature(low),
+			output.FormatTemperature(high),
+		})
+	}
+
+	csvWriter.Flush()
+	return csvWriter.Error()
+}
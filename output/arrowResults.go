@@ -0,0 +1,209 @@
+package output
+
+import (
+	"billionRowChallenge/utilities"
+	"encoding/binary"
+	"io"
+)
+
+// Values taken from Arrow's `Schema.fbs` and `Message.fbs`
+const (
+	arrowMetadataVersionV5 = 4
+
+	arrowHeaderSchema          = 1
+	arrowHeaderDictionaryBatch = 2
+	arrowHeaderRecordBatch     = 3
+
+	arrowTypeInt     = 2
+	arrowTypeUtf8    = 5
+	arrowTypeDecimal = 7
+
+	arrowContinuationMarker = 0xFFFFFFFF
+)
+
+// Kinds of columns within the binary exports
+const (
+	columnStation = iota // Dictionary encoded station name
+	columnDecimal        // Temperature in tenths, written as a decimal with a scale of 1
+	columnInt64          // Plain count
+)
+
+// resultColumn - A single column shared by the Arrow and Parquet exports. Values are in tenths for decimal columns.
+type resultColumn struct {
+	name      string
+	kind      int
+	precision int
+	values    []int64
+}
+
+// buildResultColumns - Lays the results out as columns, one row per station in sorted order. The station column holds
+// the index of each station within the sorted station list, which doubles as the dictionary.
+func buildResultColumns(outputMap map[string]utilities.OutputValues, stations []string) []resultColumn {
+
+	var columns = []resultColumn{
+		{name: "station", kind: columnStation},
+		{name: "min", kind: columnDecimal, precision: 4},
+		{name: "mean", kind: columnDecimal, precision: 4},
+		{name: "max", kind: columnDecimal, precision: 4},
+		{name: "count", kind: columnInt64},
+		{name: "sum", kind: columnDecimal, precision: 18},
+	}
+	for columnIndex := range columns {
+		columns[columnIndex].values = make([]int64, len(stations))
+	}
+
+	for index, station := range stations {
+		outputValues := outputMap[station]
+
+		columns[0].values[index] = int64(index)
+		columns[1].values[index] = int64(outputValues.Min)
+		columns[2].values[index] = int64(RoundedMean(outputValues.Total, outputValues.Count))
+		columns[3].values[index] = int64(outputValues.Max)
+		columns[4].values[index] = int64(outputValues.Count)
+		columns[5].values[index] = int64(outputValues.Total)
+	}
+
+	return columns
+}
+
+// WriteArrowStream - Writes the results as an Arrow IPC stream: the schema, a dictionary batch holding the station names,
+// a single record batch, and the end of stream marker. The station column is dictionary encoded with 32 bit indices,
+// the temperatures are decimal128 values with a scale of 1, and the count is a 64 bit integer.
+func WriteArrowStream(writer io.Writer, outputMap map[string]utilities.OutputValues) error {
+
+	stations := SortedStations(outputMap)
+	columns := buildResultColumns(outputMap, stations)
+
+	// Schema
+	var fields = make([]*fbTable, len(columns))
+	for index, column := range columns {
+		fields[index] = arrowField(column)
+	}
+	schema := &fbTable{fields: []any{int16(0), fields}}
+	if err := writeArrowMessage(writer, arrowHeaderSchema, schema, nil); err != nil {
+		return err
+	}
+
+	// Dictionary holding every station name, in the same order as the indices
+	var dictionaryBody arrowBody
+	var stationOffsets = make([]byte, 0, 4*(len(stations)+1))
+	var stationBytes []byte
+	stationOffsets = binary.LittleEndian.AppendUint32(stationOffsets, 0)
+	for _, station := range stations {
+		stationBytes = append(stationBytes, station...)
+		stationOffsets = binary.LittleEndian.AppendUint32(stationOffsets, uint32(len(stationBytes)))
+	}
+	dictionaryBody.addBuffer(nil) // No nulls, so no validity bitmap
+	dictionaryBody.addBuffer(stationOffsets)
+	dictionaryBody.addBuffer(stationBytes)
+
+	dictionaryBatch := &fbTable{fields: []any{
+		int64(0),
+		dictionaryBody.recordBatch(fbStructs{{int64(len(stations)), 0}}, len(stations)),
+		false,
+	}}
+	if err := writeArrowMessage(writer, arrowHeaderDictionaryBatch, dictionaryBatch, dictionaryBody.data); err != nil {
+		return err
+	}
+
+	// Record batch holding every column
+	var recordBody arrowBody
+	var nodes fbStructs
+	for _, column := range columns {
+		nodes = append(nodes, [2]int64{int64(len(stations)), 0})
+		recordBody.addBuffer(nil)
+
+		var columnBytes []byte
+		for _, value := range column.values {
+			switch column.kind {
+			case columnStation:
+				columnBytes = binary.LittleEndian.AppendUint32(columnBytes, uint32(value))
+			case columnInt64:
+				columnBytes = binary.LittleEndian.AppendUint64(columnBytes, uint64(value))
+			case columnDecimal:
+				// 128 bit two's complement, low word first
+				columnBytes = binary.LittleEndian.AppendUint64(columnBytes, uint64(value))
+				columnBytes = binary.LittleEndian.AppendUint64(columnBytes, uint64(value>>63))
+			}
+		}
+		recordBody.addBuffer(columnBytes)
+	}
+
+	if err := writeArrowMessage(writer, arrowHeaderRecordBatch, recordBody.recordBatch(nodes, len(stations)), recordBody.data); err != nil {
+		return err
+	}
+
+	// End of stream
+	_, err := writer.Write([]byte{0xFF, 0xFF, 0xFF, 0xFF, 0x00, 0x00, 0x00, 0x00})
+	return err
+}
+
+// arrowField - Schema field for the column. The station field carries the value type, `Utf8`, along with the
+// dictionary encoding describing its 32 bit indices.
+func arrowField(column resultColumn) *fbTable {
+
+	// name, nullable, type_type, type, dictionary, children
+	switch column.kind {
+	case columnStation:
+		dictionary := &fbTable{fields: []any{int64(0), arrowIntType(32), false}}
+		return &fbTable{fields: []any{column.name, false, uint8(arrowTypeUtf8), &fbTable{}, dictionary, []*fbTable{}}}
+	case columnDecimal:
+		decimalType := &fbTable{fields: []any{int32(column.precision), int32(1), int32(128)}}
+		return &fbTable{fields: []any{column.name, false, uint8(arrowTypeDecimal), decimalType, nil, []*fbTable{}}}
+	default:
+		return &fbTable{fields: []any{column.name, false, uint8(arrowTypeInt), arrowIntType(64), nil, []*fbTable{}}}
+	}
+}
+
+// arrowIntType - Signed integer type of the given width
+func arrowIntType(bitWidth int32) *fbTable {
+	return &fbTable{fields: []any{bitWidth, true}}
+}
+
+// arrowBody - The buffers that follow a message, each padded out to 8 bytes
+type arrowBody struct {
+	data    []byte
+	buffers fbStructs
+}
+
+// addBuffer - Appends the buffer and records its offset and length within the body
+func (body *arrowBody) addBuffer(bufferBytes []byte) {
+
+	body.buffers = append(body.buffers, [2]int64{int64(len(body.data)), int64(len(bufferBytes))})
+	body.data = append(body.data, bufferBytes...)
+	for len(body.data)%8 != 0 {
+		body.data = append(body.data, 0)
+	}
+}
+
+// recordBatch - The record batch header describing the buffers within the body
+func (body *arrowBody) recordBatch(nodes fbStructs, length int) *fbTable {
+	return &fbTable{fields: []any{int64(length), nodes, body.buffers}}
+}
+
+// writeArrowMessage - Writes a single encapsulated message: the continuation marker, the metadata size, the metadata
+// padded so the body begins on an 8 byte boundary, and finally the body itself.
+func writeArrowMessage(writer io.Writer, headerType uint8, header *fbTable, body []byte) error {
+
+	metadata := encodeFlatBuffer(&fbTable{fields: []any{
+		int16(arrowMetadataVersionV5),
+		headerType,
+		header,
+		int64(len(body)),
+	}})
+	for len(metadata)%8 != 0 {
+		metadata = append(metadata, 0)
+	}
+
+	var prefix = make([]byte, 8)
+	binary.LittleEndian.PutUint32(prefix[0:], arrowContinuationMarker)
+	binary.LittleEndian.PutUint32(prefix[4:], uint32(len(metadata)))
+
+	for _, messageBytes := range [][]byte{prefix, metadata, body} {
+		if _, err := writer.Write(messageBytes); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
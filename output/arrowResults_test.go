@@ -0,0 +1,336 @@
+package output
+
+import (
+	"billionRowChallenge/utilities"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"maps"
+	"slices"
+	"testing"
+)
+
+// fbView - A table within a FlatBuffers buffer, read the way any FlatBuffers reader would, so the encoder is checked
+// against the format rather than against itself
+type fbView struct {
+	data     []byte
+	position int
+}
+
+func fbRoot(data []byte) fbView {
+	return fbView{data: data, position: int(binary.LittleEndian.Uint32(data))}
+}
+
+// field - Position of the field within the buffer, or zero when it was left unset
+func (view fbView) field(fieldID int) int {
+
+	vtable := view.position - int(int32(binary.LittleEndian.Uint32(view.data[view.position:])))
+	entry := 4 + 2*fieldID
+	if entry >= int(binary.LittleEndian.Uint16(view.data[vtable:])) {
+		return 0
+	}
+	if offset := int(binary.LittleEndian.Uint16(view.data[vtable+entry:])); offset != 0 {
+		return view.position + offset
+	}
+	return 0
+}
+
+func (view fbView) uint8(fieldID int) uint8 {
+	if position := view.field(fieldID); position != 0 {
+		return view.data[position]
+	}
+	return 0
+}
+
+func (view fbView) int16(fieldID int) int16 {
+	if position := view.field(fieldID); position != 0 {
+		return int16(binary.LittleEndian.Uint16(view.data[position:]))
+	}
+	return 0
+}
+
+func (view fbView) int32(fieldID int) int32 {
+	if position := view.field(fieldID); position != 0 {
+		return int32(binary.LittleEndian.Uint32(view.data[position:]))
+	}
+	return 0
+}
+
+func (view fbView) int64(fieldID int) int64 {
+	if position := view.field(fieldID); position != 0 {
+		return int64(binary.LittleEndian.Uint64(view.data[position:]))
+	}
+	return 0
+}
+
+func (view fbView) bool(fieldID int) bool {
+	return view.uint8(fieldID) != 0
+}
+
+// reference - Follows the forward offset stored within the field
+func (view fbView) reference(fieldID int) int {
+	position := view.field(fieldID)
+	if position == 0 {
+		return 0
+	}
+	return position + int(binary.LittleEndian.Uint32(view.data[position:]))
+}
+
+func (view fbView) table(fieldID int) (fbView, bool) {
+	position := view.reference(fieldID)
+	return fbView{data: view.data, position: position}, position != 0
+}
+
+func (view fbView) string(fieldID int) string {
+	position := view.reference(fieldID)
+	length := int(binary.LittleEndian.Uint32(view.data[position:]))
+	return string(view.data[position+4 : position+4+length])
+}
+
+func (view fbView) tables(fieldID int) []fbView {
+
+	position := view.reference(fieldID)
+	var tables []fbView
+	for index := range int(binary.LittleEndian.Uint32(view.data[position:])) {
+		slot := position + 4 + 4*index
+		tables = append(tables, fbView{data: view.data, position: slot + int(binary.LittleEndian.Uint32(view.data[slot:]))})
+	}
+	return tables
+}
+
+// structs - A vector of two-long structs, which must sit on an 8 byte boundary
+func (view fbView) structs(t *testing.T, fieldID int) [][2]int64 {
+
+	position := view.reference(fieldID)
+	if (position+4)%8 != 0 {
+		t.Errorf("structs at %v do not begin on an 8 byte boundary", position+4)
+	}
+
+	var structs [][2]int64
+	for index := range int(binary.LittleEndian.Uint32(view.data[position:])) {
+		start := position + 4 + 16*index
+		structs = append(structs, [2]int64{
+			int64(binary.LittleEndian.Uint64(view.data[start:])),
+			int64(binary.LittleEndian.Uint64(view.data[start+8:])),
+		})
+	}
+	return structs
+}
+
+// arrowMessage - A single encapsulated message out of the stream
+type arrowMessage struct {
+	headerType uint8
+	header     fbView
+	body       []byte
+}
+
+// readArrowStream - Splits the stream into its messages, checking the framing and alignment along the way
+func readArrowStream(t *testing.T, stream []byte) []arrowMessage {
+
+	var messages []arrowMessage
+	for position := 0; ; {
+		if len(stream)-position < 8 || binary.LittleEndian.Uint32(stream[position:]) != arrowContinuationMarker {
+			t.Fatalf("no continuation marker at %v", position)
+		}
+		metadataSize := int(binary.LittleEndian.Uint32(stream[position+4:]))
+		position += 8
+
+		if metadataSize == 0 {
+			if position != len(stream) {
+				t.Errorf("%v bytes after the end of stream marker", len(stream)-position)
+			}
+			return messages
+		}
+		if (position+metadataSize)%8 != 0 {
+			t.Errorf("message body at %v does not begin on an 8 byte boundary", position+metadataSize)
+		}
+
+		message := fbRoot(stream[position : position+metadataSize])
+		position += metadataSize
+
+		if version := message.int16(0); version != arrowMetadataVersionV5 {
+			t.Errorf("metadata version %v, expected %v", version, arrowMetadataVersionV5)
+		}
+		header, _ := message.table(2)
+		bodyLength := int(message.int64(3))
+
+		messages = append(messages, arrowMessage{headerType: message.uint8(1), header: header, body: stream[position : position+bodyLength]})
+		position += bodyLength
+	}
+}
+
+// arrowBuffers - The buffers of a record batch, cut out of the message body
+func arrowBuffers(t *testing.T, batch fbView, body []byte) [][]byte {
+
+	var buffers [][]byte
+	for _, buffer := range batch.structs(t, 2) {
+		if buffer[0]%8 != 0 {
+			t.Errorf("buffer at %v is not 8 byte aligned", buffer[0])
+		}
+		buffers = append(buffers, body[buffer[0]:buffer[0]+buffer[1]])
+	}
+	return buffers
+}
+
+func TestWriteArrowStream(t *testing.T) {
+
+	for _, outputMap := range []map[string]utilities.OutputValues{testOutputMap(), {}} {
+		t.Run(fmt.Sprintf("%v stations", len(outputMap)), func(t *testing.T) {
+			var stream bytes.Buffer
+			if err := WriteArrowStream(&stream, outputMap); err != nil {
+				t.Fatalf("WriteArrowStream: %v", err)
+			}
+
+			messages := readArrowStream(t, stream.Bytes())
+			if len(messages) != 3 {
+				t.Fatalf("%v messages, expected a schema, a dictionary, and a record batch", len(messages))
+			}
+			stations := SortedStations(outputMap)
+
+			checkArrowSchema(t, messages[0])
+			dictionary := readArrowDictionary(t, messages[1], len(stations))
+			if !slices.Equal(dictionary, stations) {
+				t.Errorf("dictionary = %q, expected %q", dictionary, stations)
+			}
+
+			decoded := readArrowRecordBatch(t, messages[2], dictionary)
+			if !maps.Equal(decoded, expectedColumnValues(outputMap)) {
+				t.Errorf("record batch = %v, expected %v", decoded, expectedColumnValues(outputMap))
+			}
+		})
+	}
+}
+
+// expectedColumnValues - Every station's row as the binary exports should hold it: min, mean, max, count, sum
+func expectedColumnValues(outputMap map[string]utilities.OutputValues) map[string][5]int64 {
+
+	rows := make(map[string][5]int64, len(outputMap))
+	for station, outputValues := range outputMap {
+		rows[station] = [5]int64{
+			int64(outputValues.Min),
+			int64(RoundedMean(outputValues.Total, outputValues.Count)),
+			int64(outputValues.Max),
+			int64(outputValues.Count),
+			int64(outputValues.Total),
+		}
+	}
+	return rows
+}
+
+func checkArrowSchema(t *testing.T, message arrowMessage) {
+
+	if message.headerType != arrowHeaderSchema || len(message.body) != 0 {
+		t.Fatalf("first message is type %v with a %v byte body, expected the schema", message.headerType, len(message.body))
+	}
+
+	expected := []struct {
+		name      string
+		typeType  uint8
+		precision int32
+	}{
+		{"station", arrowTypeUtf8, 0},
+		{"min", arrowTypeDecimal, 4},
+		{"mean", arrowTypeDecimal, 4},
+		{"max", arrowTypeDecimal, 4},
+		{"count", arrowTypeInt, 0},
+		{"sum", arrowTypeDecimal, 18},
+	}
+
+	fields := message.header.tables(1)
+	if len(fields) != len(expected) {
+		t.Fatalf("%v fields, expected %v", len(fields), len(expected))
+	}
+
+	for index, field := range fields {
+		if field.string(0) != expected[index].name || field.bool(1) || field.uint8(2) != expected[index].typeType {
+			t.Errorf("field %v is %q, nullable %v, type %v, expected %+v", index, field.string(0), field.bool(1), field.uint8(2), expected[index])
+		}
+
+		fieldType, _ := field.table(3)
+		dictionary, isDictionary := field.table(4)
+		switch expected[index].typeType {
+		case arrowTypeUtf8:
+			indexType, _ := dictionary.table(1)
+			if !isDictionary || dictionary.int64(0) != 0 || indexType.int32(0) != 32 || !indexType.bool(1) {
+				t.Errorf("station field is not dictionary encoded with signed 32 bit indices")
+			}
+		case arrowTypeDecimal:
+			if fieldType.int32(0) != expected[index].precision || fieldType.int32(1) != 1 || fieldType.int32(2) != 128 {
+				t.Errorf("field %v is decimal(%v, %v) over %v bits", expected[index].name, fieldType.int32(0), fieldType.int32(1), fieldType.int32(2))
+			}
+		case arrowTypeInt:
+			if fieldType.int32(0) != 64 || !fieldType.bool(1) {
+				t.Errorf("count field is not a signed 64 bit integer")
+			}
+		}
+		if isDictionary && expected[index].typeType != arrowTypeUtf8 {
+			t.Errorf("field %v is dictionary encoded", expected[index].name)
+		}
+	}
+}
+
+func readArrowDictionary(t *testing.T, message arrowMessage, stationCount int) []string {
+
+	if message.headerType != arrowHeaderDictionaryBatch || message.header.int64(0) != 0 || message.header.bool(2) {
+		t.Fatalf("second message is type %v, expected the dictionary batch for ID 0", message.headerType)
+	}
+
+	batch, _ := message.header.table(1)
+	if batch.int64(0) != int64(stationCount) {
+		t.Errorf("dictionary holds %v values, expected %v", batch.int64(0), stationCount)
+	}
+
+	buffers := arrowBuffers(t, batch, message.body)
+	if len(buffers) != 3 || len(buffers[0]) != 0 {
+		t.Fatalf("dictionary has %v buffers, expected an empty validity bitmap, offsets, and data", len(buffers))
+	}
+
+	var dictionary []string
+	for index := range stationCount {
+		start, end := binary.LittleEndian.Uint32(buffers[1][4*index:]), binary.LittleEndian.Uint32(buffers[1][4*index+4:])
+		dictionary = append(dictionary, string(buffers[2][start:end]))
+	}
+	return dictionary
+}
+
+func readArrowRecordBatch(t *testing.T, message arrowMessage, dictionary []string) map[string][5]int64 {
+
+	if message.headerType != arrowHeaderRecordBatch {
+		t.Fatalf("third message is type %v, expected a record batch", message.headerType)
+	}
+
+	batch := message.header
+	for _, node := range batch.structs(t, 1) {
+		if node != [2]int64{int64(len(dictionary)), 0} {
+			t.Errorf("field node %v, expected %v rows without nulls", node, len(dictionary))
+		}
+	}
+
+	buffers := arrowBuffers(t, batch, message.body)
+	if len(buffers) != 12 {
+		t.Fatalf("%v buffers, expected a validity bitmap and values for each of the 6 columns", len(buffers))
+	}
+
+	rows := make(map[string][5]int64, len(dictionary))
+	for row := range int(batch.int64(0)) {
+		station := dictionary[binary.LittleEndian.Uint32(buffers[1][4*row:])]
+
+		var values [5]int64
+		for column := range 5 {
+			columnBytes := buffers[3+2*column]
+			if column == 3 {
+				values[column] = int64(binary.LittleEndian.Uint64(columnBytes[8*row:]))
+				continue
+			}
+
+			// The high word of a decimal128 must only ever carry the sign
+			low, high := int64(binary.LittleEndian.Uint64(columnBytes[16*row:])), int64(binary.LittleEndian.Uint64(columnBytes[16*row+8:]))
+			if high != low>>63 {
+				t.Errorf("decimal %v of %v has a high word of %v", column, station, high)
+			}
+			values[column] = low
+		}
+		rows[station] = values
+	}
+	return rows
+}
@@ -0,0 +1,73 @@
+package output
+
+import (
+	"unicode"
+)
+
+// wideRunes - Runes drawn two columns wide by a terminal: the East Asian wide and fullwidth blocks, along with the
+// emoji blocks.
+var wideRunes = &unicode.RangeTable{
+	R16: []unicode.Range16{
+		{Lo: 0x1100, Hi: 0x115F, Stride: 1}, // Hangul Jamo
+		{Lo: 0x2E80, Hi: 0x303E, Stride: 1}, // CJK radicals, Kangxi radicals, CJK symbols and punctuation
+		{Lo: 0x3041, Hi: 0x33FF, Stride: 1}, // Hiragana, Katakana, Bopomofo, CJK compatibility
+		{Lo: 0x3400, Hi: 0x4DBF, Stride: 1}, // CJK extension A
+		{Lo: 0x4E00, Hi: 0x9FFF, Stride: 1}, // CJK unified ideographs
+		{Lo: 0xA000, Hi: 0xA4CF, Stride: 1}, // Yi
+		{Lo: 0xAC00, Hi: 0xD7A3, Stride: 1}, // Hangul syllables
+		{Lo: 0xF900, Hi: 0xFAFF, Stride: 1}, // CJK compatibility ideographs
+		{Lo: 0xFE30, Hi: 0xFE4F, Stride: 1}, // CJK compatibility forms
+		{Lo: 0xFF00, Hi: 0xFF60, Stride: 1}, // Fullwidth forms
+		{Lo: 0xFFE0, Hi: 0xFFE6, Stride: 1}, // Fullwidth signs
+	},
+	R32: []unicode.Range32{
+		{Lo: 0x1F300, Hi: 0x1F64F, Stride: 1}, // Pictographs and emoticons
+		{Lo: 0x1F900, Hi: 0x1F9FF, Stride: 1}, // Supplemental pictographs
+		{Lo: 0x20000, Hi: 0x2FFFD, Stride: 1}, // CJK extensions B onward
+		{Lo: 0x30000, Hi: 0x3FFFD, Stride: 1}, // CJK extension G onward
+	},
+}
+
+// RuneWidth - Columns the rune takes up within a terminal. Combining marks and control characters take up none.
+func RuneWidth(r rune) int {
+	switch {
+	case unicode.In(r, unicode.Mn, unicode.Me, unicode.Cf, unicode.Cc):
+		return 0
+	case unicode.Is(wideRunes, r):
+		return 2
+	default:
+		return 1
+	}
+}
+
+// DisplayWidth - Columns the text takes up within a terminal, which differs from both its bytes and its runes once it
+// holds wide or combining characters.
+// e.g. `Abéché` is 6 columns wide whether or not the accents are combining marks, and `東京` is 4
+func DisplayWidth(text string) int {
+
+	var width int
+	for _, r := range text {
+		width += RuneWidth(r)
+	}
+
+	return width
+}
+
+// TruncateToWidth - Cuts the text down to fit within the given number of columns, ending it with `…` when anything
+// was cut. Text that already fits is returned as it is.
+func TruncateToWidth(text string, maxWidth int) string {
+
+	if DisplayWidth(text) <= maxWidth {
+		return text
+	}
+
+	var width int
+	for index, r := range text {
+		if width+RuneWidth(r) > maxWidth-1 {
+			return text[:index] + "…"
+		}
+		width += RuneWidth(r)
+	}
+
+	return text
+}
@@ -0,0 +1,94 @@
+package output
+
+import (
+	"encoding/json"
+)
+
+// ExtraColumn - A statistic kept on top of min/mean/max/count/sum, such as a percentile. Written as an extra column by
+// the table outputs, and under `stats` by the JSON outputs. `Value` returns an empty string for a station without one.
+// Text columns, such as the country a station sits in, go under `labels` in the JSON outputs instead.
+type ExtraColumn struct {
+	Name  string
+	Value func(station string) string
+	Text  bool
+}
+
+// Histograms - A histogram of every station's readings, all sharing the same bucket edges. Bucket 0 holds everything
+// below the first edge, bucket i holds `[Edges[i-1], Edges[i])`, and the final bucket everything from the last edge up.
+type Histograms struct {
+	Edges  []int                      // Tenths of a degree
+	Counts func(station string) []int // Readings within each bucket, or nil for a station without a histogram
+}
+
+// Extras - Everything kept on top of the output values, for the outputs that can show it
+type Extras struct {
+	Columns    []ExtraColumn
+	Histograms *Histograms // Nil when no histograms were kept
+}
+
+// HistogramValues - A single station's histogram within the JSON outputs
+type HistogramValues struct {
+	Edges  []json.Number `json:"edges"`
+	Counts []int         `json:"counts"`
+}
+
+// histogramValues - The JSON histogram of a single station, or nil when there is none
+func (extras Extras) histogramValues(station string) *HistogramValues {
+
+	if extras.Histograms == nil {
+		return nil
+	}
+	counts := extras.Histograms.Counts(station)
+	if counts == nil {
+		return nil
+	}
+
+	var edges = make([]json.Number, len(extras.Histograms.Edges))
+	for index, edge := range extras.Histograms.Edges {
+		edges[index] = json.Number(FormatTemperature(edge))
+	}
+
+	return &HistogramValues{Edges: edges, Counts: counts}
+}
+
+// extraStats - The extra statistics of a single station, keyed by column name, or nil when there are none
+func extraStats(station string, extra []ExtraColumn) map[string]json.Number {
+
+	var stats map[string]json.Number
+	for _, column := range extra {
+		if column.Text {
+			continue
+		}
+		value := column.Value(station)
+		if value == "" {
+			continue
+		}
+		if stats == nil {
+			stats = make(map[string]json.Number, len(extra))
+		}
+		stats[column.Name] = json.Number(value)
+	}
+
+	return stats
+}
+
+// extraLabels - The text columns of a single station, keyed by column name, or nil when there are none
+func extraLabels(station string, extra []ExtraColumn) map[string]string {
+
+	var labels map[string]string
+	for _, column := range extra {
+		if !column.Text {
+			continue
+		}
+		value := column.Value(station)
+		if value == "" {
+			continue
+		}
+		if labels == nil {
+			labels = make(map[string]string, len(extra))
+		}
+		labels[column.Name] = value
+	}
+
+	return labels
+}
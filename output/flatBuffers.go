@@ -0,0 +1,182 @@
+package output
+
+import (
+	"encoding/binary"
+)
+
+// Just enough of a FlatBuffers encoder to write the Arrow IPC metadata, without pulling in the FlatBuffers library.
+//
+// Objects are laid out front to back: every table is written before its children, so every reference points forward
+// as FlatBuffers requires. Each table is preceded by its own vtable.
+
+// fbTable - A FlatBuffers table. The index within `fields` is the field ID from the schema, with `nil` for any field
+// left unset. Fields can be `bool`, `uint8`, `int16`, `int32`, `int64`, `string`, `*fbTable`, `[]*fbTable`, or
+// `fbStructs`.
+type fbTable struct {
+	fields []any
+}
+
+// fbStructs - A vector of structs made up of two longs each, such as Arrow's `FieldNode` and `Buffer`
+type fbStructs [][2]int64
+
+// encodeFlatBuffer - Encodes the root table into a finished buffer
+func encodeFlatBuffer(root *fbTable) []byte {
+
+	encoder := &fbEncoder{buffer: make([]byte, 4, 512)}
+	rootPosition := encoder.writeTable(root)
+	binary.LittleEndian.PutUint32(encoder.buffer[0:], uint32(rootPosition))
+
+	return encoder.buffer
+}
+
+type fbEncoder struct {
+	buffer []byte
+}
+
+// pad - Adds zero bytes until the end of the buffer sits on the alignment
+func (encoder *fbEncoder) pad(alignment int) {
+	for len(encoder.buffer)%alignment != 0 {
+		encoder.buffer = append(encoder.buffer, 0)
+	}
+}
+
+// grow - Adds the given number of zero bytes and returns where they begin
+func (encoder *fbEncoder) grow(size int) int {
+	position := len(encoder.buffer)
+	encoder.buffer = append(encoder.buffer, make([]byte, size)...)
+	return position
+}
+
+// inlineSize - Bytes the field takes up within the table itself. References to other objects are always 4 bytes.
+func inlineSize(value any) int {
+	switch value.(type) {
+	case bool, uint8:
+		return 1
+	case int16:
+		return 2
+	case int64:
+		return 8
+	default:
+		return 4
+	}
+}
+
+// writeTable - Writes the vtable, the table, and then every object the table references. Returns the table position.
+func (encoder *fbEncoder) writeTable(table *fbTable) int {
+
+	// Lay out the inline fields after the leading vtable offset, each aligned to its own size
+	var fieldOffsets = make([]int, len(table.fields))
+	var tableSize = 4
+	for fieldID, value := range table.fields {
+		if value == nil {
+			continue
+		}
+		size := inlineSize(value)
+		tableSize = (tableSize + size - 1) / size * size
+		fieldOffsets[fieldID] = tableSize
+		tableSize += size
+	}
+
+	// The vtable holds its own size, the table size, and then the offset of each field (zero when unset)
+	encoder.pad(2)
+	vtablePosition := encoder.grow(4 + 2*len(table.fields))
+	binary.LittleEndian.PutUint16(encoder.buffer[vtablePosition:], uint16(4+2*len(table.fields)))
+	binary.LittleEndian.PutUint16(encoder.buffer[vtablePosition+2:], uint16(tableSize))
+	for fieldID, fieldOffset := range fieldOffsets {
+		binary.LittleEndian.PutUint16(encoder.buffer[vtablePosition+4+2*fieldID:], uint16(fieldOffset))
+	}
+
+	// Tables begin on an 8 byte boundary, so every long within them is aligned
+	encoder.pad(8)
+	tablePosition := encoder.grow(tableSize)
+	binary.LittleEndian.PutUint32(encoder.buffer[tablePosition:], uint32(int32(tablePosition-vtablePosition)))
+
+	for fieldID, value := range table.fields {
+		fieldPosition := tablePosition + fieldOffsets[fieldID]
+
+		switch typedValue := value.(type) {
+		case bool:
+			if typedValue {
+				encoder.buffer[fieldPosition] = 1
+			}
+		case uint8:
+			encoder.buffer[fieldPosition] = typedValue
+		case int16:
+			binary.LittleEndian.PutUint16(encoder.buffer[fieldPosition:], uint16(typedValue))
+		case int32:
+			binary.LittleEndian.PutUint32(encoder.buffer[fieldPosition:], uint32(typedValue))
+		case int64:
+			binary.LittleEndian.PutUint64(encoder.buffer[fieldPosition:], uint64(typedValue))
+		}
+	}
+
+	// Children come after the table, so each reference is the forward distance from the field to the child
+	for fieldID, value := range table.fields {
+		var childPosition int
+
+		switch typedValue := value.(type) {
+		case string:
+			childPosition = encoder.writeString(typedValue)
+		case *fbTable:
+			childPosition = encoder.writeTable(typedValue)
+		case []*fbTable:
+			childPosition = encoder.writeTableVector(typedValue)
+		case fbStructs:
+			childPosition = encoder.writeStructs(typedValue)
+		default:
+			continue
+		}
+
+		fieldPosition := tablePosition + fieldOffsets[fieldID]
+		binary.LittleEndian.PutUint32(encoder.buffer[fieldPosition:], uint32(childPosition-fieldPosition))
+	}
+
+	return tablePosition
+}
+
+// writeString - Length prefixed and null terminated
+func (encoder *fbEncoder) writeString(value string) int {
+
+	encoder.pad(4)
+	position := encoder.grow(4)
+	binary.LittleEndian.PutUint32(encoder.buffer[position:], uint32(len(value)))
+	encoder.buffer = append(encoder.buffer, value...)
+	encoder.buffer = append(encoder.buffer, 0)
+
+	return position
+}
+
+// writeTableVector - Length prefixed list of references, followed by each of the tables
+func (encoder *fbEncoder) writeTableVector(tables []*fbTable) int {
+
+	encoder.pad(4)
+	position := encoder.grow(4 + 4*len(tables))
+	binary.LittleEndian.PutUint32(encoder.buffer[position:], uint32(len(tables)))
+
+	for index, table := range tables {
+		slotPosition := position + 4 + 4*index
+		tablePosition := encoder.writeTable(table)
+		binary.LittleEndian.PutUint32(encoder.buffer[slotPosition:], uint32(tablePosition-slotPosition))
+	}
+
+	return position
+}
+
+// writeStructs - Length prefixed structs, with the structs themselves beginning on an 8 byte boundary
+func (encoder *fbEncoder) writeStructs(structs fbStructs) int {
+
+	encoder.pad(4)
+	if len(encoder.buffer)%8 == 0 {
+		encoder.grow(4)
+	}
+
+	position := encoder.grow(4 + 16*len(structs))
+	binary.LittleEndian.PutUint32(encoder.buffer[position:], uint32(len(structs)))
+
+	for index, longs := range structs {
+		binary.LittleEndian.PutUint64(encoder.buffer[position+4+16*index:], uint64(longs[0]))
+		binary.LittleEndian.PutUint64(encoder.buffer[position+12+16*index:], uint64(longs[1]))
+	}
+
+	return position
+}
@@ -0,0 +1,86 @@
+package output
+
+import (
+	"billionRowChallenge/utilities"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// FormatResults - Builds the canonical challenge output, `{Abha=-23.0/18.0/59.2, Abidjan=...}`, with every station
+// sorted by name and each entry written as min/mean/max.
+func FormatResults(outputMap map[string]utilities.OutputValues) string {
+
+	stations := SortedStations(outputMap)
+
+	var builder strings.Builder
+	builder.Grow(len(stations) * 32)
+
+	builder.WriteByte('{')
+	for index, station := range stations {
+		if index > 0 {
+			builder.WriteString(", ")
+		}
+
+		outputValues := outputMap[station]
+
+		builder.WriteString(station)
+		builder.WriteByte('=')
+		builder.WriteString(FormatTemperature(outputValues.Min))
+		builder.WriteByte('/')
+		builder.WriteString(FormatTemperature(RoundedMean(outputValues.Total, outputValues.Count)))
+		builder.WriteByte('/')
+		builder.WriteString(FormatTemperature(outputValues.Max))
+	}
+	builder.WriteByte('}')
+
+	return builder.String()
+}
+
+// SortedStations - The station names of the output map, in sorted order
+func SortedStations(outputMap map[string]utilities.OutputValues) []string {
+
+	stations := make([]string, 0, len(outputMap))
+	for station := range outputMap {
+		stations = append(stations, station)
+	}
+	slices.Sort(stations)
+
+	return stations
+}
+
+// RoundedMean - The mean of the readings in tenths of a degree, following the challenge rules of rounding a half
+// toward positive infinity. Worked out with integers, so there is no floating point error to worry about.
+// e.g. `-2.25` becomes `-2.2` and `2.25` becomes `2.3`
+func RoundedMean(total int, count int) int {
+
+	// floor((total / count) + 0.5), as a single division
+	numerator := 2*total + count
+	denominator := 2 * count
+
+	quotient := numerator / denominator
+
+	// Go division truncates toward zero, so step down for negative values that did not divide evenly
+	if numerator%denominator != 0 && numerator < 0 {
+		quotient--
+	}
+
+	return quotient
+}
+
+// FormatTemperature - Writes a temperature held in tenths of a degree with a single decimal place. Built from the integer
+// directly, so a value of zero is always `0.0` and never `-0.0`.
+func FormatTemperature(temperature int) string {
+
+	var temperatureBytes = make([]byte, 0, 6)
+
+	if temperature < 0 {
+		temperatureBytes = append(temperatureBytes, utilities.NegativeHex)
+		temperature = -temperature
+	}
+
+	temperatureBytes = strconv.AppendInt(temperatureBytes, int64(temperature/10), 10)
+	temperatureBytes = append(temperatureBytes, utilities.DecimalHex, byte(utilities.ZeroHex+temperature%10))
+
+	return string(temperatureBytes)
+}
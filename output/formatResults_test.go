@@ -0,0 +1,120 @@
+package output
+
+import (
+	"billionRowChallenge/expectedOutput"
+	"billionRowChallenge/utilities"
+	"fmt"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestRoundedMean(t *testing.T) {
+
+	tests := []struct {
+		total    int
+		count    int
+		expected int
+	}{
+		{0, 1, 0},
+		{5, 2, 3},    // 0.25 rounds up to 0.3
+		{-5, 2, -2},  // -0.25 rounds up to -0.2
+		{-1, 2, 0},   // -0.05 rounds up to 0.0
+		{1, 2, 1},    // 0.05 rounds up to 0.1
+		{-3, 2, -1},  // -0.15 rounds up to -0.1
+		{10, 3, 3},   // 0.333 rounds down
+		{-10, 3, -3}, // -0.333 rounds up
+		{20, 3, 7},   // 0.666 rounds up
+		{-20, 3, -7}, // -0.666 rounds down
+		{-999, 1, -999},
+		{999 * 1_000_000_000, 1_000_000_000, 999},
+	}
+
+	for _, test := range tests {
+		if mean := RoundedMean(test.total, test.count); mean != test.expected {
+			t.Errorf("RoundedMean(%v, %v) = %v, expected %v", test.total, test.count, mean, test.expected)
+		}
+	}
+}
+
+func TestFormatTemperature(t *testing.T) {
+
+	tests := map[int]string{0: "0.0", 1: "0.1", -1: "-0.1", 9: "0.9", 10: "1.0", -10: "-1.0", 123: "12.3", -999: "-99.9", 999: "99.9"}
+
+	for temperature, expected := range tests {
+		if formatted := FormatTemperature(temperature); formatted != expected {
+			t.Errorf("FormatTemperature(%v) = %q, expected %q", temperature, formatted, expected)
+		}
+	}
+}
+
+// TestFormatResultsMatchesExpectedOutput - Golden test against the slow reference answer, over readings chosen to land
+// means on exact halves of both signs, along with random readings across the whole range
+func TestFormatResultsMatchesExpectedOutput(t *testing.T) {
+
+	var rows []string
+	addRows := func(station string, temperatures ...string) {
+		for _, temperature := range temperatures {
+			rows = append(rows, station+";"+temperature)
+		}
+	}
+
+	addRows("Halfway Up", "0.2", "0.3")
+	addRows("Halfway Down", "-0.2", "-0.3")
+	addRows("Nearly Zero", "-0.1", "0.0")
+	addRows("Negative Zero", "-0.0", "-0.0")
+	addRows("Extremes", "-99.9", "99.9", "-99.9")
+	addRows("Zürich", "1.5")
+	addRows("İstanbul", "-12.3", "4.5")
+
+	random := rand.New(rand.NewPCG(1, 2))
+	for row := range 50_000 {
+		temperature := random.IntN(1999) - 999
+		rows = append(rows, fmt.Sprintf("Station %v;%v", row%211, FormatTemperature(temperature)))
+	}
+
+	path := filepath.Join(t.TempDir(), "measurements.txt")
+	if err := os.WriteFile(path, []byte(strings.Join(rows, "\n")+"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	outputMap := make(map[string]utilities.OutputValues)
+	for _, row := range rows {
+		station, temperature, _ := strings.Cut(row, ";")
+		value, err := strconv.Atoi(strings.Replace(temperature, ".", "", 1))
+		if err != nil {
+			t.Fatal(err)
+		}
+		utilities.AddTemperature(outputMap, station, value)
+	}
+
+	formatted, expected := FormatResults(outputMap), expectedOutput.CalculateExpectedOutput(path)
+	if formatted != expected {
+		t.Errorf("FormatResults differs from the expected output:\n%v\n%v", formatted, expected)
+	}
+
+	for _, entry := range []string{"Halfway Up=0.2/0.3/0.3", "Halfway Down=-0.3/-0.2/-0.2", "Nearly Zero=-0.1/0.0/0.0",
+		"Negative Zero=0.0/0.0/0.0", "Extremes=-99.9/-33.3/99.9"} {
+		if !strings.Contains(formatted, entry) {
+			t.Errorf("FormatResults is missing %q", entry)
+		}
+	}
+}
+
+func TestFormatResultsLayout(t *testing.T) {
+
+	outputMap := map[string]utilities.OutputValues{
+		"b": {Min: -1, Max: 1, Total: 0, Count: 2},
+		"a": {Min: 5, Max: 5, Total: 5, Count: 1},
+	}
+
+	if formatted := FormatResults(outputMap); formatted != "{a=0.5/0.5/0.5, b=-0.1/0.0/0.1}" {
+		t.Errorf("FormatResults = %q", formatted)
+	}
+	if formatted := FormatResults(map[string]utilities.OutputValues{}); formatted != "{}" {
+		t.Errorf("FormatResults of nothing = %q, expected {}", formatted)
+	}
+}
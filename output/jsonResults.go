@@ -0,0 +1,124 @@
+package output
+
+import (
+	"billionRowChallenge/utilities"
+	"encoding/json"
+	"io"
+	"time"
+)
+
+// ResultsSchemaVersion - Version of the JSON and NDJSON layouts described within `output/schema`. Bumped whenever a
+// field is renamed, removed, or changes meaning. New optional fields do not bump the version.
+const ResultsSchemaVersion = 1
+
+// RunMetadata - Describes the run that produced a set of results
+type RunMetadata struct {
+	Command    string    `json:"command"`
+	Source     string    `json:"source,omitempty"`
+	StartedAt  time.Time `json:"startedAt"`
+	DurationMs int64     `json:"durationMs"`
+	Rows       int       `json:"rows"`
+	Stations   int       `json:"stations"`
+}
+
+// StationValues - A single station within the results. Temperatures are written straight from the tenths, so the
+// numbers are always exact with a single decimal place.
+type StationValues struct {
+	Min       json.Number            `json:"min"`
+	Max       json.Number            `json:"max"`
+	Mean      json.Number            `json:"mean"`
+	Sum       json.Number            `json:"sum"`
+	Count     int                    `json:"count"`
+	Stats     map[string]json.Number `json:"stats,omitempty"`     // Extra statistics, when any were kept
+	Labels    map[string]string      `json:"labels,omitempty"`    // Text columns, such as the country of the station
+	Histogram *HistogramValues       `json:"histogram,omitempty"` // Only when histograms were kept
+}
+
+// StationRecord - A single NDJSON line
+type StationRecord struct {
+	SchemaVersion int    `json:"schemaVersion"`
+	Station       string `json:"station"`
+	StationValues
+}
+
+// JSONResults - The whole JSON document, keyed by station
+type JSONResults struct {
+	SchemaVersion int                      `json:"schemaVersion"`
+	Run           RunMetadata              `json:"run"`
+	Stations      map[string]StationValues `json:"stations"`
+}
+
+// NewRunMetadata - Fills in the metadata for a finished run, timed from `startedAt` until now
+func NewRunMetadata(command string, source string, startedAt time.Time, outputMap map[string]utilities.OutputValues) RunMetadata {
+
+	var rows int
+	for _, outputValues := range outputMap {
+		rows += outputValues.Count
+	}
+
+	return RunMetadata{
+		Command:    command,
+		Source:     source,
+		StartedAt:  startedAt.UTC(),
+		DurationMs: time.Since(startedAt).Milliseconds(),
+		Rows:       rows,
+		Stations:   len(outputMap),
+	}
+}
+
+// NewStationValues - Converts the tenths held within the output values into the JSON numbers
+func NewStationValues(outputValues utilities.OutputValues) StationValues {
+	return StationValues{
+		Min:   json.Number(FormatTemperature(outputValues.Min)),
+		Max:   json.Number(FormatTemperature(outputValues.Max)),
+		Mean:  json.Number(FormatTemperature(RoundedMean(outputValues.Total, outputValues.Count))),
+		Sum:   json.Number(FormatTemperature(outputValues.Total)),
+		Count: outputValues.Count,
+	}
+}
+
+// WriteJSON - Writes the results as a single JSON document along with the run metadata
+func WriteJSON(writer io.Writer, outputMap map[string]utilities.OutputValues, metadata RunMetadata, extras Extras) error {
+
+	results := JSONResults{
+		SchemaVersion: ResultsSchemaVersion,
+		Run:           metadata,
+		Stations:      make(map[string]StationValues, len(outputMap)),
+	}
+	for station, outputValues := range outputMap {
+		stationValues := NewStationValues(outputValues)
+		stationValues.Stats = extraStats(station, extras.Columns)
+		stationValues.Labels = extraLabels(station, extras.Columns)
+		stationValues.Histogram = extras.histogramValues(station)
+		results.Stations[station] = stationValues
+	}
+
+	encoder := json.NewEncoder(writer)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(results)
+}
+
+// WriteNDJSON - Writes one JSON record per station, one per line, sorted by station. Each record is written as soon as
+// it is built, so a reader can begin consuming the stream before the final station is written.
+func WriteNDJSON(writer io.Writer, outputMap map[string]utilities.OutputValues, extras Extras) error {
+
+	encoder := json.NewEncoder(writer)
+
+	for _, station := range SortedStations(outputMap) {
+		stationValues := NewStationValues(outputMap[station])
+		stationValues.Stats = extraStats(station, extras.Columns)
+		stationValues.Labels = extraLabels(station, extras.Columns)
+		stationValues.Histogram = extras.histogramValues(station)
+
+		err := encoder.Encode(StationRecord{
+			SchemaVersion: ResultsSchemaVersion,
+			Station:       station,
+			StationValues: stationValues,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,164 @@
+package output
+
+import (
+	"billionRowChallenge/utilities"
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testOutputMap() map[string]utilities.OutputValues {
+	return map[string]utilities.OutputValues{
+		"Abha":   {Min: -45, Max: 123, Total: 78, Count: 2},
+		"Zürich": {Min: -999, Max: 999, Total: -1, Count: 3},
+		"Accra":  {Min: 0, Max: 0, Total: 0, Count: 1},
+	}
+}
+
+// testExtras - A number column, a text column, and histograms, each missing for one station
+func testExtras() Extras {
+	return Extras{
+		Columns: []ExtraColumn{
+			{Name: "median", Value: func(station string) string {
+				return map[string]string{"Abha": "-4.5", "Zürich": "0.0"}[station]
+			}},
+			{Name: "country", Text: true, Value: func(station string) string {
+				return map[string]string{"Abha": "Saudi Arabia", "Accra": "Ghana"}[station]
+			}},
+		},
+		Histograms: &Histograms{Edges: []int{-100, 0, 100}, Counts: func(station string) []int {
+			return map[string][]int{"Abha": {0, 1, 0, 1}}[station]
+		}},
+	}
+}
+
+// schemaFields - The required and allowed properties of one of the published schemas
+func schemaFields(t *testing.T, name string) ([]string, map[string]json.RawMessage) {
+
+	schemaBytes, err := os.ReadFile(filepath.Join("schema", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var schema struct {
+		Required   []string                   `json:"required"`
+		Properties map[string]json.RawMessage `json:"properties"`
+	}
+	if err = json.Unmarshal(schemaBytes, &schema); err != nil {
+		t.Fatal(err)
+	}
+
+	return schema.Required, schema.Properties
+}
+
+// checkSchemaFields - Every required field is present, and every field present is described by the schema
+func checkSchemaFields(t *testing.T, record map[string]json.RawMessage, required []string, properties map[string]json.RawMessage) {
+
+	for _, field := range required {
+		if _, ok := record[field]; !ok {
+			t.Errorf("record is missing the required %q", field)
+		}
+	}
+	for field := range record {
+		if _, ok := properties[field]; !ok {
+			t.Errorf("record holds %q, which the schema does not describe", field)
+		}
+	}
+}
+
+func TestWriteNDJSON(t *testing.T) {
+
+	var buffer bytes.Buffer
+	if err := WriteNDJSON(&buffer, testOutputMap(), testExtras()); err != nil {
+		t.Fatalf("WriteNDJSON: %v", err)
+	}
+
+	required, properties := schemaFields(t, "station.v1.schema.json")
+
+	var records []StationRecord
+	scanner := bufio.NewScanner(&buffer)
+	for scanner.Scan() {
+		var fields map[string]json.RawMessage
+		if err := json.Unmarshal(scanner.Bytes(), &fields); err != nil {
+			t.Fatalf("line %q: %v", scanner.Text(), err)
+		}
+		checkSchemaFields(t, fields, required, properties)
+
+		var record StationRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			t.Fatal(err)
+		}
+		records = append(records, record)
+	}
+
+	var stations []string
+	for _, record := range records {
+		stations = append(stations, record.Station)
+	}
+	if !slices.Equal(stations, []string{"Abha", "Accra", "Zürich"}) {
+		t.Fatalf("stations = %v, expected them sorted", stations)
+	}
+
+	abha := records[0]
+	if abha.SchemaVersion != ResultsSchemaVersion || abha.Min != "-4.5" || abha.Max != "12.3" || abha.Mean != "3.9" ||
+		abha.Sum != "7.8" || abha.Count != 2 {
+		t.Errorf("Abha = %+v", abha)
+	}
+	if abha.Stats["median"] != "-4.5" || abha.Labels["country"] != "Saudi Arabia" {
+		t.Errorf("Abha stats %v, labels %v", abha.Stats, abha.Labels)
+	}
+	if abha.Histogram == nil || !slices.Equal(abha.Histogram.Counts, []int{0, 1, 0, 1}) ||
+		!slices.Equal(abha.Histogram.Edges, []json.Number{"-10.0", "0.0", "10.0"}) {
+		t.Errorf("Abha histogram = %+v", abha.Histogram)
+	}
+
+	// Missing extras are left out rather than written empty
+	if accra := records[1]; accra.Stats != nil || accra.Histogram != nil || accra.Labels["country"] != "Ghana" {
+		t.Errorf("Accra = %+v", accra)
+	}
+	if zurich := records[2]; zurich.Labels != nil || zurich.Mean != "0.0" {
+		t.Errorf("Zürich = %+v", zurich)
+	}
+}
+
+func TestWriteJSON(t *testing.T) {
+
+	startedAt := time.Date(2026, 10, 19, 12, 0, 0, 0, time.FixedZone("", 2*60*60))
+	metadata := NewRunMetadata("aggregate", "measurements.txt", startedAt, testOutputMap())
+
+	var buffer bytes.Buffer
+	if err := WriteJSON(&buffer, testOutputMap(), metadata, testExtras()); err != nil {
+		t.Fatalf("WriteJSON: %v", err)
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(buffer.Bytes(), &fields); err != nil {
+		t.Fatal(err)
+	}
+	required, properties := schemaFields(t, "results.v1.schema.json")
+	checkSchemaFields(t, fields, required, properties)
+
+	var results JSONResults
+	if err := json.Unmarshal(buffer.Bytes(), &results); err != nil {
+		t.Fatal(err)
+	}
+	if results.Run.Rows != 6 || results.Run.Stations != 3 || !results.Run.StartedAt.Equal(startedAt) {
+		t.Errorf("run = %+v", results.Run)
+	}
+	if !strings.HasSuffix(results.Run.StartedAt.Format(time.RFC3339), "Z") {
+		t.Errorf("run started at %v, expected UTC", results.Run.StartedAt)
+	}
+
+	for station, outputValues := range testOutputMap() {
+		stationValues := results.Stations[station]
+		if stationValues.Count != outputValues.Count || stationValues.Sum != json.Number(FormatTemperature(outputValues.Total)) {
+			t.Errorf("%v = %+v", station, stationValues)
+		}
+	}
+}
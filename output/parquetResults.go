@@ -0,0 +1,250 @@
+package output
+
+import (
+	"billionRowChallenge/utilities"
+	"encoding/binary"
+	"io"
+	"math/bits"
+)
+
+// Values taken from the Parquet format's `parquet.thrift`
+const (
+	parquetMagic = "PAR1"
+
+	parquetTypeInt32     = 1
+	parquetTypeInt64     = 2
+	parquetTypeByteArray = 6
+
+	parquetRequired = 0
+
+	parquetConvertedUTF8    = 0
+	parquetConvertedDecimal = 5
+
+	parquetEncodingPlain         = 0
+	parquetEncodingRLE           = 3
+	parquetEncodingRLEDictionary = 8
+
+	parquetUncompressed = 0
+
+	parquetPageData       = 0
+	parquetPageDictionary = 2
+)
+
+// parquetChunk - Where a column chunk ended up within the file, for the footer
+type parquetChunk struct {
+	offset               int64
+	dictionaryPageOffset int64
+	dataPageOffset       int64
+	size                 int64
+	encodings            []int32
+}
+
+// WriteParquet - Writes the results as a Parquet file holding a single row group, one row per station sorted by station.
+// The station column is dictionary encoded, the temperatures are decimals with a scale of 1 stored as 32 bit integers
+// (64 bit for the sum), and the count is a 64 bit integer. Every column is required and uncompressed.
+func WriteParquet(writer io.Writer, outputMap map[string]utilities.OutputValues) error {
+
+	stations := SortedStations(outputMap)
+	columns := buildResultColumns(outputMap, stations)
+
+	var file = []byte(parquetMagic)
+	var chunks = make([]parquetChunk, len(columns))
+
+	for index, column := range columns {
+		chunk := &chunks[index]
+		chunk.offset = int64(len(file))
+
+		if column.kind == columnStation {
+			var dictionaryBytes []byte
+			for _, station := range stations {
+				dictionaryBytes = binary.LittleEndian.AppendUint32(dictionaryBytes, uint32(len(station)))
+				dictionaryBytes = append(dictionaryBytes, station...)
+			}
+
+			chunk.dictionaryPageOffset = int64(len(file))
+			file = append(file, parquetPageHeader(parquetPageDictionary, len(stations), parquetEncodingPlain, len(dictionaryBytes))...)
+			file = append(file, dictionaryBytes...)
+
+			chunk.dataPageOffset = int64(len(file))
+			dataBytes := parquetDictionaryIndices(column.values, len(stations))
+			file = append(file, parquetPageHeader(parquetPageData, len(stations), parquetEncodingRLEDictionary, len(dataBytes))...)
+			file = append(file, dataBytes...)
+
+			chunk.encodings = []int32{parquetEncodingPlain, parquetEncodingRLE, parquetEncodingRLEDictionary}
+		} else {
+			var dataBytes []byte
+			for _, value := range column.values {
+				if parquetPhysicalType(column) == parquetTypeInt32 {
+					dataBytes = binary.LittleEndian.AppendUint32(dataBytes, uint32(value))
+				} else {
+					dataBytes = binary.LittleEndian.AppendUint64(dataBytes, uint64(value))
+				}
+			}
+
+			chunk.dataPageOffset = int64(len(file))
+			file = append(file, parquetPageHeader(parquetPageData, len(stations), parquetEncodingPlain, len(dataBytes))...)
+			file = append(file, dataBytes...)
+
+			chunk.encodings = []int32{parquetEncodingPlain, parquetEncodingRLE}
+		}
+
+		chunk.size = int64(len(file)) - chunk.offset
+	}
+
+	footer := parquetFooter(columns, chunks, len(stations))
+	file = append(file, footer...)
+	file = binary.LittleEndian.AppendUint32(file, uint32(len(footer)))
+	file = append(file, parquetMagic...)
+
+	_, err := writer.Write(file)
+	return err
+}
+
+// parquetPhysicalType - Decimals with a precision of up to 9 digits fit within 32 bits
+func parquetPhysicalType(column resultColumn) int32 {
+	switch {
+	case column.kind == columnStation:
+		return parquetTypeByteArray
+	case column.kind == columnDecimal && column.precision <= 9:
+		return parquetTypeInt32
+	default:
+		return parquetTypeInt64
+	}
+}
+
+// parquetPageHeader - Header for an uncompressed dictionary or data page. Data pages never hold any levels, as every
+// column is required and flat.
+func parquetPageHeader(pageType int32, valueCount int, encoding int32, size int) []byte {
+
+	var thrift thriftWriter
+	thrift.beginStruct(0)
+	thrift.writeI32(1, pageType)
+	thrift.writeI32(2, int32(size))
+	thrift.writeI32(3, int32(size))
+
+	if pageType == parquetPageData {
+		thrift.beginStruct(5)
+		thrift.writeI32(1, int32(valueCount))
+		thrift.writeI32(2, encoding)
+		thrift.writeI32(3, parquetEncodingRLE)
+		thrift.writeI32(4, parquetEncodingRLE)
+		thrift.endStruct()
+	} else {
+		thrift.beginStruct(7)
+		thrift.writeI32(1, int32(valueCount))
+		thrift.writeI32(2, encoding)
+		thrift.endStruct()
+	}
+
+	thrift.endStruct()
+	return thrift.buffer
+}
+
+// parquetDictionaryIndices - The bit width followed by the indices as bit packed runs of 8 values, least significant
+// bit first. The final run is padded with zeros, which readers ignore past the value count.
+func parquetDictionaryIndices(indices []int64, dictionarySize int) []byte {
+
+	bitWidth := bits.Len(uint(max(dictionarySize-1, 1)))
+	groupCount := (len(indices) + 7) / 8
+
+	var data = []byte{byte(bitWidth)}
+	data = binary.AppendUvarint(data, uint64(groupCount)<<1|1)
+
+	packedStart := len(data)
+	data = append(data, make([]byte, groupCount*bitWidth)...)
+	for index, value := range indices {
+		for bit := range bitWidth {
+			if value>>bit&1 == 1 {
+				position := index*bitWidth + bit
+				data[packedStart+position/8] |= 1 << (position % 8)
+			}
+		}
+	}
+
+	return data
+}
+
+// parquetFooter - The `FileMetaData` holding the schema and the single row group
+func parquetFooter(columns []resultColumn, chunks []parquetChunk, rowCount int) []byte {
+
+	var thrift thriftWriter
+	thrift.beginStruct(0)
+	thrift.writeI32(1, 1)
+
+	// Schema, flattened with the root first
+	thrift.beginList(2, thriftStruct, len(columns)+1)
+	thrift.beginListElement()
+	thrift.writeString(4, "schema")
+	thrift.writeI32(5, int32(len(columns)))
+	thrift.endStruct()
+
+	for _, column := range columns {
+		thrift.beginListElement()
+		thrift.writeI32(1, parquetPhysicalType(column))
+		thrift.writeI32(3, parquetRequired)
+		thrift.writeString(4, column.name)
+
+		switch column.kind {
+		case columnStation:
+			thrift.writeI32(6, parquetConvertedUTF8)
+			thrift.beginStruct(10)
+			thrift.beginStruct(1) // STRING
+			thrift.endStruct()
+			thrift.endStruct()
+		case columnDecimal:
+			thrift.writeI32(6, parquetConvertedDecimal)
+			thrift.writeI32(7, 1)
+			thrift.writeI32(8, int32(column.precision))
+			thrift.beginStruct(10)
+			thrift.beginStruct(5) // DECIMAL
+			thrift.writeI32(1, 1)
+			thrift.writeI32(2, int32(column.precision))
+			thrift.endStruct()
+			thrift.endStruct()
+		}
+
+		thrift.endStruct()
+	}
+
+	thrift.writeI64(3, int64(rowCount))
+
+	// Row group
+	var totalSize int64
+	for _, chunk := range chunks {
+		totalSize += chunk.size
+	}
+
+	thrift.beginList(4, thriftStruct, 1)
+	thrift.beginListElement()
+	thrift.beginList(1, thriftStruct, len(columns))
+
+	for index, column := range columns {
+		chunk := chunks[index]
+
+		thrift.beginListElement()
+		thrift.writeI64(2, chunk.offset)
+		thrift.beginStruct(3)
+		thrift.writeI32(1, parquetPhysicalType(column))
+		thrift.writeI32List(2, chunk.encodings)
+		thrift.writeStringList(3, []string{column.name})
+		thrift.writeI32(4, parquetUncompressed)
+		thrift.writeI64(5, int64(rowCount))
+		thrift.writeI64(6, chunk.size)
+		thrift.writeI64(7, chunk.size)
+		thrift.writeI64(9, chunk.dataPageOffset)
+		if column.kind == columnStation {
+			thrift.writeI64(11, chunk.dictionaryPageOffset)
+		}
+		thrift.endStruct()
+		thrift.endStruct()
+	}
+
+	thrift.writeI64(2, totalSize)
+	thrift.writeI64(3, int64(rowCount))
+	thrift.endStruct()
+
+	thrift.writeString(6, "billionRowChallenge")
+	thrift.endStruct()
+
+	return thrift.buffer
+}
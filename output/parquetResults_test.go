@@ -0,0 +1,243 @@
+package output
+
+import (
+	"billionRowChallenge/utilities"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"maps"
+	"slices"
+	"testing"
+)
+
+// thriftFields - A decoded compact protocol struct, keyed by field ID. Values are `int64`, `[]byte`, `[]any`, `bool`,
+// or another `thriftFields`.
+type thriftFields map[int16]any
+
+// thriftReader - Reads the compact protocol the way any Thrift library would, so the writer is checked against the
+// format rather than against itself
+type thriftReader struct {
+	data     []byte
+	position int
+}
+
+func (reader *thriftReader) uvarint(t *testing.T) uint64 {
+	value, size := binary.Uvarint(reader.data[reader.position:])
+	if size <= 0 {
+		t.Fatalf("bad varint at %v", reader.position)
+	}
+	reader.position += size
+	return value
+}
+
+func (reader *thriftReader) zigzag(t *testing.T) int64 {
+	value := reader.uvarint(t)
+	return int64(value>>1) ^ -int64(value&1)
+}
+
+func (reader *thriftReader) value(t *testing.T, valueType byte) any {
+	switch valueType {
+	case 1, 2:
+		return valueType == 1
+	case 3:
+		reader.position++
+		return int64(int8(reader.data[reader.position-1]))
+	case 4, 5, 6:
+		return reader.zigzag(t)
+	case thriftBinary:
+		length := int(reader.uvarint(t))
+		reader.position += length
+		return reader.data[reader.position-length : reader.position]
+	case thriftList:
+		header := reader.data[reader.position]
+		reader.position++
+		size, elementType := int(header>>4), header&0x0F
+		if size == 15 {
+			size = int(reader.uvarint(t))
+		}
+		var list []any
+		for range size {
+			list = append(list, reader.value(t, elementType))
+		}
+		return list
+	case thriftStruct:
+		return reader.readStruct(t)
+	}
+	t.Fatalf("unexpected compact type %v at %v", valueType, reader.position)
+	return nil
+}
+
+func (reader *thriftReader) readStruct(t *testing.T) thriftFields {
+
+	fields := thriftFields{}
+	var lastFieldID int16
+	for {
+		header := reader.data[reader.position]
+		reader.position++
+		if header == 0 {
+			return fields
+		}
+
+		fieldType := header & 0x0F
+		if delta := int16(header >> 4); delta != 0 {
+			lastFieldID += delta
+		} else {
+			lastFieldID = int16(reader.zigzag(t))
+		}
+		fields[lastFieldID] = reader.value(t, fieldType)
+	}
+}
+
+// readThriftStruct - Decodes the struct at the position, returning it with the position directly after it
+func readThriftStruct(t *testing.T, data []byte, position int) (thriftFields, int) {
+	reader := &thriftReader{data: data, position: position}
+	return reader.readStruct(t), reader.position
+}
+
+// parquetColumnValues - Decodes the pages of a single column chunk into its values, with the station column looked up
+// through its dictionary
+func parquetColumnValues(t *testing.T, file []byte, metadata thriftFields, rowCount int) []any {
+
+	physicalType := metadata[1].(int64)
+	position := int(metadata[9].(int64))
+
+	var dictionary [][]byte
+	if dictionaryOffset, ok := metadata[11].(int64); ok {
+		header, dataStart := readThriftStruct(t, file, int(dictionaryOffset))
+		if header[1].(int64) != parquetPageDictionary || header[2] != header[3] {
+			t.Fatalf("dictionary page header %v", header)
+		}
+		page := file[dataStart : dataStart+int(header[3].(int64))]
+		for len(page) > 0 {
+			length := binary.LittleEndian.Uint32(page)
+			dictionary = append(dictionary, page[4:4+length])
+			page = page[4+length:]
+		}
+		if dataStart+int(header[3].(int64)) != position {
+			t.Errorf("data page does not directly follow the dictionary page")
+		}
+	}
+
+	header, dataStart := readThriftStruct(t, file, position)
+	dataPageHeader := header[5].(thriftFields)
+	if header[1].(int64) != parquetPageData || dataPageHeader[1].(int64) != int64(rowCount) {
+		t.Fatalf("data page header %v", header)
+	}
+	page := file[dataStart : dataStart+int(header[3].(int64))]
+
+	var values []any
+	switch dataPageHeader[2].(int64) {
+	case parquetEncodingRLEDictionary:
+		bitWidth := int(page[0])
+		runHeader, size := binary.Uvarint(page[1:])
+		if runHeader&1 != 1 {
+			t.Fatalf("dictionary indices are not bit packed")
+		}
+		packed := page[1+size:]
+		if len(packed) != int(runHeader>>1)*bitWidth {
+			t.Errorf("bit packed run is %v bytes, expected %v", len(packed), int(runHeader>>1)*bitWidth)
+		}
+		for row := range rowCount {
+			var index int
+			for bit := range bitWidth {
+				position := row*bitWidth + bit
+				index |= int(packed[position/8]>>(position%8)&1) << bit
+			}
+			values = append(values, string(dictionary[index]))
+		}
+	case parquetEncodingPlain:
+		for row := range rowCount {
+			if physicalType == parquetTypeInt32 {
+				values = append(values, int64(int32(binary.LittleEndian.Uint32(page[4*row:]))))
+			} else {
+				values = append(values, int64(binary.LittleEndian.Uint64(page[8*row:])))
+			}
+		}
+	default:
+		t.Fatalf("unexpected encoding %v", dataPageHeader[2])
+	}
+
+	return values
+}
+
+func TestWriteParquet(t *testing.T) {
+
+	// Enough stations for the dictionary indices to need several bits and to run past a single group of 8
+	manyStations := make(map[string]utilities.OutputValues)
+	for index := range 300 {
+		manyStations[fmt.Sprintf("Station %03d", index)] = utilities.OutputValues{Min: -index, Max: index, Total: index * 7, Count: index + 1}
+	}
+
+	for _, outputMap := range []map[string]utilities.OutputValues{testOutputMap(), manyStations} {
+		t.Run(fmt.Sprintf("%v stations", len(outputMap)), func(t *testing.T) {
+			var buffer bytes.Buffer
+			if err := WriteParquet(&buffer, outputMap); err != nil {
+				t.Fatalf("WriteParquet: %v", err)
+			}
+			file := buffer.Bytes()
+
+			if !bytes.HasPrefix(file, []byte(parquetMagic)) || !bytes.HasSuffix(file, []byte(parquetMagic)) {
+				t.Fatal("file does not begin and end with the magic")
+			}
+			footerLength := int(binary.LittleEndian.Uint32(file[len(file)-8:]))
+			footerStart := len(file) - 8 - footerLength
+
+			metadata, footerEnd := readThriftStruct(t, file, footerStart)
+			if footerEnd != len(file)-8 {
+				t.Errorf("footer decoded to %v, expected %v", footerEnd, len(file)-8)
+			}
+			if metadata[3].(int64) != int64(len(outputMap)) {
+				t.Errorf("footer holds %v rows, expected %v", metadata[3], len(outputMap))
+			}
+
+			// The root, then one leaf per column
+			schema := metadata[2].([]any)
+			var names []string
+			for _, element := range schema[1:] {
+				names = append(names, string(element.(thriftFields)[4].([]byte)))
+			}
+			if root := schema[0].(thriftFields); root[5].(int64) != int64(len(schema)-1) ||
+				!slices.Equal(names, []string{"station", "min", "mean", "max", "count", "sum"}) {
+				t.Errorf("schema %v", schema)
+			}
+
+			rowGroup := metadata[4].([]any)[0].(thriftFields)
+			chunks := rowGroup[1].([]any)
+			chunkEnd := int64(len(parquetMagic))
+
+			var columns [][]any
+			for index, chunk := range chunks {
+				chunkFields := chunk.(thriftFields)
+				columnMetadata := chunkFields[3].(thriftFields)
+
+				if chunkFields[2].(int64) != chunkEnd {
+					t.Errorf("column %v begins at %v, expected %v", names[index], chunkFields[2], chunkEnd)
+				}
+				if path := columnMetadata[3].([]any); len(path) != 1 || string(path[0].([]byte)) != names[index] {
+					t.Errorf("column %v has path %q", names[index], path)
+				}
+				if columnMetadata[1] != schema[index+1].(thriftFields)[1] {
+					t.Errorf("column %v has a different type to its schema element", names[index])
+				}
+				chunkEnd += columnMetadata[7].(int64)
+
+				columns = append(columns, parquetColumnValues(t, file, columnMetadata, len(outputMap)))
+			}
+			if chunkEnd != int64(footerStart) || rowGroup[2].(int64) != chunkEnd-int64(len(parquetMagic)) {
+				t.Errorf("column chunks end at %v, the footer begins at %v", chunkEnd, footerStart)
+			}
+
+			decoded := make(map[string][5]int64, len(outputMap))
+			for row := range len(outputMap) {
+				var values [5]int64
+				for column := range 5 {
+					values[column] = columns[column+1][row].(int64)
+				}
+				decoded[columns[0][row].(string)] = values
+			}
+			if !maps.Equal(decoded, expectedColumnValues(outputMap)) {
+				t.Errorf("decoded values differ from the results")
+			}
+		})
+	}
+}
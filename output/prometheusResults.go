@@ -0,0 +1,128 @@
+package output
+
+import (
+	"billionRowChallenge/utilities"
+	"bufio"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// PrometheusContentType - Content type of the Prometheus text exposition format
+const PrometheusContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// prometheusMetric - A single metric family, one sample per station
+type prometheusMetric struct {
+	name       string
+	help       string
+	metricType string
+	value      func(outputValues utilities.OutputValues) string
+}
+
+var prometheusMetrics = []prometheusMetric{
+	{
+		name:       "brc_station_min_celsius",
+		help:       "Lowest temperature read at the station.",
+		metricType: "gauge",
+		value:      func(outputValues utilities.OutputValues) string { return FormatTemperature(outputValues.Min) },
+	},
+	{
+		name:       "brc_station_max_celsius",
+		help:       "Highest temperature read at the station.",
+		metricType: "gauge",
+		value:      func(outputValues utilities.OutputValues) string { return FormatTemperature(outputValues.Max) },
+	},
+	{
+		name:       "brc_station_mean_celsius",
+		help:       "Mean temperature read at the station, rounded to a tenth of a degree.",
+		metricType: "gauge",
+		value: func(outputValues utilities.OutputValues) string {
+			return FormatTemperature(RoundedMean(outputValues.Total, outputValues.Count))
+		},
+	},
+	{
+		name:       "brc_station_readings_total",
+		help:       "Number of readings taken at the station.",
+		metricType: "counter",
+		value:      func(outputValues utilities.OutputValues) string { return strconv.Itoa(outputValues.Count) },
+	},
+}
+
+// prometheusLabelEscaper - Label values escape backslashes, double quotes, and line breaks
+var prometheusLabelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// WritePrometheus - Writes the results in the Prometheus text exposition format. Every metric family holds one sample
+// per station, labelled by the station name, with the stations sorted by name. Histograms, when kept, are written as
+// a classic Prometheus histogram of cumulative `_bucket` series along with `_sum` and `_count`.
+func WritePrometheus(writer io.Writer, outputMap map[string]utilities.OutputValues, extras Extras) error {
+
+	stations := SortedStations(outputMap)
+	bufferedWriter := bufio.NewWriter(writer)
+
+	for _, metric := range prometheusMetrics {
+		bufferedWriter.WriteString("# HELP " + metric.name + " " + metric.help + "\n")
+		bufferedWriter.WriteString("# TYPE " + metric.name + " " + metric.metricType + "\n")
+
+		for _, station := range stations {
+			bufferedWriter.WriteString(metric.name + `{station="` + prometheusLabelEscaper.Replace(station) + `"} `)
+			bufferedWriter.WriteString(metric.value(outputMap[station]) + "\n")
+		}
+	}
+
+	if extras.Histograms != nil {
+		writePrometheusHistograms(bufferedWriter, outputMap, stations, extras.Histograms)
+	}
+
+	return bufferedWriter.Flush()
+}
+
+// writePrometheusHistograms - Cumulative `_bucket` samples along with `_sum` and `_count`. Buckets hold
+// `[lower, upper)` while Prometheus bounds are inclusive, so as the readings are whole tenths, each bound is written as
+// one tenth below the edge.
+func writePrometheusHistograms(writer *bufio.Writer, outputMap map[string]utilities.OutputValues, stations []string,
+	histograms *Histograms) {
+
+	const name = "brc_station_temperature_celsius"
+
+	var bounds = make([]string, len(histograms.Edges)+1)
+	for index, edge := range histograms.Edges {
+		bounds[index] = FormatTemperature(edge - 1)
+	}
+	bounds[len(histograms.Edges)] = "+Inf"
+
+	writer.WriteString("# HELP " + name + " Readings taken at the station, bucketed by temperature.\n")
+	writer.WriteString("# TYPE " + name + " histogram\n")
+
+	for _, station := range stations {
+		counts := histograms.Counts(station)
+		if counts == nil {
+			continue
+		}
+		label := `station="` + prometheusLabelEscaper.Replace(station) + `"`
+
+		var cumulative int
+		for index, count := range counts {
+			cumulative += count
+			writer.WriteString(name + "_bucket{" + label + `,le="` + bounds[index] + `"} ` + strconv.Itoa(cumulative) + "\n")
+		}
+		writer.WriteString(name + "_sum{" + label + "} " + FormatTemperature(outputMap[station].Total) + "\n")
+		writer.WriteString(name + "_count{" + label + "} " + strconv.Itoa(outputMap[station].Count) + "\n")
+	}
+}
+
+// MetricsHandler - Serves the results returned by `snapshot` in the Prometheus text exposition format, so it can be
+// mounted at `/metrics`. The snapshot is taken afresh for every scrape.
+func MetricsHandler(snapshot func() (map[string]utilities.OutputValues, error)) http.Handler {
+	return http.HandlerFunc(func(responseWriter http.ResponseWriter, request *http.Request) {
+
+		outputMap, err := snapshot()
+		if err != nil {
+			http.Error(responseWriter, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		responseWriter.Header().Set("Content-Type", PrometheusContentType)
+		WritePrometheus(responseWriter, outputMap, Extras{})
+	})
+}
@@ -0,0 +1,97 @@
+package output
+
+import (
+	"billionRowChallenge/utilities"
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestWritePrometheus(t *testing.T) {
+
+	outputMap := map[string]utilities.OutputValues{
+		"Abha":             {Min: -45, Max: 123, Total: 78, Count: 2},
+		`Back\slash "Bay"`: {Min: 1, Max: 1, Total: 1, Count: 1},
+	}
+	histograms := &Histograms{Edges: []int{0, 100}, Counts: func(station string) []int {
+		return map[string][]int{"Abha": {1, 0, 1}}[station]
+	}}
+
+	var buffer bytes.Buffer
+	if err := WritePrometheus(&buffer, outputMap, Extras{Histograms: histograms}); err != nil {
+		t.Fatalf("WritePrometheus: %v", err)
+	}
+	exposition := buffer.String()
+
+	for _, line := range []string{
+		"# TYPE brc_station_min_celsius gauge",
+		`brc_station_min_celsius{station="Abha"} -4.5`,
+		`brc_station_mean_celsius{station="Abha"} 3.9`,
+		"# TYPE brc_station_readings_total counter",
+		`brc_station_readings_total{station="Back\\slash \"Bay\""} 1`,
+		"# TYPE brc_station_temperature_celsius histogram",
+		`brc_station_temperature_celsius_bucket{station="Abha",le="-0.1"} 1`,
+		`brc_station_temperature_celsius_bucket{station="Abha",le="9.9"} 1`,
+		`brc_station_temperature_celsius_bucket{station="Abha",le="+Inf"} 2`,
+		`brc_station_temperature_celsius_sum{station="Abha"} 7.8`,
+		`brc_station_temperature_celsius_count{station="Abha"} 2`,
+	} {
+		if !strings.Contains(exposition, line+"\n") {
+			t.Errorf("exposition is missing %q", line)
+		}
+	}
+
+	// Stations without a histogram have no histogram series
+	if strings.Contains(exposition, `brc_station_temperature_celsius_count{station="Back`) {
+		t.Error("a station without a histogram was given histogram series")
+	}
+
+	// Every metric family is announced once, ahead of its samples
+	var announced = make(map[string]bool)
+	for _, line := range strings.Split(strings.TrimSuffix(exposition, "\n"), "\n") {
+		if name, ok := strings.CutPrefix(line, "# TYPE "); ok {
+			name, _, _ = strings.Cut(name, " ")
+			if announced[name] {
+				t.Errorf("%v is announced twice", name)
+			}
+			announced[name] = true
+			continue
+		}
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+		name, _, _ := strings.Cut(line, "{")
+		name = strings.TrimSuffix(strings.TrimSuffix(strings.TrimSuffix(name, "_bucket"), "_sum"), "_count")
+		if !announced[name] {
+			t.Errorf("sample %q comes before its family is announced", line)
+		}
+	}
+}
+
+func TestMetricsHandler(t *testing.T) {
+
+	handler := MetricsHandler(func() (map[string]utilities.OutputValues, error) {
+		return map[string]utilities.OutputValues{"Abha": {Min: 1, Max: 1, Total: 1, Count: 1}}, nil
+	})
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if recorder.Code != http.StatusOK || recorder.Header().Get("Content-Type") != PrometheusContentType {
+		t.Errorf("scrape = %v, %q", recorder.Code, recorder.Header().Get("Content-Type"))
+	}
+	if !strings.Contains(recorder.Body.String(), `brc_station_max_celsius{station="Abha"} 0.1`) {
+		t.Errorf("scrape body = %q", recorder.Body.String())
+	}
+
+	failing := MetricsHandler(func() (map[string]utilities.OutputValues, error) {
+		return nil, errors.New("snapshot unavailable")
+	})
+	recorder = httptest.NewRecorder()
+	failing.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if recorder.Code != http.StatusInternalServerError {
+		t.Errorf("failed snapshot answered with %v", recorder.Code)
+	}
+}
@@ -0,0 +1,147 @@
+package output
+
+import (
+	"billionRowChallenge/utilities"
+	_ "embed"
+	"fmt"
+	"html/template"
+	"io"
+	"strconv"
+	"unicode/utf8"
+)
+
+// Limits set by the challenge rules, which the results are checked against
+const (
+	MaxStationNameBytes = 100
+	MaxStationCount     = 10000
+	MinTemperature      = -999 // Tenths of a degree
+	MaxTemperature      = 999
+)
+
+//go:embed templates/report.html.tmpl
+var reportTemplateText string
+
+var reportTemplate = template.Must(template.New("report").Parse(reportTemplateText))
+
+// Width of each range chart, and the space left at either end so the end caps are not clipped
+const (
+	reportChartWidth   = 240
+	reportChartPadding = 4
+)
+
+// ReportSetting - A single line of the run configuration shown at the top of the report
+type ReportSetting struct {
+	Name  string
+	Value string
+}
+
+// ReportOptions - Everything within the report besides the results themselves
+type ReportOptions struct {
+	Title    string
+	Run      RunMetadata
+	Settings []ReportSetting
+	Warnings []string
+}
+
+// reportStation - A row of the station table, along with the positions of its range chart
+type reportStation struct {
+	Name   string
+	Values StationValues
+	Min    int // Tenths of a degree, used when sorting
+	Mean   int
+	Max    int
+	Sum    int
+	MinX   string
+	MeanX  string
+	MaxX   string
+}
+
+// reportData - The data handed over to the template
+type reportData struct {
+	ReportOptions
+	Stations   []reportStation
+	ChartWidth int
+	ScaleMin   string
+	ScaleMax   string
+}
+
+// ValidateResults - Checks the results against the challenge rules and returns a warning for every broken rule. Station
+// names are checked for valid UTF-8 and their length, and temperatures for the -99.9 to 99.9 range.
+func ValidateResults(outputMap map[string]utilities.OutputValues) []string {
+
+	var warnings []string
+
+	if len(outputMap) > MaxStationCount {
+		warnings = append(warnings, fmt.Sprintf("%v stations, more than the %v allowed", len(outputMap), MaxStationCount))
+	}
+
+	for _, station := range SortedStations(outputMap) {
+		outputValues := outputMap[station]
+
+		if !utf8.ValidString(station) {
+			warnings = append(warnings, fmt.Sprintf("station %q is not valid UTF-8", station))
+		}
+		if len(station) > MaxStationNameBytes {
+			warnings = append(warnings, fmt.Sprintf("station %q is %v bytes long, more than the %v allowed", station, len(station), MaxStationNameBytes))
+		}
+		if outputValues.Min < MinTemperature || outputValues.Max > MaxTemperature {
+			warnings = append(warnings, fmt.Sprintf("station %q has readings outside of -99.9 to 99.9 (%v to %v)", station,
+				FormatTemperature(outputValues.Min), FormatTemperature(outputValues.Max)))
+		}
+	}
+
+	return warnings
+}
+
+// WriteHTMLReport - Writes a single self-contained HTML page holding the run configuration, any warnings, and a
+// sortable table of the stations. Every station carries an inline SVG chart of its min/mean/max range, with every
+// chart drawn on the same scale so the ranges can be compared down the table.
+func WriteHTMLReport(writer io.Writer, outputMap map[string]utilities.OutputValues, options ReportOptions) error {
+
+	stations := SortedStations(outputMap)
+
+	// Shared scale, covering every reading
+	var scaleMin, scaleMax = 0, 0
+	for index, station := range stations {
+		outputValues := outputMap[station]
+		if index == 0 || outputValues.Min < scaleMin {
+			scaleMin = outputValues.Min
+		}
+		if index == 0 || outputValues.Max > scaleMax {
+			scaleMax = outputValues.Max
+		}
+	}
+	scaleRange := max(scaleMax-scaleMin, 1)
+
+	position := func(tenths int) string {
+		x := reportChartPadding + float64(tenths-scaleMin)/float64(scaleRange)*(reportChartWidth-2*reportChartPadding)
+		return strconv.FormatFloat(x, 'f', 1, 64)
+	}
+
+	data := reportData{
+		ReportOptions: options,
+		Stations:      make([]reportStation, len(stations)),
+		ChartWidth:    reportChartWidth,
+		ScaleMin:      FormatTemperature(scaleMin),
+		ScaleMax:      FormatTemperature(scaleMax),
+	}
+
+	for index, station := range stations {
+		outputValues := outputMap[station]
+		mean := RoundedMean(outputValues.Total, outputValues.Count)
+
+		data.Stations[index] = reportStation{
+			Name:   station,
+			Values: NewStationValues(outputValues),
+			Min:    outputValues.Min,
+			Mean:   mean,
+			Max:    outputValues.Max,
+			Sum:    outputValues.Total,
+			MinX:   position(outputValues.Min),
+			MeanX:  position(mean),
+			MaxX:   position(outputValues.Max),
+		}
+	}
+
+	return reportTemplate.Execute(writer, data)
+}
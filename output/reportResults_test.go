@@ -0,0 +1,61 @@
+package output
+
+import (
+	"billionRowChallenge/utilities"
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestValidateResults(t *testing.T) {
+
+	outputMap := map[string]utilities.OutputValues{
+		"Abha":                   {Min: -999, Max: 999, Total: 0, Count: 2},
+		"Too Hot":                {Min: 0, Max: 1000, Total: 1000, Count: 2},
+		"Bad \xff":               {Min: 0, Max: 0, Total: 0, Count: 1},
+		strings.Repeat("a", 101): {Min: 0, Max: 0, Total: 0, Count: 1},
+	}
+
+	warnings := ValidateResults(outputMap)
+	if len(warnings) != 3 {
+		t.Fatalf("warnings = %q, expected one for each broken station", warnings)
+	}
+	for _, warning := range warnings {
+		if strings.Contains(warning, "Abha") {
+			t.Errorf("warning %q for a station within the rules", warning)
+		}
+	}
+
+	if warnings := ValidateResults(testOutputMap()); len(warnings) != 0 {
+		t.Errorf("warnings = %q for results within the rules", warnings)
+	}
+}
+
+func TestWriteHTMLReport(t *testing.T) {
+
+	outputMap := testOutputMap()
+	outputMap[`<script>alert("x")</script>`] = utilities.OutputValues{Min: 1, Max: 1, Total: 1, Count: 1}
+
+	var buffer bytes.Buffer
+	options := ReportOptions{Title: "Results & more", Warnings: []string{"a warning"}}
+	if err := WriteHTMLReport(&buffer, outputMap, options); err != nil {
+		t.Fatalf("WriteHTMLReport: %v", err)
+	}
+	report := buffer.String()
+
+	if strings.Contains(report, `<script>alert`) {
+		t.Error("a station name was written into the page unescaped")
+	}
+	for _, expected := range []string{"Results &amp; more", "a warning", "Zürich", "&lt;script&gt;", "<svg"} {
+		if !strings.Contains(report, expected) {
+			t.Errorf("report is missing %q", expected)
+		}
+	}
+
+	// Self-contained, so nothing is fetched from elsewhere
+	for _, external := range []string{`src="http`, `href="http`, "@import"} {
+		if strings.Contains(report, external) {
+			t.Errorf("report refers to %q", external)
+		}
+	}
+}
@@ -0,0 +1,171 @@
+package output
+
+import (
+	"billionRowChallenge/utilities"
+	"bufio"
+	"cmp"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// QuoteMode - Decides which fields of the table are wrapped in double quotes
+type QuoteMode int
+
+const (
+	QuoteMinimal QuoteMode = iota // Only fields holding the delimiter, a quote, or a line break
+	QuoteAll                      // Every field, including the header and the numbers
+	QuoteNone                     // Never, a field that would need quoting is an error
+)
+
+// TableColumns - Columns of the tabular output, in order
+var TableColumns = []string{"station", "min", "mean", "max", "count", "sum"}
+
+// SortKeys - Keys the stations can be sorted by
+var SortKeys = []string{"station", "min", "mean", "max", "count", "sum"}
+
+// TableOptions - How the tabular output is laid out
+type TableOptions struct {
+	Delimiter  rune      // `,` for CSV, `\t` for TSV
+	Quoting    QuoteMode // Which fields are quoted
+	Header     bool      // Write the column names as the first row
+	SortBy     string    // One of `SortKeys`, sorting by station when empty
+	Descending bool      // Reverse the sort order
+}
+
+// CSVOptions - Comma separated values with a header row, sorted by station
+func CSVOptions() TableOptions {
+	return TableOptions{Delimiter: ',', Quoting: QuoteMinimal, Header: true, SortBy: "station"}
+}
+
+// TSVOptions - Tab separated values with a header row, sorted by station
+func TSVOptions() TableOptions {
+	return TableOptions{Delimiter: '\t', Quoting: QuoteMinimal, Header: true, SortBy: "station"}
+}
+
+// ParseQuoteMode - Converts `minimal`, `all`, or `none` into the quote mode
+func ParseQuoteMode(quoting string) (QuoteMode, error) {
+	switch quoting {
+	case "minimal":
+		return QuoteMinimal, nil
+	case "all":
+		return QuoteAll, nil
+	case "none":
+		return QuoteNone, nil
+	}
+	return QuoteMinimal, fmt.Errorf("unknown quote mode %q, expected minimal, all, or none", quoting)
+}
+
+// CompareStations - Builds a comparison for sorting stations by the given key in ascending order. Ties always fall back
+// to the station name, so the order is stable between runs.
+func CompareStations(outputMap map[string]utilities.OutputValues, sortBy string) (func(first string, second string) int, error) {
+
+	var valueOf func(outputValues utilities.OutputValues) float64
+
+	switch sortBy {
+	case "", "station":
+		return strings.Compare, nil
+	case "min":
+		valueOf = func(outputValues utilities.OutputValues) float64 { return float64(outputValues.Min) }
+	case "max":
+		valueOf = func(outputValues utilities.OutputValues) float64 { return float64(outputValues.Max) }
+	case "count":
+		valueOf = func(outputValues utilities.OutputValues) float64 { return float64(outputValues.Count) }
+	case "sum":
+		valueOf = func(outputValues utilities.OutputValues) float64 { return float64(outputValues.Total) }
+	case "mean":
+		// Uses the exact mean rather than the rounded one, so stations sharing a printed mean still sort correctly
+		valueOf = func(outputValues utilities.OutputValues) float64 {
+			return float64(outputValues.Total) / float64(outputValues.Count)
+		}
+	default:
+		return nil, fmt.Errorf("unknown sort key %q, expected one of %v", sortBy, strings.Join(SortKeys, ", "))
+	}
+
+	return func(first string, second string) int {
+		if result := cmp.Compare(valueOf(outputMap[first]), valueOf(outputMap[second])); result != 0 {
+			return result
+		}
+		return strings.Compare(first, second)
+	}, nil
+}
+
+// WriteTable - Writes the results as delimited rows with the columns station, min, mean, max, count, and sum, followed
+// by any extra columns. Temperatures are written straight from the tenths with a single decimal place. Histograms are
+// left out, as they do not fit within a single field.
+func WriteTable(writer io.Writer, outputMap map[string]utilities.OutputValues, options TableOptions, extras Extras) error {
+
+	extra := extras.Columns
+
+	compareStations, err := CompareStations(outputMap, options.SortBy)
+	if err != nil {
+		return err
+	}
+
+	stations := SortedStations(outputMap)
+	slices.SortFunc(stations, compareStations)
+	if options.Descending {
+		slices.Reverse(stations)
+	}
+
+	bufferedWriter := bufio.NewWriter(writer)
+
+	if options.Header {
+		header := slices.Clone(TableColumns)
+		for _, column := range extra {
+			header = append(header, column.Name)
+		}
+		if err = writeTableRow(bufferedWriter, header, options); err != nil {
+			return err
+		}
+	}
+
+	var fields = make([]string, len(TableColumns)+len(extra))
+	for _, station := range stations {
+		outputValues := outputMap[station]
+
+		fields[0] = station
+		fields[1] = FormatTemperature(outputValues.Min)
+		fields[2] = FormatTemperature(RoundedMean(outputValues.Total, outputValues.Count))
+		fields[3] = FormatTemperature(outputValues.Max)
+		fields[4] = strconv.Itoa(outputValues.Count)
+		fields[5] = FormatTemperature(outputValues.Total)
+		for index, column := range extra {
+			fields[len(TableColumns)+index] = column.Value(station)
+		}
+
+		if err = writeTableRow(bufferedWriter, fields, options); err != nil {
+			return err
+		}
+	}
+
+	return bufferedWriter.Flush()
+}
+
+// writeTableRow - Writes a single row, quoting each field as the options require
+func writeTableRow(writer *bufio.Writer, fields []string, options TableOptions) error {
+
+	for index, field := range fields {
+		if index > 0 {
+			writer.WriteRune(options.Delimiter)
+		}
+
+		needsQuotes := field == "" || strings.ContainsRune(field, options.Delimiter) || strings.ContainsAny(field, "\"\r\n")
+
+		switch {
+		case options.Quoting == QuoteAll || (options.Quoting == QuoteMinimal && needsQuotes):
+			writer.WriteByte('"')
+			writer.WriteString(strings.ReplaceAll(field, `"`, `""`))
+			writer.WriteByte('"')
+		case options.Quoting == QuoteNone && needsQuotes && field != "":
+			return fmt.Errorf("field %q needs quoting, which is turned off", field)
+		default:
+			writer.WriteString(field)
+		}
+	}
+
+	_, err := writer.WriteString("\n")
+	return err
+}
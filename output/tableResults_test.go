@@ -0,0 +1,101 @@
+package output
+
+import (
+	"billionRowChallenge/utilities"
+	"bytes"
+	"encoding/csv"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestWriteTableReadsBackAsCSV(t *testing.T) {
+
+	outputMap := testOutputMap()
+	outputMap[`Quote "Point", North`] = utilities.OutputValues{Min: 1, Max: 2, Total: 3, Count: 2}
+	outputMap["Line\nBreak"] = utilities.OutputValues{Min: -1, Max: -1, Total: -1, Count: 1}
+
+	for _, options := range []TableOptions{CSVOptions(), TSVOptions(), {Delimiter: ',', Quoting: QuoteAll, Header: true}} {
+		var buffer bytes.Buffer
+		if err := WriteTable(&buffer, outputMap, options, testExtras()); err != nil {
+			t.Fatalf("WriteTable: %v", err)
+		}
+
+		reader := csv.NewReader(&buffer)
+		reader.Comma = options.Delimiter
+		records, err := reader.ReadAll()
+		if err != nil {
+			t.Fatalf("reading the table back: %v", err)
+		}
+
+		if header := append(slices.Clone(TableColumns), "median", "country"); !slices.Equal(records[0], header) {
+			t.Errorf("header = %q, expected %q", records[0], header)
+		}
+		if len(records) != len(outputMap)+1 {
+			t.Fatalf("%v rows, expected %v", len(records)-1, len(outputMap))
+		}
+
+		for _, record := range records[1:] {
+			outputValues, ok := outputMap[record[0]]
+			if !ok {
+				t.Errorf("row of unknown station %q", record[0])
+				continue
+			}
+			if record[1] != FormatTemperature(outputValues.Min) || record[3] != FormatTemperature(outputValues.Max) {
+				t.Errorf("row %q does not match %+v", record, outputValues)
+			}
+		}
+	}
+}
+
+func TestWriteTableSorting(t *testing.T) {
+
+	tests := []struct {
+		sortBy     string
+		descending bool
+		expected   []string
+	}{
+		{"station", false, []string{"Abha", "Accra", "Zürich"}},
+		{"station", true, []string{"Zürich", "Accra", "Abha"}},
+		{"min", false, []string{"Zürich", "Abha", "Accra"}},
+		{"mean", true, []string{"Abha", "Accra", "Zürich"}}, // Accra and Zürich both print 0.0, Accra's exact mean is higher
+		{"count", false, []string{"Accra", "Abha", "Zürich"}},
+	}
+
+	for _, test := range tests {
+		var buffer bytes.Buffer
+		options := TableOptions{Delimiter: ',', SortBy: test.sortBy, Descending: test.descending}
+		if err := WriteTable(&buffer, testOutputMap(), options, Extras{}); err != nil {
+			t.Fatalf("WriteTable: %v", err)
+		}
+
+		var stations []string
+		for _, line := range strings.Split(strings.TrimSuffix(buffer.String(), "\n"), "\n") {
+			station, _, _ := strings.Cut(line, ",")
+			stations = append(stations, station)
+		}
+		if !slices.Equal(stations, test.expected) {
+			t.Errorf("sorted by %v, descending %v: %v, expected %v", test.sortBy, test.descending, stations, test.expected)
+		}
+	}
+
+	if err := WriteTable(&bytes.Buffer{}, testOutputMap(), TableOptions{SortBy: "median"}, Extras{}); err == nil {
+		t.Error("WriteTable accepted an unknown sort key")
+	}
+}
+
+func TestWriteTableWithoutQuoting(t *testing.T) {
+
+	outputMap := map[string]utilities.OutputValues{"Washington, D.C.": {Min: 1, Max: 1, Total: 1, Count: 1}}
+	options := TableOptions{Delimiter: ',', Quoting: QuoteNone}
+
+	if err := WriteTable(&bytes.Buffer{}, outputMap, options, Extras{}); err == nil {
+		t.Error("a station holding the delimiter was written without quotes")
+	}
+
+	options.Delimiter = '\t'
+	var buffer bytes.Buffer
+	if err := WriteTable(&buffer, outputMap, options, Extras{}); err != nil || buffer.String() != "Washington, D.C.\t0.1\t0.1\t0.1\t1\t0.1\n" {
+		t.Errorf("WriteTable = %q, %v", buffer.String(), err)
+	}
+}
@@ -0,0 +1,191 @@
+package output
+
+import (
+	"billionRowChallenge/utilities"
+	"bufio"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// ANSI escape codes used when highlighting
+const (
+	ansiBold  = "\x1b[1m"
+	ansiBlue  = "\x1b[34m"
+	ansiRed   = "\x1b[31m"
+	ansiReset = "\x1b[0m"
+)
+
+// TerminalTableOptions - How the table drawn for a terminal is laid out
+type TerminalTableOptions struct {
+	SortBy       string // One of `SortKeys`, sorting by station when empty
+	Descending   bool   // Reverse the sort order
+	MaxNameWidth int    // Station names wider than this many columns are truncated, zero leaves them whole
+	PageSize     int    // Rows per page, with the header repeated at the top of every page. Zero is a single page.
+	Color        bool   // Highlight the header and the extreme values with ANSI colours
+}
+
+// terminalCell - The text of a cell along with its colour, kept apart so the padding only counts the text
+type terminalCell struct {
+	text  string
+	color string
+}
+
+// WriteTerminalTable - Writes the results as a table with aligned columns, for reading within a terminal. Columns are
+// sized by display width rather than bytes, so wide CJK and accented station names still line up. The lowest
+// minimum is highlighted in blue and the highest maximum in red when colour is turned on. Any extra columns follow
+// the sum, with each station's histogram drawn as a row of bars at the very end.
+func WriteTerminalTable(writer io.Writer, outputMap map[string]utilities.OutputValues, options TerminalTableOptions, extras Extras) error {
+
+	extra := extras.Columns
+	if extras.Histograms != nil {
+		extra = append(slices.Clone(extra), ExtraColumn{Name: "histogram", Value: func(station string) string {
+			return HistogramBars(extras.Histograms.Counts(station))
+		}})
+	}
+
+	compareStations, err := CompareStations(outputMap, options.SortBy)
+	if err != nil {
+		return err
+	}
+
+	stations := SortedStations(outputMap)
+	slices.SortFunc(stations, compareStations)
+	if options.Descending {
+		slices.Reverse(stations)
+	}
+
+	// Extremes across every station
+	var lowest, highest int
+	for index, station := range stations {
+		if index == 0 || outputMap[station].Min < lowest {
+			lowest = outputMap[station].Min
+		}
+		if index == 0 || outputMap[station].Max > highest {
+			highest = outputMap[station].Max
+		}
+	}
+
+	// Build every cell first, so the column widths are known before anything is written
+	columnNames := slices.Clone(TableColumns)
+	for _, column := range extra {
+		columnNames = append(columnNames, column.Name)
+	}
+
+	var rows = make([][]terminalCell, len(stations))
+	var widths = make([]int, len(columnNames))
+	for column, name := range columnNames {
+		widths[column] = DisplayWidth(name)
+	}
+
+	for index, station := range stations {
+		outputValues := outputMap[station]
+
+		name := station
+		if options.MaxNameWidth > 0 {
+			name = TruncateToWidth(station, options.MaxNameWidth)
+		}
+
+		row := []terminalCell{
+			{text: name},
+			{text: FormatTemperature(outputValues.Min)},
+			{text: FormatTemperature(RoundedMean(outputValues.Total, outputValues.Count))},
+			{text: FormatTemperature(outputValues.Max)},
+			{text: strconv.Itoa(outputValues.Count)},
+			{text: FormatTemperature(outputValues.Total)},
+		}
+		for _, column := range extra {
+			row = append(row, terminalCell{text: column.Value(station)})
+		}
+		if outputValues.Min == lowest {
+			row[1].color = ansiBlue
+		}
+		if outputValues.Max == highest {
+			row[3].color = ansiRed
+		}
+
+		for column, cell := range row {
+			widths[column] = max(widths[column], DisplayWidth(cell.text))
+		}
+		rows[index] = row
+	}
+
+	var header = make([]terminalCell, len(columnNames))
+	for column, name := range columnNames {
+		header[column] = terminalCell{text: name, color: ansiBold}
+	}
+
+	var separator = make([]string, len(widths))
+	for column, width := range widths {
+		separator[column] = strings.Repeat("─", width)
+	}
+
+	bufferedWriter := bufio.NewWriter(writer)
+
+	pageSize := options.PageSize
+	if pageSize <= 0 {
+		pageSize = max(len(rows), 1)
+	}
+
+	for start := 0; start < len(rows) || start == 0; start += pageSize {
+		if start > 0 {
+			bufferedWriter.WriteString("\n")
+		}
+
+		writeTerminalRow(bufferedWriter, header, widths, options.Color)
+		bufferedWriter.WriteString(strings.Join(separator, "──") + "\n")
+
+		for _, row := range rows[start:min(start+pageSize, len(rows))] {
+			writeTerminalRow(bufferedWriter, row, widths, options.Color)
+		}
+	}
+
+	return bufferedWriter.Flush()
+}
+
+// histogramLevels - Bars of increasing height, an empty bucket being a space
+var histogramLevels = []rune(" ▁▂▃▄▅▆▇█")
+
+// HistogramBars - One bar per bucket, scaled against the fullest bucket. Any bucket holding a reading gets at least the
+// lowest bar, so it is never mistaken for an empty one.
+func HistogramBars(counts []int) string {
+
+	var fullest int
+	for _, count := range counts {
+		fullest = max(fullest, count)
+	}
+
+	var bars = make([]rune, len(counts))
+	for index, count := range counts {
+		level := 0
+		if count > 0 {
+			level = max(1, (count*(len(histogramLevels)-1)+fullest-1)/fullest)
+		}
+		bars[index] = histogramLevels[level]
+	}
+
+	return string(bars)
+}
+
+// writeTerminalRow - Pads the station to the left and every number to the right, two spaces between columns
+func writeTerminalRow(writer *bufio.Writer, row []terminalCell, widths []int, color bool) {
+
+	for column, cell := range row {
+		padding := strings.Repeat(" ", widths[column]-DisplayWidth(cell.text))
+
+		if column > 0 {
+			writer.WriteString("  " + padding)
+		}
+		if color && cell.color != "" {
+			writer.WriteString(cell.color + cell.text + ansiReset)
+		} else {
+			writer.WriteString(cell.text)
+		}
+		if column == 0 && len(row) > 1 {
+			writer.WriteString(padding)
+		}
+	}
+
+	writer.WriteString("\n")
+}
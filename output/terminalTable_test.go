@@ -0,0 +1,124 @@
+package output
+
+import (
+	"billionRowChallenge/utilities"
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestDisplayWidth(t *testing.T) {
+
+	tests := map[string]int{
+		"":          0,
+		"Abha":      4,
+		"Abéché":    6,
+		"Abe\u0301": 3, // Combining acute accent
+		"東京":        4,
+		"ｿｳﾙ":       3, // Halfwidth katakana
+		"Ｔｏｋｙｏ":     10,
+		"🌡 Hot":     6,
+		"a\u200bb":  2, // Zero width space
+	}
+
+	for text, expected := range tests {
+		if width := DisplayWidth(text); width != expected {
+			t.Errorf("DisplayWidth(%q) = %v, expected %v", text, width, expected)
+		}
+	}
+}
+
+func TestTruncateToWidth(t *testing.T) {
+
+	tests := []struct {
+		text     string
+		maxWidth int
+		expected string
+	}{
+		{"Abha", 4, "Abha"},
+		{"Abidjan", 4, "Abi…"},
+		{"東京都", 4, "東…"},
+		{"東京都", 5, "東京…"},
+		{"Abéché", 4, "Abé…"},
+	}
+
+	for _, test := range tests {
+		truncated := TruncateToWidth(test.text, test.maxWidth)
+		if truncated != test.expected || DisplayWidth(truncated) > test.maxWidth {
+			t.Errorf("TruncateToWidth(%q, %v) = %q, expected %q", test.text, test.maxWidth, truncated, test.expected)
+		}
+	}
+}
+
+func TestWriteTerminalTableAlignment(t *testing.T) {
+
+	outputMap := map[string]utilities.OutputValues{
+		"東京":     {Min: -45, Max: 123, Total: 78, Count: 2},
+		"Abéché": {Min: 0, Max: 999, Total: 999, Count: 1},
+		"Z":      {Min: -999, Max: 0, Total: -999, Count: 1},
+	}
+
+	var buffer bytes.Buffer
+	if err := WriteTerminalTable(&buffer, outputMap, TerminalTableOptions{}, Extras{}); err != nil {
+		t.Fatalf("WriteTerminalTable: %v", err)
+	}
+
+	// Every line, and every column boundary within it, lines up by display width
+	lines := strings.Split(strings.TrimSuffix(buffer.String(), "\n"), "\n")
+	if len(lines) != 5 {
+		t.Fatalf("%v lines, expected a header, a separator, and 3 rows:\n%v", len(lines), buffer.String())
+	}
+	for _, line := range lines[1:] {
+		if DisplayWidth(line) != DisplayWidth(lines[0]) {
+			t.Errorf("line %q is %v columns wide, the header is %v", line, DisplayWidth(line), DisplayWidth(lines[0]))
+		}
+	}
+	if !strings.HasPrefix(lines[2], "Abéché  ") || !strings.HasPrefix(lines[4], "東京    ") {
+		t.Errorf("station names are not padded to the widest:\n%v", buffer.String())
+	}
+}
+
+func TestWriteTerminalTablePagesAndColor(t *testing.T) {
+
+	var buffer bytes.Buffer
+	options := TerminalTableOptions{PageSize: 2, Color: true}
+	if err := WriteTerminalTable(&buffer, testOutputMap(), options, Extras{}); err != nil {
+		t.Fatalf("WriteTerminalTable: %v", err)
+	}
+	table := buffer.String()
+
+	if headers := strings.Count(table, ansiBold+"station"+ansiReset); headers != 2 {
+		t.Errorf("header written %v times, expected once for each of the 2 pages", headers)
+	}
+	if !strings.Contains(table, ansiBlue+"-99.9"+ansiReset) || !strings.Contains(table, ansiRed+"99.9"+ansiReset) {
+		t.Errorf("the extremes are not highlighted:\n%q", table)
+	}
+
+	buffer.Reset()
+	if err := WriteTerminalTable(&buffer, testOutputMap(), TerminalTableOptions{}, Extras{}); err != nil {
+		t.Fatalf("WriteTerminalTable: %v", err)
+	}
+	if strings.Contains(buffer.String(), "\x1b[") {
+		t.Error("colour was written while turned off")
+	}
+}
+
+func TestHistogramBars(t *testing.T) {
+
+	tests := []struct {
+		counts   []int
+		expected string
+	}{
+		{nil, ""},
+		{[]int{0, 0}, "  "},
+		{[]int{0, 1, 8}, " ▁█"},
+		{[]int{1, 1000}, "▁█"},
+		{[]int{4, 8, 2}, "▄█▂"},
+	}
+
+	for _, test := range tests {
+		if bars := HistogramBars(test.counts); bars != test.expected {
+			t.Errorf("HistogramBars(%v) = %q, expected %q", test.counts, bars, test.expected)
+		}
+	}
+}
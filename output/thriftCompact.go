@@ -0,0 +1,110 @@
+package output
+
+import (
+	"encoding/binary"
+)
+
+// Just enough of the Thrift compact protocol to write the Parquet page headers and footer, without pulling in Thrift.
+// Structs are written by calling the field writers in ascending field ID order, followed by `endStruct`.
+
+// Compact protocol type IDs
+const (
+	thriftI32    = 5
+	thriftI64    = 6
+	thriftBinary = 8
+	thriftList   = 9
+	thriftStruct = 12
+)
+
+type thriftWriter struct {
+	buffer       []byte
+	lastFieldID  int16   // Field IDs are written as a delta from the previous field within the same struct
+	parentFields []int16 // Previous field IDs of the structs that are still open
+}
+
+// fieldHeader - Writes the delta from the previous field when it fits, otherwise the full field ID
+func (writer *thriftWriter) fieldHeader(fieldID int16, fieldType byte) {
+
+	if delta := fieldID - writer.lastFieldID; delta > 0 && delta <= 15 {
+		writer.buffer = append(writer.buffer, byte(delta)<<4|fieldType)
+	} else {
+		writer.buffer = append(writer.buffer, fieldType)
+		writer.varint(uint64(zigzag(int64(fieldID))))
+	}
+	writer.lastFieldID = fieldID
+}
+
+func (writer *thriftWriter) varint(value uint64) {
+	writer.buffer = binary.AppendUvarint(writer.buffer, value)
+}
+
+func zigzag(value int64) uint64 {
+	return uint64(value<<1) ^ uint64(value>>63)
+}
+
+func (writer *thriftWriter) writeI32(fieldID int16, value int32) {
+	writer.fieldHeader(fieldID, thriftI32)
+	writer.varint(zigzag(int64(value)))
+}
+
+func (writer *thriftWriter) writeI64(fieldID int16, value int64) {
+	writer.fieldHeader(fieldID, thriftI64)
+	writer.varint(zigzag(value))
+}
+
+func (writer *thriftWriter) writeString(fieldID int16, value string) {
+	writer.fieldHeader(fieldID, thriftBinary)
+	writer.varint(uint64(len(value)))
+	writer.buffer = append(writer.buffer, value...)
+}
+
+// beginStruct - Opens a struct field. Nothing is written for the root struct, which passes a field ID of zero.
+func (writer *thriftWriter) beginStruct(fieldID int16) {
+	if fieldID != 0 {
+		writer.fieldHeader(fieldID, thriftStruct)
+	}
+	writer.parentFields = append(writer.parentFields, writer.lastFieldID)
+	writer.lastFieldID = 0
+}
+
+// beginListElement - Opens a struct that sits within a list
+func (writer *thriftWriter) beginListElement() {
+	writer.parentFields = append(writer.parentFields, writer.lastFieldID)
+	writer.lastFieldID = 0
+}
+
+// endStruct - Writes the stop byte and returns to the enclosing struct
+func (writer *thriftWriter) endStruct() {
+	writer.buffer = append(writer.buffer, 0)
+	writer.lastFieldID = writer.parentFields[len(writer.parentFields)-1]
+	writer.parentFields = writer.parentFields[:len(writer.parentFields)-1]
+}
+
+// beginList - Writes the list header, the elements follow straight after
+func (writer *thriftWriter) beginList(fieldID int16, elementType byte, size int) {
+
+	writer.fieldHeader(fieldID, thriftList)
+	if size < 15 {
+		writer.buffer = append(writer.buffer, byte(size)<<4|elementType)
+	} else {
+		writer.buffer = append(writer.buffer, 0xF0|elementType)
+		writer.varint(uint64(size))
+	}
+}
+
+// writeI32List - A whole list of i32 values
+func (writer *thriftWriter) writeI32List(fieldID int16, values []int32) {
+	writer.beginList(fieldID, thriftI32, len(values))
+	for _, value := range values {
+		writer.varint(zigzag(int64(value)))
+	}
+}
+
+// writeStringList - A whole list of strings
+func (writer *thriftWriter) writeStringList(fieldID int16, values []string) {
+	writer.beginList(fieldID, thriftBinary, len(values))
+	for _, value := range values {
+		writer.varint(uint64(len(value)))
+		writer.buffer = append(writer.buffer, value...)
+	}
+}
@@ -0,0 +1,148 @@
+package output
+
+import (
+	"billionRowChallenge/utilities"
+	"bufio"
+	"encoding/json"
+	"io"
+	"strconv"
+	"time"
+)
+
+// TimeSeriesSchemaVersion - Version of the time series JSON and NDJSON layouts, `timeseries.v1.schema.json` and
+// `timebucket.v1.schema.json` within `output/schema`. Kept apart from `ResultsSchemaVersion`, as the layouts differ.
+const TimeSeriesSchemaVersion = 1
+
+// TimeSeriesColumns - Columns of the tabular time series output, in order
+var TimeSeriesColumns = []string{"station", "bucket", "min", "mean", "max", "count", "sum"}
+
+// TimeBucket - The readings of a single station within a single time bucket
+type TimeBucket struct {
+	Start  time.Time // Beginning of the bucket, within the zone the buckets were drawn in
+	Values utilities.OutputValues
+}
+
+// TimeSeries - The buckets of every station in time order, keyed by station
+type TimeSeries map[string][]TimeBucket
+
+// Totals - Every station's buckets combined, as the plain results
+func (series TimeSeries) Totals() map[string]utilities.OutputValues {
+
+	var outputMap = make(map[string]utilities.OutputValues, len(series))
+	for station, buckets := range series {
+		for _, bucket := range buckets {
+			if outputValues, ok := outputMap[station]; ok {
+				outputMap[station] = utilities.MergeOutputValues(outputValues, bucket.Values)
+			} else {
+				outputMap[station] = bucket.Values
+			}
+		}
+	}
+
+	return outputMap
+}
+
+// TimeBucketValues - A single bucket within the JSON outputs, the start written as RFC3339 with the zone's offset
+type TimeBucketValues struct {
+	Start string `json:"start"`
+	StationValues
+}
+
+// TimeBucketRecord - A single NDJSON line of the time series
+type TimeBucketRecord struct {
+	SchemaVersion int    `json:"schemaVersion"`
+	Station       string `json:"station"`
+	TimeBucketValues
+}
+
+// TimeSeriesResults - The whole JSON document of the time series, keyed by station
+type TimeSeriesResults struct {
+	SchemaVersion int                           `json:"schemaVersion"`
+	Run           RunMetadata                   `json:"run"`
+	Bucket        string                        `json:"bucket"`
+	TimeZone      string                        `json:"timeZone"`
+	Stations      map[string][]TimeBucketValues `json:"stations"`
+}
+
+// newTimeBucketValues - Converts a bucket into its JSON values
+func newTimeBucketValues(bucket TimeBucket) TimeBucketValues {
+	return TimeBucketValues{Start: bucket.Start.Format(time.RFC3339), StationValues: NewStationValues(bucket.Values)}
+}
+
+// WriteTimeSeriesTable - Writes one row per station and bucket, sorted by station and then by time. The sort options
+// are not used, as the rows always follow the series.
+func WriteTimeSeriesTable(writer io.Writer, series TimeSeries, options TableOptions) error {
+
+	bufferedWriter := bufio.NewWriter(writer)
+
+	if options.Header {
+		if err := writeTableRow(bufferedWriter, TimeSeriesColumns, options); err != nil {
+			return err
+		}
+	}
+
+	var fields = make([]string, len(TimeSeriesColumns))
+	for _, station := range SortedStations(series.Totals()) {
+		for _, bucket := range series[station] {
+			fields[0] = station
+			fields[1] = bucket.Start.Format(time.RFC3339)
+			fields[2] = FormatTemperature(bucket.Values.Min)
+			fields[3] = FormatTemperature(RoundedMean(bucket.Values.Total, bucket.Values.Count))
+			fields[4] = FormatTemperature(bucket.Values.Max)
+			fields[5] = strconv.Itoa(bucket.Values.Count)
+			fields[6] = FormatTemperature(bucket.Values.Total)
+
+			if err := writeTableRow(bufferedWriter, fields, options); err != nil {
+				return err
+			}
+		}
+	}
+
+	return bufferedWriter.Flush()
+}
+
+// WriteTimeSeriesJSON - Writes the time series as a single JSON document along with the run metadata, the bucket size,
+// and the zone the buckets were drawn in
+func WriteTimeSeriesJSON(writer io.Writer, series TimeSeries, metadata RunMetadata, bucket string, timeZone string) error {
+
+	results := TimeSeriesResults{
+		SchemaVersion: TimeSeriesSchemaVersion,
+		Run:           metadata,
+		Bucket:        bucket,
+		TimeZone:      timeZone,
+		Stations:      make(map[string][]TimeBucketValues, len(series)),
+	}
+	for station, buckets := range series {
+		var values = make([]TimeBucketValues, len(buckets))
+		for index, bucket := range buckets {
+			values[index] = newTimeBucketValues(bucket)
+		}
+		results.Stations[station] = values
+	}
+
+	encoder := json.NewEncoder(writer)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(results)
+}
+
+// WriteTimeSeriesNDJSON - Writes one JSON record per station and bucket, one per line, sorted by station and then by
+// time
+func WriteTimeSeriesNDJSON(writer io.Writer, series TimeSeries) error {
+
+	encoder := json.NewEncoder(writer)
+
+	for _, station := range SortedStations(series.Totals()) {
+		for _, bucket := range series[station] {
+			err := encoder.Encode(TimeBucketRecord{
+				SchemaVersion:    TimeSeriesSchemaVersion,
+				Station:          station,
+				TimeBucketValues: newTimeBucketValues(bucket),
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,148 @@
+package output
+
+import (
+	"billionRowChallenge/utilities"
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// testTimeSeries - Two stations, one of them with a bucket either side of midnight
+func testTimeSeries() TimeSeries {
+
+	london, _ := time.LoadLocation("Europe/London")
+	return TimeSeries{
+		"Abha": {
+			{Start: time.Date(2024, 7, 1, 0, 0, 0, 0, london), Values: utilities.OutputValues{Min: -5, Max: 125, Total: 120, Count: 2}},
+			{Start: time.Date(2024, 7, 2, 0, 0, 0, 0, london), Values: utilities.OutputValues{Min: 10, Max: 10, Total: 10, Count: 1}},
+		},
+		"Cork": {
+			{Start: time.Date(2024, 7, 1, 0, 0, 0, 0, london), Values: utilities.OutputValues{Min: 90, Max: 90, Total: 90, Count: 1}},
+		},
+	}
+}
+
+// schemaDefinitionFields - The required and allowed properties of a definition within one of the published schemas
+func schemaDefinitionFields(t *testing.T, name string, definition string) ([]string, map[string]json.RawMessage) {
+
+	schemaBytes, err := os.ReadFile(filepath.Join("schema", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var schema struct {
+		Definitions map[string]struct {
+			Required   []string                   `json:"required"`
+			Properties map[string]json.RawMessage `json:"properties"`
+		} `json:"$defs"`
+	}
+	if err = json.Unmarshal(schemaBytes, &schema); err != nil {
+		t.Fatal(err)
+	}
+
+	fields, ok := schema.Definitions[definition]
+	if !ok {
+		t.Fatalf("%v does not define %q", name, definition)
+	}
+	return fields.Required, fields.Properties
+}
+
+func TestWriteTimeSeriesJSON(t *testing.T) {
+
+	series := testTimeSeries()
+	metadata := NewRunMetadata("timeseries", "measurements.txt", time.Now(), series.Totals())
+
+	var buffer bytes.Buffer
+	if err := WriteTimeSeriesJSON(&buffer, series, metadata, "day", "Europe/London"); err != nil {
+		t.Fatalf("WriteTimeSeriesJSON: %v", err)
+	}
+
+	var document struct {
+		Stations map[string][]map[string]json.RawMessage `json:"stations"`
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(buffer.Bytes(), &fields); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(buffer.Bytes(), &document); err != nil {
+		t.Fatal(err)
+	}
+
+	required, properties := schemaFields(t, "timeseries.v1.schema.json")
+	checkSchemaFields(t, fields, required, properties)
+
+	required, properties = schemaDefinitionFields(t, "timeseries.v1.schema.json", "timeBucketValues")
+	for _, buckets := range document.Stations {
+		for _, bucket := range buckets {
+			checkSchemaFields(t, bucket, required, properties)
+		}
+	}
+
+	var results TimeSeriesResults
+	if err := json.Unmarshal(buffer.Bytes(), &results); err != nil {
+		t.Fatal(err)
+	}
+	if results.SchemaVersion != TimeSeriesSchemaVersion || results.Bucket != "day" || results.TimeZone != "Europe/London" {
+		t.Errorf("results = %+v", results)
+	}
+	if abha := results.Stations["Abha"]; len(abha) != 2 || abha[0].Start != "2024-07-01T00:00:00+01:00" || abha[0].Mean != "6.0" {
+		t.Errorf("Abha = %+v", abha)
+	}
+}
+
+func TestWriteTimeSeriesNDJSON(t *testing.T) {
+
+	var buffer bytes.Buffer
+	if err := WriteTimeSeriesNDJSON(&buffer, testTimeSeries()); err != nil {
+		t.Fatalf("WriteTimeSeriesNDJSON: %v", err)
+	}
+
+	required, properties := schemaFields(t, "timebucket.v1.schema.json")
+
+	var records []TimeBucketRecord
+	scanner := bufio.NewScanner(&buffer)
+	for scanner.Scan() {
+		var fields map[string]json.RawMessage
+		if err := json.Unmarshal(scanner.Bytes(), &fields); err != nil {
+			t.Fatalf("line %q: %v", scanner.Text(), err)
+		}
+		checkSchemaFields(t, fields, required, properties)
+
+		var record TimeBucketRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			t.Fatal(err)
+		}
+		records = append(records, record)
+	}
+
+	if len(records) != 3 || records[0].Station != "Abha" || records[1].Start != "2024-07-02T00:00:00+01:00" || records[2].Station != "Cork" {
+		t.Fatalf("records = %+v, expected them sorted by station and then by time", records)
+	}
+	for _, record := range records {
+		if record.SchemaVersion != TimeSeriesSchemaVersion {
+			t.Errorf("schemaVersion = %v, expected %v", record.SchemaVersion, TimeSeriesSchemaVersion)
+		}
+	}
+}
+
+func TestTimeSeriesSchemaVersions(t *testing.T) {
+
+	// Each schema pins the version its layout is written with
+	for _, name := range []string{"timeseries.v1.schema.json", "timebucket.v1.schema.json"} {
+		_, properties := schemaFields(t, name)
+
+		var schemaVersion struct {
+			Const int `json:"const"`
+		}
+		if err := json.Unmarshal(properties["schemaVersion"], &schemaVersion); err != nil {
+			t.Fatal(err)
+		}
+		if schemaVersion.Const != TimeSeriesSchemaVersion {
+			t.Errorf("%v pins schemaVersion %v, expected %v", name, schemaVersion.Const, TimeSeriesSchemaVersion)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package output
+
+import (
+	"billionRowChallenge/utilities"
+	"container/heap"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// TopKeys - Keys stations can be ranked by
+var TopKeys = []string{"mean", "max", "min", "count", "sum"}
+
+// TopStations - The `n` stations ranking highest by the key, highest first, or the lowest first when `ascending`. Ties
+// are ranked by station name. Only the leaders are ever kept in order, within a heap of `n` stations, so picking a few
+// out of many thousands of stations never sorts them all.
+func TopStations(outputMap map[string]utilities.OutputValues, by string, n int, ascending bool) ([]string, error) {
+
+	if !slices.Contains(TopKeys, by) {
+		return nil, fmt.Errorf("unknown ranking key %q, expected one of %v", by, strings.Join(TopKeys, ", "))
+	}
+	compareStations, err := CompareStations(outputMap, by)
+	if err != nil {
+		return nil, err
+	}
+
+	// Ranks first when it compares lowest
+	rank := compareStations
+	if !ascending {
+		rank = func(first string, second string) int { return compareStations(second, first) }
+	}
+
+	if n <= 0 {
+		return []string{}, nil
+	}
+
+	// The root is the weakest of the leaders, replaced whenever a station outranks it
+	leaders := &stationHeap{stations: make([]string, 0, min(n, len(outputMap))), rank: rank}
+	for station := range outputMap {
+		if len(leaders.stations) < n {
+			heap.Push(leaders, station)
+		} else if rank(station, leaders.stations[0]) < 0 {
+			leaders.stations[0] = station
+			heap.Fix(leaders, 0)
+		}
+	}
+
+	slices.SortFunc(leaders.stations, rank)
+	return leaders.stations, nil
+}
+
+// stationHeap - Stations held with the lowest ranking at the root
+type stationHeap struct {
+	stations []string
+	rank     func(first string, second string) int
+}
+
+func (stationHeap *stationHeap) Len() int { return len(stationHeap.stations) }
+
+func (stationHeap *stationHeap) Less(first int, second int) bool {
+	return stationHeap.rank(stationHeap.stations[first], stationHeap.stations[second]) > 0
+}
+
+func (stationHeap *stationHeap) Swap(first int, second int) {
+	stationHeap.stations[first], stationHeap.stations[second] = stationHeap.stations[second], stationHeap.stations[first]
+}
+
+func (stationHeap *stationHeap) Push(station any) {
+	stationHeap.stations = append(stationHeap.stations, station.(string))
+}
+
+func (stationHeap *stationHeap) Pop() any {
+	last := stationHeap.stations[len(stationHeap.stations)-1]
+	stationHeap.stations = stationHeap.stations[:len(stationHeap.stations)-1]
+	return last
+}
@@ -0,0 +1,78 @@
+package output
+
+import (
+	"billionRowChallenge/utilities"
+	"cmp"
+	"fmt"
+	"math/rand/v2"
+	"slices"
+	"testing"
+)
+
+func TestTopStationsMatchesFullSort(t *testing.T) {
+
+	// Few distinct values, so many stations tie and are ranked by name
+	random := rand.New(rand.NewPCG(13, 14))
+	outputMap := make(map[string]utilities.OutputValues)
+	for range 2_000 {
+		outputMap[fmt.Sprintf("Station %06d", random.IntN(1_000_000))] = utilities.OutputValues{
+			Min: random.IntN(20) - 10, Max: random.IntN(20), Total: random.IntN(40) - 20, Count: random.IntN(5) + 1,
+		}
+	}
+
+	valueOf := map[string]func(outputValues utilities.OutputValues) float64{
+		"mean": func(outputValues utilities.OutputValues) float64 {
+			return float64(outputValues.Total) / float64(outputValues.Count)
+		},
+		"max":   func(outputValues utilities.OutputValues) float64 { return float64(outputValues.Max) },
+		"min":   func(outputValues utilities.OutputValues) float64 { return float64(outputValues.Min) },
+		"count": func(outputValues utilities.OutputValues) float64 { return float64(outputValues.Count) },
+		"sum":   func(outputValues utilities.OutputValues) float64 { return float64(outputValues.Total) },
+	}
+
+	for _, by := range TopKeys {
+		// Every station sorted lowest first, the descending ranking being the same order reversed
+		sorted := make([]string, 0, len(outputMap))
+		for station := range outputMap {
+			sorted = append(sorted, station)
+		}
+		slices.SortFunc(sorted, func(first string, second string) int {
+			return cmp.Or(cmp.Compare(valueOf[by](outputMap[first]), valueOf[by](outputMap[second])), cmp.Compare(first, second))
+		})
+		reversed := slices.Clone(sorted)
+		slices.Reverse(reversed)
+
+		for _, n := range []int{1, 10, 500, len(outputMap), len(outputMap) + 10} {
+			for _, ascending := range []bool{true, false} {
+				expected := reversed
+				if ascending {
+					expected = sorted
+				}
+				expected = expected[:min(n, len(expected))]
+
+				stations, err := TopStations(outputMap, by, n, ascending)
+				if err != nil {
+					t.Fatalf("TopStations(%v): %v", by, err)
+				}
+				if !slices.Equal(stations, expected) {
+					t.Errorf("TopStations(%v, %v, ascending %v) differs from the full sort", by, n, ascending)
+				}
+			}
+		}
+	}
+}
+
+func TestTopStationsArguments(t *testing.T) {
+
+	if stations, err := TopStations(testOutputMap(), "mean", 0, false); err != nil || len(stations) != 0 {
+		t.Errorf("TopStations(0) = %v, %v", stations, err)
+	}
+	if stations, err := TopStations(map[string]utilities.OutputValues{}, "mean", 3, false); err != nil || len(stations) != 0 {
+		t.Errorf("TopStations of no stations = %v, %v", stations, err)
+	}
+	for _, by := range []string{"", "station", "median"} {
+		if _, err := TopStations(testOutputMap(), by, 3, false); err == nil {
+			t.Errorf("TopStations(%q) succeeded", by)
+		}
+	}
+}
@@ -587,7 +587,7 @@ func PartialReadManager(entryWaitGroup *sync.WaitGroup, numberOfRoutineCalls int
 		// partialRead: `[]byte{ame} []byte{26} 0x2`
 		//
 		// If the existing entry has a `nil` decimal field, then that entry STARTS with the valid city bytes
-		if value.DecimalField == string(0x00) {
+		if value.DecimalField == string(rune(0x00)) {
 			// cityCompleteArray = append(value.City, partialEntry.City...)
 			// temperatureCompleteArray = append(value.TemperatureField, partialEntry.TemperatureWhole...)
 			// temperatureCompleteArray = append(temperatureCompleteArray, partialEntry.DecimalPoint)
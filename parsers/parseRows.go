@@ -0,0 +1,140 @@
+package parsers
+
+import (
+	"billionRowChallenge/utilities"
+)
+
+// ScanRows - Walks every complete row within the byte slice with the same rotating `;` -> `.` -> `\n` inspection as
+// `ParseByteBuffer`, but expects the slice to begin at the start of a row, so no partial reads need to be linked
+// together. Hands each row over to `record` as the index the row begins at, the station, the whole-number bytes of the
+// temperature and every byte between the decimal point and the newline, all straight out of the byte slice, so none of
+// them may be kept past the call.
+//
+// Rows missing the semicolon, the decimal or the digit after it are skipped. Any bytes after the final newline are
+// left alone. Returns the number of bytes that were consumed so the caller can carry the partial row over into the
+// next read.
+func ScanRows(byteData []byte, record func(rowStart int, station []byte, temperatureWhole []byte, temperatureDecimal []byte)) int {
+
+	var byteSliceStartingIndex int // Starting index of the current line
+	var targetByteToCheckFor uint  // Rotate which byte character is currently being watched for
+
+	// Target values to inspect for and the index position they were last found at
+	var targetBytes = [3]byte{utilities.SemicolonHex, utilities.DecimalHex, utilities.NewLineHex}
+	var targetIndexes [3]int
+
+	for index := range byteData {
+
+		// The target byte fields (`;`, `.`, `\n`) always appear in the same order, so only one check is needed per byte
+		if byteData[index] != targetBytes[targetByteToCheckFor] {
+
+			// A newline before the row is complete means the row is malformed, so drop it and start over on the next row
+			if byteData[index] == utilities.NewLineHex {
+				targetByteToCheckFor = 0
+				byteSliceStartingIndex = index + 1
+			}
+			continue
+		}
+		targetIndexes[targetByteToCheckFor] = index
+
+		// Move to the next target byte to inspect
+		targetByteToCheckFor++
+
+		// Once the newline is found, the full row is available
+		if targetByteToCheckFor > 2 {
+			if targetIndexes[utilities.NewLineIndex] > targetIndexes[utilities.DecimalIndex]+1 {
+				record(
+					byteSliceStartingIndex,
+					byteData[byteSliceStartingIndex:targetIndexes[utilities.SemiColonIndex]],
+					byteData[targetIndexes[utilities.SemiColonIndex]+1:targetIndexes[utilities.DecimalIndex]],
+					byteData[targetIndexes[utilities.DecimalIndex]+1:targetIndexes[utilities.NewLineIndex]],
+				)
+			}
+
+			targetByteToCheckFor = 0           // Reset the inspector for the next loop
+			byteSliceStartingIndex = index + 1 // Set the starting index for the next row
+		}
+	}
+
+	return byteSliceStartingIndex
+}
+
+// ParseRows - Parses every complete row found by `ScanRows` and adds the readings straight into the output map.
+// Returns the number of bytes that were consumed, the same as `ScanRows`.
+func ParseRows(byteData []byte, outputMap map[string]utilities.OutputValues) int {
+	return ScanRows(byteData, func(_ int, station []byte, temperatureWhole []byte, temperatureDecimal []byte) {
+		utilities.AddTemperature(outputMap, string(station), ParseTemperature(temperatureWhole, temperatureDecimal[len(temperatureDecimal)-1]))
+	})
+}
+
+// ValidTemperature - Whether the temperature bytes handed over by `ScanRows` hold a reading `ParseTemperature` can
+// convert: an optional minus sign, one or two digits, the decimal point and a single digit. `ParseTemperature` trusts
+// every byte to be a digit, so anything read from outside the measurements file must pass this check first.
+func ValidTemperature(temperatureWhole []byte, temperatureDecimal []byte) bool {
+
+	if len(temperatureWhole) > 0 && temperatureWhole[0] == utilities.NegativeHex {
+		temperatureWhole = temperatureWhole[1:]
+	}
+	if len(temperatureWhole) < 1 || len(temperatureWhole) > 2 || len(temperatureDecimal) != 1 {
+		return false
+	}
+
+	for _, digit := range temperatureWhole {
+		if !isDigit(digit) {
+			return false
+		}
+	}
+	return isDigit(temperatureDecimal[0])
+}
+
+// isDigit - Whether the byte is one of `0` to `9`
+func isDigit(digit byte) bool {
+	return digit >= utilities.ZeroHex && digit <= utilities.NineHex
+}
+
+// ParseTemperature - Converts the whole-number bytes and the singular decimal byte into the temperature value,
+// multiplied by 10. Skips the `strconv` round trip, as the values are always in the `-99.9` to `99.9` range.
+func ParseTemperature(temperatureWholeByteSlice []byte, temperatureDecimalByte byte) int {
+
+	var isNegative bool
+	var temperatureValue int
+
+	for _, digit := range temperatureWholeByteSlice {
+		if digit == utilities.NegativeHex {
+			isNegative = true
+			continue
+		}
+		temperatureValue = temperatureValue*10 + int(digit-utilities.ZeroHex)
+	}
+	temperatureValue = temperatureValue*10 + int(temperatureDecimalByte-utilities.ZeroHex)
+
+	if isNegative {
+		return -temperatureValue
+	}
+	return temperatureValue
+}
+
+// ParseRowsFunc - The same as `ParseRows`, but hands every complete row over to `record` rather than adding it into an
+// output map
+func ParseRowsFunc(byteData []byte, record func(station string, temperature int)) int {
+	return ScanRows(byteData, func(_ int, station []byte, temperatureWhole []byte, temperatureDecimal []byte) {
+		record(string(station), ParseTemperature(temperatureWhole, temperatureDecimal[len(temperatureDecimal)-1]))
+	})
+}
+
+// ParseRowsAt - The same as `ParseRowsFunc`, while also handing over the index each row begins at within the byte
+// slice, for callers that need to point back at the rows themselves. The station is left as bytes straight out of the
+// slice, as most rows are only looked up and never kept, so it must be copied before it is kept past the call.
+func ParseRowsAt(byteData []byte, record func(rowStart int, station []byte, temperature int)) int {
+	return ScanRows(byteData, func(rowStart int, station []byte, temperatureWhole []byte, temperatureDecimal []byte) {
+		record(rowStart, station, ParseTemperature(temperatureWhole, temperatureDecimal[len(temperatureDecimal)-1]))
+	})
+}
+
+// ParseStations - Walks the rows the same as `ParseRowsFunc`, skipping the same malformed rows, but only hands over the
+// station name of each, straight out of the byte slice. The temperature is never parsed and the name never copied, so
+// the slice must not be kept past the call.
+func ParseStations(byteData []byte, record func(station []byte)) int {
+	return ScanRows(byteData, func(_ int, station []byte, _ []byte, _ []byte) {
+		record(station)
+	})
+}
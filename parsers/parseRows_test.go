@@ -0,0 +1,156 @@
+package parsers
+
+import (
+	"billionRowChallenge/utilities"
+	"fmt"
+	"maps"
+	"slices"
+	"testing"
+)
+
+// scannedRow - One row as handed over by `ScanRows`, copied out of the byte slice
+type scannedRow struct {
+	rowStart int
+	station  string
+	whole    string
+	decimal  string
+}
+
+func TestScanRows(t *testing.T) {
+
+	tests := []struct {
+		name     string
+		data     string
+		rows     []scannedRow
+		consumed int
+	}{
+		{"empty", "", nil, 0},
+		{"rows", "Abha;12.3\nZürich;-4.5\n", []scannedRow{{0, "Abha", "12", "3"}, {10, "Zürich", "-4", "5"}}, 23},
+		{"partial final row", "Abha;1.2\nAcc", []scannedRow{{0, "Abha", "1", "2"}}, 9},
+		{"partial final decimal", "Abha;1.2\nAccra;3.", []scannedRow{{0, "Abha", "1", "2"}}, 9},
+		{"missing semicolon", "Abha 1.2\nAccra;3.4\n", []scannedRow{{9, "Accra", "3", "4"}}, 19},
+		{"missing decimal", "Abha;12\nAccra;3.4\n", []scannedRow{{8, "Accra", "3", "4"}}, 18},
+		{"missing decimal digit", "Abha;12.\nAccra;3.4\n", []scannedRow{{9, "Accra", "3", "4"}}, 19},
+		{"empty line", "\nAbha;1.2\n", []scannedRow{{1, "Abha", "1", "2"}}, 10},
+		{"dot in station", "St. John's;-0.1\n", []scannedRow{{0, "St. John's", "-0", "1"}}, 16},
+		{"carriage return", "Abha;1.2\r\n", []scannedRow{{0, "Abha", "1", "2\r"}}, 10},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var rows []scannedRow
+			consumed := ScanRows([]byte(test.data), func(rowStart int, station []byte, temperatureWhole []byte, temperatureDecimal []byte) {
+				rows = append(rows, scannedRow{rowStart, string(station), string(temperatureWhole), string(temperatureDecimal)})
+			})
+
+			if !slices.Equal(rows, test.rows) {
+				t.Errorf("rows = %+v, expected %+v", rows, test.rows)
+			}
+			if consumed != test.consumed {
+				t.Errorf("consumed %v bytes, expected %v", consumed, test.consumed)
+			}
+		})
+	}
+}
+
+func TestParseTemperature(t *testing.T) {
+
+	tests := map[string]int{
+		"0.0": 0, "-0.0": 0, "0.1": 1, "-0.1": -1, "9.9": 99, "12.3": 123, "-12.3": -123, "99.9": 999, "-99.9": -999,
+	}
+
+	for temperature, expected := range tests {
+		whole, decimal := temperature[:len(temperature)-2], temperature[len(temperature)-1]
+		if value := ParseTemperature([]byte(whole), decimal); value != expected {
+			t.Errorf("ParseTemperature(%q) = %v, expected %v", temperature, value, expected)
+		}
+	}
+}
+
+func TestValidTemperature(t *testing.T) {
+
+	tests := []struct {
+		whole   string
+		decimal string
+		valid   bool
+	}{
+		{"0", "0", true},
+		{"-9", "9", true},
+		{"99", "9", true},
+		{"-99", "9", true},
+		{"", "1", false},
+		{"-", "1", false},
+		{"100", "0", false},
+		{"--1", "0", false},
+		{"1-", "0", false},
+		{"1a", "0", false},
+		{" 1", "0", false},
+		{"1", "", false},
+		{"1", "23", false},
+		{"1", "x", false},
+		{"1", "2\r", false},
+	}
+
+	for _, test := range tests {
+		if valid := ValidTemperature([]byte(test.whole), []byte(test.decimal)); valid != test.valid {
+			t.Errorf("ValidTemperature(%q, %q) = %v, expected %v", test.whole, test.decimal, valid, test.valid)
+		}
+	}
+}
+
+func TestParseRowsAcrossReads(t *testing.T) {
+
+	rows := benchmarkRows()
+
+	expected := make(map[string]utilities.OutputValues)
+	ParseRows(rows, expected)
+
+	// Feed the rows through in uneven reads, carrying each partial row over the way the section readers do
+	for _, readSize := range []int{1, 7, 64, 4093} {
+		outputMap := make(map[string]utilities.OutputValues)
+
+		var carried []byte
+		for position := 0; position < len(rows); position += readSize {
+			carried = append(carried, rows[position:min(position+readSize, len(rows))]...)
+			carried = carried[ParseRows(carried, outputMap):]
+		}
+
+		if len(carried) != 0 || !maps.Equal(outputMap, expected) {
+			t.Errorf("reads of %v bytes gave different results, with %v bytes left over", readSize, len(carried))
+		}
+	}
+}
+
+// benchmarkRows - A few megabytes of rows spread over a few hundred stations, the same shape as the measurements file
+func benchmarkRows() []byte {
+
+	var rows []byte
+	for row := range 200_000 {
+		rows = fmt.Appendf(rows, "Station %03d;%.1f\n", row%413, float64(row%1999-999)/10)
+	}
+	return rows
+}
+
+// BenchmarkParseRows - The aggregation every section reader runs, going through the `ScanRows` callback
+func BenchmarkParseRows(b *testing.B) {
+
+	rows := benchmarkRows()
+	b.SetBytes(int64(len(rows)))
+	b.ResetTimer()
+
+	for range b.N {
+		ParseRows(rows, make(map[string]utilities.OutputValues))
+	}
+}
+
+// BenchmarkScanRows - Only the row scan, with a callback that does nothing, to show what the scan costs on its own
+func BenchmarkScanRows(b *testing.B) {
+
+	rows := benchmarkRows()
+	b.SetBytes(int64(len(rows)))
+	b.ResetTimer()
+
+	for range b.N {
+		ScanRows(rows, func(int, []byte, []byte, []byte) {})
+	}
+}
@@ -0,0 +1,176 @@
+package quantilesketch
+
+import (
+	"fmt"
+	"math"
+)
+
+// MaxBins - Bins each side of a sketch may hold. Past this the bins nearest zero are folded together, which only costs
+// accuracy for the values closest to zero once the sketch spans more than `gamma^MaxBins`, around 17 orders of
+// magnitude at 1% relative accuracy.
+const MaxBins = 2048
+
+// Mapping - Maps values onto logarithmically sized bins so that every value within a bin is within the relative
+// accuracy of the bin's representative value
+type Mapping struct {
+	RelativeAccuracy float64
+	gamma            float64 // Ratio between the bounds of a bin
+	multiplier       float64 // 1 / ln(gamma)
+}
+
+// NewMapping - The mapping for a relative accuracy between 0 and 1, exclusive. An accuracy of 0.01 means every quantile
+// is within 1% of a value that was actually read.
+func NewMapping(relativeAccuracy float64) (*Mapping, error) {
+
+	if !(relativeAccuracy > 0 && relativeAccuracy < 1) {
+		return nil, fmt.Errorf("relative accuracy %v must be between 0 and 1", relativeAccuracy)
+	}
+
+	gamma := (1 + relativeAccuracy) / (1 - relativeAccuracy)
+	return &Mapping{RelativeAccuracy: relativeAccuracy, gamma: gamma, multiplier: 1 / math.Log(gamma)}, nil
+}
+
+// index - The bin holding a positive value, which is `(gamma^(index-1), gamma^index]`
+func (mapping *Mapping) index(value float64) int {
+	return int(math.Ceil(math.Log(value) * mapping.multiplier))
+}
+
+// value - The representative value of a bin, equally far in relative terms from both of its bounds
+func (mapping *Mapping) value(index int) float64 {
+	return 2 * math.Pow(mapping.gamma, float64(index)) / (mapping.gamma + 1)
+}
+
+// store - Counts within a run of consecutive bins
+type store struct {
+	offset int // Bin index of counts[0]
+	counts []uint64
+}
+
+// add - Counts within the bin, growing the run to reach it. A run that would span more than `MaxBins` folds its lowest
+// bins into the lowest one kept.
+func (store *store) add(index int, count uint64) {
+
+	if len(store.counts) == 0 {
+		store.offset = index
+		store.counts = make([]uint64, 1)
+	}
+
+	lowest := min(store.offset, index)
+	highest := max(store.offset+len(store.counts)-1, index)
+	if highest-lowest+1 > MaxBins {
+		lowest = highest - MaxBins + 1
+	}
+	store.extend(lowest, highest)
+
+	store.counts[max(index, lowest)-store.offset] += count
+}
+
+// extend - Moves the run over to cover exactly `lowest` through `highest`, folding any bins below `lowest` into it
+func (store *store) extend(lowest int, highest int) {
+
+	if lowest == store.offset && highest == store.offset+len(store.counts)-1 {
+		return
+	}
+
+	var counts = make([]uint64, highest-lowest+1)
+	for index, count := range store.counts {
+		counts[max(store.offset+index, lowest)-lowest] += count
+	}
+	store.offset, store.counts = lowest, counts
+}
+
+// merge - Adds every bin of the other run
+func (store *store) merge(other store) {
+	for index, count := range other.counts {
+		if count > 0 {
+			store.add(other.offset+index, count)
+		}
+	}
+}
+
+// Sketch - A DDSketch: an approximate record of the values read, small enough to keep per station and able to be
+// merged without losing accuracy. Positive and negative values are binned by magnitude apart from each other, and zero
+// is counted on its own.
+type Sketch struct {
+	mapping  *Mapping
+	positive store
+	negative store
+	zero     uint64
+	count    uint64
+}
+
+// NewSketch - An empty sketch using the mapping
+func NewSketch(mapping *Mapping) *Sketch {
+	return &Sketch{mapping: mapping}
+}
+
+// Add - Records a single value
+func (sketch *Sketch) Add(value float64) {
+
+	sketch.count++
+
+	switch {
+	case value > 0:
+		sketch.positive.add(sketch.mapping.index(value), 1)
+	case value < 0:
+		sketch.negative.add(sketch.mapping.index(-value), 1)
+	default:
+		sketch.zero++
+	}
+}
+
+// Merge - Adds every value recorded by the other sketch, which must share the same relative accuracy
+func (sketch *Sketch) Merge(other *Sketch) {
+
+	sketch.positive.merge(other.positive)
+	sketch.negative.merge(other.negative)
+	sketch.zero += other.zero
+	sketch.count += other.count
+}
+
+// Count - Number of values recorded
+func (sketch *Sketch) Count() int {
+	return int(sketch.count)
+}
+
+// Quantiles - The value at each percentile, using the nearest rank the same as exact percentiles do, within the relative
+// accuracy of the value that rank actually holds. Percentiles must be given in ascending order.
+func (sketch *Sketch) Quantiles(percentiles ...float64) []float64 {
+
+	var values = make([]float64, len(percentiles))
+	if sketch.count == 0 {
+		return values
+	}
+
+	var ranks = make([]uint64, len(percentiles))
+	for index, percentile := range percentiles {
+		ranks[index] = max(uint64(math.Ceil(percentile*float64(sketch.count)/100)), 1)
+	}
+
+	var seen uint64
+	var next int
+	visit := func(value float64, count uint64) {
+		seen += count
+		for next < len(ranks) && ranks[next] <= seen {
+			values[next] = value
+			next++
+		}
+	}
+
+	// Lowest first: the largest negative magnitudes, then zero, then the positive values
+	for index := len(sketch.negative.counts) - 1; index >= 0; index-- {
+		if count := sketch.negative.counts[index]; count > 0 {
+			visit(-sketch.mapping.value(sketch.negative.offset+index), count)
+		}
+	}
+	if sketch.zero > 0 {
+		visit(0, sketch.zero)
+	}
+	for index, count := range sketch.positive.counts {
+		if count > 0 {
+			visit(sketch.mapping.value(sketch.positive.offset+index), count)
+		}
+	}
+
+	return values
+}
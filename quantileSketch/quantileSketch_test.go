@@ -0,0 +1,210 @@
+package quantilesketch
+
+import (
+	resultsnapshot "billionRowChallenge/resultSnapshot"
+	"billionRowChallenge/utilities"
+	"bytes"
+	"errors"
+	"math"
+	"math/rand/v2"
+	"slices"
+	"testing"
+)
+
+var testPercentiles = []float64{0, 0.1, 1, 10, 25, 50, 75, 90, 99, 99.9, 100}
+
+// exactQuantiles - The value at each percentile by nearest rank over the sorted values
+func exactQuantiles(values []float64, percentiles []float64) []float64 {
+
+	sorted := slices.Clone(values)
+	slices.Sort(sorted)
+
+	var quantiles = make([]float64, len(percentiles))
+	for index, percentile := range percentiles {
+		rank := max(int(math.Ceil(percentile*float64(len(sorted))/100)), 1)
+		quantiles[index] = sorted[rank-1]
+	}
+	return quantiles
+}
+
+// checkAccuracy - Fails unless every quantile is within the relative accuracy of the exact one
+func checkAccuracy(t *testing.T, sketch *Sketch, values []float64) {
+
+	t.Helper()
+	quantiles := sketch.Quantiles(testPercentiles...)
+	for index, exact := range exactQuantiles(values, testPercentiles) {
+		if math.Abs(quantiles[index]-exact) > sketch.mapping.RelativeAccuracy*math.Abs(exact)*(1+1e-9) {
+			t.Errorf("p%v = %v, expected within %v of %v", testPercentiles[index], quantiles[index],
+				sketch.mapping.RelativeAccuracy, exact)
+		}
+	}
+}
+
+// testTemperatures - Readings of a few stations in tenths, dealt into partials as well as kept whole
+func testTemperatures(mapping *Mapping, partialCount int) (Sketches, []Sketches) {
+
+	random := rand.New(rand.NewPCG(9, 10))
+	collector := NewCollector(mapping)
+
+	whole := collector().(Sketches)
+	var partials = make([]Sketches, partialCount)
+	for index := range partials {
+		partials[index] = collector().(Sketches)
+	}
+
+	for index := range 30_000 {
+		station := []string{"Abha", "Zürich", "Accra"}[random.IntN(3)]
+		reading := random.IntN(1999) - 999
+		whole.Add(station, reading)
+		partials[index%partialCount].Add(station, reading)
+	}
+
+	return whole, partials
+}
+
+func TestNewMapping(t *testing.T) {
+
+	for _, accuracy := range []float64{0, 1, -0.1, 1.5, math.NaN()} {
+		if _, err := NewMapping(accuracy); err == nil {
+			t.Errorf("NewMapping(%v) succeeded", accuracy)
+		}
+	}
+}
+
+func TestQuantilesWithinAccuracy(t *testing.T) {
+
+	random := rand.New(rand.NewPCG(11, 12))
+	tests := map[string]func() float64{
+		"uniform":     func() float64 { return random.Float64()*200 - 100 },
+		"tenths":      func() float64 { return float64(random.IntN(1999) - 999) },
+		"exponential": func() float64 { return random.ExpFloat64() * 1000 },
+		"negative":    func() float64 { return -random.ExpFloat64() },
+	}
+
+	// Accuracies coarse enough that every generator's values fit within MaxBins, so nothing is folded
+	for _, accuracy := range []float64{0.01, 0.05} {
+		mapping, _ := NewMapping(accuracy)
+		for name, generate := range tests {
+			sketch := NewSketch(mapping)
+			var values []float64
+			for range 20_000 {
+				value := generate()
+				sketch.Add(value)
+				values = append(values, value)
+			}
+
+			if sketch.Count() != len(values) {
+				t.Errorf("%v: Count = %v, expected %v", name, sketch.Count(), len(values))
+			}
+			checkAccuracy(t, sketch, values)
+		}
+	}
+
+	mapping, _ := NewMapping(0.01)
+	if quantiles := NewSketch(mapping).Quantiles(50); quantiles[0] != 0 {
+		t.Errorf("median of nothing = %v, expected 0", quantiles[0])
+	}
+}
+
+func TestFoldingKeepsTheHighQuantiles(t *testing.T) {
+
+	// Values spanning far more than MaxBins, so the bins nearest zero are folded together
+	mapping, _ := NewMapping(0.01)
+	sketch := NewSketch(mapping)
+	var values []float64
+	for exponent := -60; exponent <= 60; exponent++ {
+		value := math.Pow(10, float64(exponent))
+		sketch.Add(value)
+		values = append(values, value)
+	}
+
+	if bins := len(sketch.positive.counts); bins > MaxBins {
+		t.Errorf("%v bins held, expected at most %v", bins, MaxBins)
+	}
+	// Around 17 orders of magnitude are kept below the highest value, so 10⁵⁰ upward stay within the accuracy
+	percentiles := []float64{92, 100}
+	quantiles := sketch.Quantiles(percentiles...)
+	for index, exact := range exactQuantiles(values, percentiles) {
+		if math.Abs(quantiles[index]-exact) > 0.01*exact*(1+1e-9) {
+			t.Errorf("p%v = %v, expected within 1%% of %v", percentiles[index], quantiles[index], exact)
+		}
+	}
+	if lowest := sketch.Quantiles(0)[0]; lowest < 1e42 {
+		t.Errorf("p0 = %v, expected the lowest values folded into the lowest bin kept", lowest)
+	}
+}
+
+func TestMergeEqualsSinglePass(t *testing.T) {
+
+	mapping, _ := NewMapping(0.01)
+	whole, partials := testTemperatures(mapping, 5)
+
+	merged := NewCollector(mapping)()
+	for _, partial := range partials {
+		merged.Merge(partial)
+	}
+	if !bytes.Equal(merged.(Sketches).Section().Data, whole.Section().Data) {
+		t.Error("merged partials differ from a single pass")
+	}
+}
+
+func TestSectionRoundTrip(t *testing.T) {
+
+	mapping, _ := NewMapping(0.02)
+	sketches, _ := testTemperatures(mapping, 1)
+	sketches.Add("Zero", 0)
+
+	section := sketches.Section()
+	decoded, err := FromSection(section.Data)
+	if err != nil {
+		t.Fatalf("FromSection: %v", err)
+	}
+	if decoded.Mapping.RelativeAccuracy != mapping.RelativeAccuracy {
+		t.Errorf("decoded relative accuracy = %v, expected %v", decoded.Mapping.RelativeAccuracy, mapping.RelativeAccuracy)
+	}
+	for station, sketch := range sketches.Stations {
+		decodedSketch := decoded.Stations[station]
+		if decodedSketch == nil || decodedSketch.Count() != sketch.Count() ||
+			!slices.Equal(decodedSketch.Quantiles(testPercentiles...), sketch.Quantiles(testPercentiles...)) {
+			t.Errorf("decoded %v differs from the sketch encoded", station)
+		}
+	}
+	if !bytes.Equal(decoded.Section().Data, section.Data) {
+		t.Error("encoding the decoded sketches gave different bytes")
+	}
+
+	for length := range len(section.Data) {
+		if _, err := FromSection(section.Data[:length]); err == nil {
+			t.Fatalf("section cut to %v of %v bytes was accepted", length, len(section.Data))
+		}
+	}
+}
+
+func TestSnapshotMerge(t *testing.T) {
+
+	mapping, _ := NewMapping(0.01)
+	whole, partials := testTemperatures(mapping, 2)
+
+	var snapshots []resultsnapshot.Snapshot
+	for _, partial := range partials {
+		snapshot := resultsnapshot.New(map[string]utilities.OutputValues{})
+		snapshot.Sections = []resultsnapshot.Section{partial.Section()}
+		snapshots = append(snapshots, snapshot)
+	}
+
+	merged, err := resultsnapshot.Merge(snapshots[0], snapshots[1])
+	if err != nil {
+		t.Fatalf("Merge: %v", err)
+	}
+	if data, _ := merged.FindSection(SnapshotSectionTag); !bytes.Equal(data, whole.Section().Data) {
+		t.Error("merged section differs from the section of a single pass")
+	}
+
+	// Sketches of other accuracies cannot be added together
+	otherMapping, _ := NewMapping(0.02)
+	other, _ := testTemperatures(otherMapping, 1)
+	snapshots[1].Sections = []resultsnapshot.Section{other.Section()}
+	if _, err = resultsnapshot.Merge(snapshots[0], snapshots[1]); !errors.Is(err, resultsnapshot.ErrIncompatible) {
+		t.Errorf("merging different accuracies = %v, expected %v", err, resultsnapshot.ErrIncompatible)
+	}
+}
@@ -0,0 +1,185 @@
+package quantilesketch
+
+import (
+	resultsnapshot "billionRowChallenge/resultSnapshot"
+	"billionRowChallenge/utilities"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+)
+
+// SnapshotSectionTag - Tag of the snapshot section holding the sketches
+const SnapshotSectionTag = 4
+
+var errSectionTruncated = errors.New("quantile sketch section is truncated")
+
+func init() {
+	resultsnapshot.SectionMergers[SnapshotSectionTag] = mergeSections
+}
+
+// Sketches - A quantile sketch of every station's readings, all sharing the same relative accuracy
+type Sketches struct {
+	Mapping  *Mapping
+	Stations map[string]*Sketch
+}
+
+// NewCollector - Makes empty sketches with the mapping, ready to be handed to the section reader
+func NewCollector(mapping *Mapping) func() utilities.Collector {
+	return func() utilities.Collector {
+		return Sketches{Mapping: mapping, Stations: make(map[string]*Sketch)}
+	}
+}
+
+// Add - Records a single reading within the station's sketch
+func (sketches Sketches) Add(station string, temperature int) {
+
+	sketch, ok := sketches.Stations[station]
+	if !ok {
+		sketch = NewSketch(sketches.Mapping)
+		sketches.Stations[station] = sketch
+	}
+	sketch.Add(float64(temperature))
+}
+
+// Merge - Folds the sketches collected by another section into these. Both must share the same relative accuracy.
+func (sketches Sketches) Merge(other utilities.Collector) {
+	for station, otherSketch := range other.(Sketches).Stations {
+		if sketch, ok := sketches.Stations[station]; ok {
+			sketch.Merge(otherSketch)
+		} else {
+			sketches.Stations[station] = otherSketch
+		}
+	}
+}
+
+// Section - The sketches laid out as a snapshot section: the bits of the relative accuracy and the station count, then
+// for every station sorted by name, its name, zero count, and the positive and negative runs of bins, each being the
+// offset, the bin count, and the count within each bin
+func (sketches Sketches) Section() resultsnapshot.Section {
+
+	stations := make([]string, 0, len(sketches.Stations))
+	for station := range sketches.Stations {
+		stations = append(stations, station)
+	}
+	slices.Sort(stations)
+
+	appendStore := func(data []byte, store store) []byte {
+		data = binary.AppendVarint(data, int64(store.offset))
+		data = binary.AppendUvarint(data, uint64(len(store.counts)))
+		for _, count := range store.counts {
+			data = binary.AppendUvarint(data, count)
+		}
+		return data
+	}
+
+	var data []byte
+	data = binary.AppendUvarint(data, math.Float64bits(sketches.Mapping.RelativeAccuracy))
+	data = binary.AppendUvarint(data, uint64(len(stations)))
+	for _, station := range stations {
+		sketch := sketches.Stations[station]
+
+		data = binary.AppendUvarint(data, uint64(len(station)))
+		data = append(data, station...)
+		data = binary.AppendUvarint(data, sketch.zero)
+		data = appendStore(data, sketch.positive)
+		data = appendStore(data, sketch.negative)
+	}
+
+	return resultsnapshot.Section{Tag: SnapshotSectionTag, Data: data}
+}
+
+// FromSection - Reads the sketches back out of a snapshot section
+func FromSection(data []byte) (Sketches, error) {
+
+	var position int
+	var failed bool
+	readUvarint := func() uint64 {
+		value, size := binary.Uvarint(data[position:])
+		if size <= 0 {
+			failed = true
+			return 0
+		}
+		position += size
+		return value
+	}
+	readStore := func() (store store, total uint64) {
+		offset, size := binary.Varint(data[position:])
+		if size <= 0 {
+			failed = true
+			return store, 0
+		}
+		position += size
+
+		binCount := readUvarint()
+		if failed || binCount > MaxBins || binCount > uint64(len(data)-position) {
+			failed = true
+			return store, 0
+		}
+		store.offset = int(offset)
+		store.counts = make([]uint64, binCount)
+		for index := range store.counts {
+			store.counts[index] = readUvarint()
+			total += store.counts[index]
+		}
+		return store, total
+	}
+
+	mapping, err := NewMapping(math.Float64frombits(readUvarint()))
+	if failed {
+		return Sketches{}, errSectionTruncated
+	}
+	if err != nil {
+		return Sketches{}, err
+	}
+	sketches := Sketches{Mapping: mapping, Stations: make(map[string]*Sketch)}
+
+	stationCount := readUvarint()
+	for range stationCount {
+		nameLength := readUvarint()
+		if failed || nameLength > uint64(len(data)-position) {
+			return Sketches{}, errSectionTruncated
+		}
+		station := string(data[position : position+int(nameLength)])
+		position += int(nameLength)
+
+		sketch := NewSketch(mapping)
+		sketch.zero = readUvarint()
+		positive, positiveTotal := readStore()
+		negative, negativeTotal := readStore()
+		if failed {
+			return Sketches{}, errSectionTruncated
+		}
+		sketch.positive, sketch.negative = positive, negative
+		sketch.count = sketch.zero + positiveTotal + negativeTotal
+
+		sketches.Stations[station] = sketch
+	}
+
+	if failed {
+		return Sketches{}, errSectionTruncated
+	}
+	return sketches, nil
+}
+
+// mergeSections - Combines the sketch sections of two snapshots being merged, which must share the same relative
+// accuracy
+func mergeSections(first []byte, second []byte) ([]byte, error) {
+
+	firstSketches, err := FromSection(first)
+	if err != nil {
+		return nil, err
+	}
+	secondSketches, err := FromSection(second)
+	if err != nil {
+		return nil, err
+	}
+	if firstSketches.Mapping.RelativeAccuracy != secondSketches.Mapping.RelativeAccuracy {
+		return nil, fmt.Errorf("%w: the sketches have relative accuracies of %v and %v", resultsnapshot.ErrIncompatible,
+			firstSketches.Mapping.RelativeAccuracy, secondSketches.Mapping.RelativeAccuracy)
+	}
+
+	firstSketches.Merge(secondSketches)
+	return firstSketches.Section().Data, nil
+}
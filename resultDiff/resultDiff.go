@@ -0,0 +1,241 @@
+package resultdiff
+
+import (
+	"billionRowChallenge/output"
+	resultsnapshot "billionRowChallenge/resultSnapshot"
+	"billionRowChallenge/utilities"
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// StationResult - A single station as either side of the diff holds it, in tenths of a degree. The canonical output
+// only carries min/mean/max, so the sum and count are only compared when both sides have them.
+type StationResult struct {
+	Min       int
+	Mean      int
+	Max       int
+	Sum       int
+	Count     int
+	HasTotals bool
+}
+
+// Results - Every station on one side of the diff
+type Results map[string]StationResult
+
+// FieldDifference - A field that differs by more than the tolerance
+type FieldDifference struct {
+	Station  string
+	Field    string
+	Expected int
+	Actual   int
+}
+
+// Report - Everything that differs between the two sides
+type Report struct {
+	Missing     []string // Stations expected but not found
+	Extra       []string // Stations found but not expected
+	Differences []FieldDifference
+}
+
+// FromOutputMap - Converts aggregated output values into results, keeping the exact totals
+func FromOutputMap(outputMap map[string]utilities.OutputValues) Results {
+
+	var results = make(Results, len(outputMap))
+	for station, outputValues := range outputMap {
+		results[station] = StationResult{
+			Min:       outputValues.Min,
+			Mean:      output.RoundedMean(outputValues.Total, outputValues.Count),
+			Max:       outputValues.Max,
+			Sum:       outputValues.Total,
+			Count:     outputValues.Count,
+			HasTotals: true,
+		}
+	}
+
+	return results
+}
+
+// ParseFormatted - Reads the canonical challenge output, `{Abha=-23.0/18.0/59.2, Abidjan=...}`. Station names may
+// themselves hold `, `, so a piece that does not end in three temperatures is joined onto the next one.
+func ParseFormatted(text string) (Results, error) {
+
+	text = strings.TrimSpace(text)
+	if !strings.HasPrefix(text, "{") || !strings.HasSuffix(text, "}") {
+		return nil, errors.New("formatted results must be wrapped in braces")
+	}
+	text = text[1 : len(text)-1]
+
+	var results = make(Results)
+	if text == "" {
+		return results, nil
+	}
+
+	var pending string
+	for _, piece := range strings.Split(text, ", ") {
+		if pending != "" {
+			piece = pending + ", " + piece
+		}
+
+		separator := strings.LastIndexByte(piece, '=')
+		if separator < 0 {
+			pending = piece
+			continue
+		}
+		temperatures := strings.Split(piece[separator+1:], "/")
+		if len(temperatures) != 3 {
+			pending = piece
+			continue
+		}
+
+		var values [3]int
+		var err error
+		for index, temperature := range temperatures {
+			if values[index], err = ParseTenths(temperature); err != nil {
+				break
+			}
+		}
+		if err != nil {
+			pending = piece
+			continue
+		}
+
+		results[piece[:separator]] = StationResult{Min: values[0], Mean: values[1], Max: values[2]}
+		pending = ""
+	}
+
+	if pending != "" {
+		return nil, fmt.Errorf("could not read the entry %q", pending)
+	}
+
+	return results, nil
+}
+
+// ParseTenths - Reads a temperature with a single decimal place, such as `-12.3`, into tenths
+func ParseTenths(temperature string) (int, error) {
+
+	whole, decimal, found := strings.Cut(temperature, ".")
+	if !found || len(decimal) != 1 || decimal[0] < '0' || decimal[0] > '9' {
+		return 0, fmt.Errorf("temperature %q does not have a single decimal place", temperature)
+	}
+
+	value, err := strconv.Atoi(whole)
+	if err != nil {
+		return 0, fmt.Errorf("temperature %q is not a number", temperature)
+	}
+
+	tenths := value*10 + int(decimal[0]-'0')
+	if strings.HasPrefix(whole, "-") {
+		tenths = value*10 - int(decimal[0]-'0')
+	}
+
+	return tenths, nil
+}
+
+// Load - Reads either a result snapshot or a file holding the canonical output, going by the snapshot magic
+func Load(path string) (Results, error) {
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if bytes.HasPrefix(data, []byte(resultsnapshot.Magic)) {
+		snapshot, err := resultsnapshot.Decode(data)
+		if err != nil {
+			return nil, fmt.Errorf("%v: %w", path, err)
+		}
+		return FromOutputMap(snapshot.OutputMap), nil
+	}
+
+	results, err := ParseFormatted(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("%v: %w", path, err)
+	}
+
+	return results, nil
+}
+
+// Compare - Lists the stations missing from either side and every field differing by more than the tolerance, which
+// is in tenths for the temperatures. Counts are always compared exactly.
+func Compare(expected Results, actual Results, tolerance int) Report {
+
+	var report Report
+
+	for station := range actual {
+		if _, ok := expected[station]; !ok {
+			report.Extra = append(report.Extra, station)
+		}
+	}
+	slices.Sort(report.Extra)
+
+	var stations = make([]string, 0, len(expected))
+	for station := range expected {
+		stations = append(stations, station)
+	}
+	slices.Sort(stations)
+
+	for _, station := range stations {
+		expectedResult := expected[station]
+		actualResult, ok := actual[station]
+		if !ok {
+			report.Missing = append(report.Missing, station)
+			continue
+		}
+
+		compare := func(field string, expectedValue int, actualValue int, fieldTolerance int) {
+			if delta := actualValue - expectedValue; delta > fieldTolerance || -delta > fieldTolerance {
+				report.Differences = append(report.Differences, FieldDifference{station, field, expectedValue, actualValue})
+			}
+		}
+
+		compare("min", expectedResult.Min, actualResult.Min, tolerance)
+		compare("mean", expectedResult.Mean, actualResult.Mean, tolerance)
+		compare("max", expectedResult.Max, actualResult.Max, tolerance)
+		if expectedResult.HasTotals && actualResult.HasTotals {
+			compare("count", expectedResult.Count, actualResult.Count, 0)
+			compare("sum", expectedResult.Sum, actualResult.Sum, tolerance)
+		}
+	}
+
+	return report
+}
+
+// Matches - Whether nothing at all differs
+func (report Report) Matches() bool {
+	return len(report.Missing) == 0 && len(report.Extra) == 0 && len(report.Differences) == 0
+}
+
+// String - One line for every missing or extra station and every differing field
+// e.g. `- Abha`, `+ Zagreb`, `~ Accra mean: expected 26.4, got 26.5 (+0.1)`
+func (report Report) String() string {
+
+	var builder strings.Builder
+
+	for _, station := range report.Missing {
+		builder.WriteString("- " + station + "\n")
+	}
+	for _, station := range report.Extra {
+		builder.WriteString("+ " + station + "\n")
+	}
+	for _, difference := range report.Differences {
+		format := output.FormatTemperature
+		if difference.Field == "count" {
+			format = strconv.Itoa
+		}
+
+		delta := format(difference.Actual - difference.Expected)
+		if difference.Actual > difference.Expected {
+			delta = "+" + delta
+		}
+
+		fmt.Fprintf(&builder, "~ %v %v: expected %v, got %v (%v)\n", difference.Station, difference.Field,
+			format(difference.Expected), format(difference.Actual), delta)
+	}
+
+	return builder.String()
+}
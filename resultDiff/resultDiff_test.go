@@ -0,0 +1,147 @@
+package resultdiff
+
+import (
+	"billionRowChallenge/output"
+	resultsnapshot "billionRowChallenge/resultSnapshot"
+	"billionRowChallenge/utilities"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func testOutputMap() map[string]utilities.OutputValues {
+	return map[string]utilities.OutputValues{
+		"Abha":                {Min: -45, Max: 123, Total: 78, Count: 2},
+		"Washington, D.C.":    {Min: -5, Max: 5, Total: 0, Count: 2},
+		"Odd=Name, 1.0/2.0/3": {Min: 1, Max: 1, Total: 1, Count: 1},
+		"Zürich":              {Min: -999, Max: 999, Total: -1, Count: 3},
+	}
+}
+
+// withoutTotals - The results as the canonical output holds them
+func withoutTotals(results Results) Results {
+
+	var stripped = make(Results, len(results))
+	for station, result := range results {
+		stripped[station] = StationResult{Min: result.Min, Mean: result.Mean, Max: result.Max}
+	}
+	return stripped
+}
+
+func TestParseFormattedRoundTrip(t *testing.T) {
+
+	outputMap := testOutputMap()
+
+	results, err := ParseFormatted(output.FormatResults(outputMap) + "\n")
+	if err != nil {
+		t.Fatalf("ParseFormatted: %v", err)
+	}
+	if expected := withoutTotals(FromOutputMap(outputMap)); !maps.Equal(results, expected) {
+		t.Errorf("parsed %v, expected %v", results, expected)
+	}
+
+	if results, err = ParseFormatted("{}"); err != nil || len(results) != 0 {
+		t.Errorf("ParseFormatted({}) = %v, %v", results, err)
+	}
+}
+
+func TestParseFormattedErrors(t *testing.T) {
+
+	for _, text := range []string{"", "Abha=1.0/2.0/3.0", "{Abha=1.0/2.0}", "{Abha=1/2/3}", "{Abha=1.0/2.0/x.0}", "{Abha}"} {
+		if results, err := ParseFormatted(text); err == nil {
+			t.Errorf("ParseFormatted(%q) = %v, expected an error", text, results)
+		}
+	}
+}
+
+func TestParseTenths(t *testing.T) {
+
+	tests := map[string]int{"0.0": 0, "-0.5": -5, "0.5": 5, "12.3": 123, "-12.3": -123, "-99.9": -999, "1234.5": 12345}
+	for temperature, expected := range tests {
+		if tenths, err := ParseTenths(temperature); err != nil || tenths != expected {
+			t.Errorf("ParseTenths(%q) = %v, %v, expected %v", temperature, tenths, err, expected)
+		}
+	}
+
+	for _, temperature := range []string{"1", "1.", "1.23", "a.1", "1.a", ""} {
+		if _, err := ParseTenths(temperature); err == nil {
+			t.Errorf("ParseTenths(%q) succeeded", temperature)
+		}
+	}
+}
+
+func TestCompare(t *testing.T) {
+
+	expected := FromOutputMap(testOutputMap())
+	actual := FromOutputMap(testOutputMap())
+
+	if report := Compare(expected, actual, 0); !report.Matches() || report.String() != "" {
+		t.Errorf("identical results reported %q", report.String())
+	}
+
+	abha := actual["Abha"]
+	abha.Mean++
+	abha.Count++
+	actual["Abha"] = abha
+	delete(actual, "Zürich")
+	actual["Zagreb"] = StationResult{}
+
+	report := Compare(expected, actual, 0)
+	if !slices.Equal(report.Missing, []string{"Zürich"}) || !slices.Equal(report.Extra, []string{"Zagreb"}) {
+		t.Errorf("missing %v, extra %v", report.Missing, report.Extra)
+	}
+	expectedDifferences := []FieldDifference{{"Abha", "mean", 39, 40}, {"Abha", "count", 2, 3}}
+	if !slices.Equal(report.Differences, expectedDifferences) {
+		t.Errorf("differences = %v, expected %v", report.Differences, expectedDifferences)
+	}
+	if text := "- Zürich\n+ Zagreb\n~ Abha mean: expected 3.9, got 4.0 (+0.1)\n~ Abha count: expected 2, got 3 (+1)\n"; report.String() != text {
+		t.Errorf("report = %q, expected %q", report.String(), text)
+	}
+
+	// The tolerance covers the temperatures, never the count
+	report = Compare(expected, actual, 1)
+	if !slices.Equal(report.Differences, expectedDifferences[1:]) {
+		t.Errorf("differences within a tolerance of 0.1 = %v", report.Differences)
+	}
+
+	// The canonical output has no totals, so only min/mean/max are compared against it
+	if report = Compare(withoutTotals(expected), FromOutputMap(testOutputMap()), 0); !report.Matches() {
+		t.Errorf("canonical output against a snapshot reported %q", report.String())
+	}
+}
+
+func TestLoad(t *testing.T) {
+
+	directory := t.TempDir()
+	outputMap := testOutputMap()
+
+	snapshotPath := filepath.Join(directory, "results.snap")
+	if err := resultsnapshot.Save(snapshotPath, resultsnapshot.New(outputMap)); err != nil {
+		t.Fatal(err)
+	}
+	formattedPath := filepath.Join(directory, "results.txt")
+	if err := os.WriteFile(formattedPath, []byte(output.FormatResults(outputMap)), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	fromSnapshot, err := Load(snapshotPath)
+	if err != nil || !maps.Equal(fromSnapshot, FromOutputMap(outputMap)) {
+		t.Errorf("Load(snapshot) = %v, %v", fromSnapshot, err)
+	}
+	fromText, err := Load(formattedPath)
+	if err != nil || !maps.Equal(fromText, withoutTotals(FromOutputMap(outputMap))) {
+		t.Errorf("Load(formatted) = %v, %v", fromText, err)
+	}
+
+	// A corrupt snapshot is an error rather than being read as text
+	data, _ := os.ReadFile(snapshotPath)
+	data[len(data)-1] ^= 0xff
+	if err = os.WriteFile(snapshotPath, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = Load(snapshotPath); err == nil {
+		t.Error("Load accepted a corrupt snapshot")
+	}
+}
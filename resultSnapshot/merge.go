@@ -0,0 +1,92 @@
+package resultsnapshot
+
+import (
+	"billionRowChallenge/utilities"
+	"errors"
+	"fmt"
+	"slices"
+)
+
+// ErrIncompatible - Returned when snapshots were read with different dialects, so their integers do not mean the same,
+// or carry statistics that cannot be combined
+var ErrIncompatible = errors.New("snapshots are not compatible")
+
+// SectionMerger - Combines the data of two sections sharing a tag
+type SectionMerger func(first []byte, second []byte) ([]byte, error)
+
+// SectionMergers - How each kind of section is combined when merging. A section without a merger cannot be merged, as
+// there is no way of knowing how to combine it.
+var SectionMergers = map[uint64]SectionMerger{}
+
+// Merge - Combines the snapshots into one: the min of the mins, the max of the maxes, and the totals and counts summed.
+// Refuses snapshots whose dialects differ from the first, sections that have no registered merger, and snapshots that do
+// not all carry the same sections, as statistics covering only some of the readings would be wrong.
+func Merge(snapshots ...Snapshot) (Snapshot, error) {
+
+	if len(snapshots) == 0 {
+		return New(map[string]utilities.OutputValues{}), nil
+	}
+
+	merged := Snapshot{
+		Version:   SnapshotVersion,
+		Dialect:   snapshots[0].Dialect,
+		OutputMap: make(map[string]utilities.OutputValues, len(snapshots[0].OutputMap)),
+	}
+
+	for index, snapshot := range snapshots {
+		if snapshot.Dialect != merged.Dialect {
+			return merged, fmt.Errorf("%w: snapshot %v has %v, the first has %v", ErrIncompatible, index+1, snapshot.Dialect, merged.Dialect)
+		}
+		if !slices.Equal(sectionTags(snapshot), sectionTags(snapshots[0])) {
+			return merged, fmt.Errorf("%w: snapshot %v has sections %v, the first has %v", ErrIncompatible, index+1,
+				sectionTags(snapshot), sectionTags(snapshots[0]))
+		}
+
+		utilities.MergeOutputMaps(merged.OutputMap, snapshot.OutputMap)
+
+		for _, section := range snapshot.Sections {
+			if err := merged.mergeSection(section); err != nil {
+				return merged, fmt.Errorf("snapshot %v: %w", index+1, err)
+			}
+		}
+	}
+
+	return merged, nil
+}
+
+// sectionTags - The tags of every section the snapshot carries, sorted
+func sectionTags(snapshot Snapshot) []uint64 {
+
+	var tags = make([]uint64, 0, len(snapshot.Sections))
+	for _, section := range snapshot.Sections {
+		tags = append(tags, section.Tag)
+	}
+	slices.Sort(tags)
+
+	return tags
+}
+
+// mergeSection - Adds the section, or combines it with the section already holding the same tag
+func (snapshot *Snapshot) mergeSection(section Section) error {
+
+	merger, ok := SectionMergers[section.Tag]
+	if !ok {
+		return fmt.Errorf("%w: section %v has no way of being merged", ErrIncompatible, section.Tag)
+	}
+
+	for index, existing := range snapshot.Sections {
+		if existing.Tag != section.Tag {
+			continue
+		}
+
+		data, err := merger(existing.Data, section.Data)
+		if err != nil {
+			return err
+		}
+		snapshot.Sections[index].Data = data
+		return nil
+	}
+
+	snapshot.Sections = append(snapshot.Sections, section)
+	return nil
+}
@@ -0,0 +1,120 @@
+package resultsnapshot
+
+import (
+	"billionRowChallenge/utilities"
+	"bytes"
+	"errors"
+	"maps"
+	"testing"
+)
+
+// testSectionTag - A tag no real statistic uses, given a merger that concatenates for the length of a test
+const testSectionTag = 1_000_001
+
+func registerTestMerger(t *testing.T) {
+
+	SectionMergers[testSectionTag] = func(first []byte, second []byte) ([]byte, error) {
+		return append(append([]byte{}, first...), second...), nil
+	}
+	t.Cleanup(func() { delete(SectionMergers, testSectionTag) })
+}
+
+func TestMergeEqualsSinglePass(t *testing.T) {
+
+	readings := []struct {
+		station     string
+		temperature int
+	}{
+		{"Abha", 123}, {"Accra", -45}, {"Abha", -999}, {"Zürich", 0}, {"Accra", 999},
+		{"Abha", 17}, {"Zürich", -3}, {"Accra", -45}, {"Ouagadoug", 301}, {"Abha", 123},
+	}
+
+	// Every reading in one map, against the readings dealt out into three partial maps
+	whole := make(map[string]utilities.OutputValues)
+	var partials = []map[string]utilities.OutputValues{{}, {}, {}}
+	for index, reading := range readings {
+		utilities.AddTemperature(whole, reading.station, reading.temperature)
+		utilities.AddTemperature(partials[index%len(partials)], reading.station, reading.temperature)
+	}
+
+	var snapshots []Snapshot
+	for _, partial := range partials {
+		snapshots = append(snapshots, New(partial))
+	}
+
+	merged, err := Merge(snapshots...)
+	if err != nil {
+		t.Fatalf("Merge: %v", err)
+	}
+	if !maps.Equal(merged.OutputMap, whole) {
+		t.Errorf("merged = %v, expected %v", merged.OutputMap, whole)
+	}
+
+	// Merging must leave the partial snapshots as they were
+	if again, err := Merge(snapshots...); err != nil || !maps.Equal(again.OutputMap, whole) {
+		t.Errorf("merging a second time gave %v, %v", again.OutputMap, err)
+	}
+}
+
+func TestMergeSections(t *testing.T) {
+
+	registerTestMerger(t)
+
+	first := New(map[string]utilities.OutputValues{"Abha": {Min: 1, Max: 1, Total: 1, Count: 1}})
+	first.Sections = []Section{{Tag: testSectionTag, Data: []byte{1, 2}}}
+	second := New(map[string]utilities.OutputValues{"Abha": {Min: 2, Max: 2, Total: 2, Count: 1}})
+	second.Sections = []Section{{Tag: testSectionTag, Data: []byte{3}}}
+
+	merged, err := Merge(first, second)
+	if err != nil {
+		t.Fatalf("Merge: %v", err)
+	}
+	if data, _ := merged.FindSection(testSectionTag); !bytes.Equal(data, []byte{1, 2, 3}) {
+		t.Errorf("merged section = %v, expected [1 2 3]", data)
+	}
+	if !bytes.Equal(first.Sections[0].Data, []byte{1, 2}) {
+		t.Errorf("merging changed the first snapshot's section to %v", first.Sections[0].Data)
+	}
+}
+
+func TestMergeRejectsIncompatible(t *testing.T) {
+
+	registerTestMerger(t)
+
+	plain := New(map[string]utilities.OutputValues{"Abha": {Min: 1, Max: 1, Total: 1, Count: 1}})
+
+	hundredths := New(map[string]utilities.OutputValues{"Abha": {Min: 10, Max: 10, Total: 10, Count: 1}})
+	hundredths.Dialect.Scale = 2
+
+	withSection := New(map[string]utilities.OutputValues{"Abha": {Min: 1, Max: 1, Total: 1, Count: 1}})
+	withSection.Sections = []Section{{Tag: testSectionTag, Data: []byte{1}}}
+
+	unknownSection := New(map[string]utilities.OutputValues{"Abha": {Min: 1, Max: 1, Total: 1, Count: 1}})
+	unknownSection.Sections = []Section{{Tag: testSectionTag + 1, Data: []byte{1}}}
+
+	tests := []struct {
+		name      string
+		snapshots []Snapshot
+	}{
+		{"dialect", []Snapshot{plain, hundredths}},
+		{"section missing from the second", []Snapshot{withSection, plain}},
+		{"section missing from the first", []Snapshot{plain, withSection}},
+		{"section with no merger", []Snapshot{unknownSection, unknownSection}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := Merge(test.snapshots...); !errors.Is(err, ErrIncompatible) {
+				t.Errorf("Merge = %v, expected %v", err, ErrIncompatible)
+			}
+		})
+	}
+}
+
+func TestMergeNothing(t *testing.T) {
+
+	merged, err := Merge()
+	if err != nil || len(merged.OutputMap) != 0 || merged.Dialect != DefaultDialect() {
+		t.Errorf("Merge() = %v, %v, expected an empty snapshot", merged, err)
+	}
+}
@@ -0,0 +1,274 @@
+package resultsnapshot
+
+import (
+	"billionRowChallenge/utilities"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"slices"
+)
+
+// Layout of a snapshot file, every integer little endian or varint encoded:
+//
+//	magic          "BRCS"
+//	version        uint16
+//	dialect        separator byte, decimal point byte, scale byte
+//	station count  uvarint
+//	stations       name length uvarint, name, min varint, max varint, total varint, count uvarint
+//	section count  uvarint
+//	sections       tag uvarint, length uvarint, data
+//	checksum       uint32 CRC-32C of everything before it
+//
+// Sections carry any extra per-run statistics. Readers keep sections they do not understand untouched, so adding a new
+// kind of section does not bump the version.
+
+const Magic = "BRCS"
+
+const SnapshotVersion = 1 // Bumped whenever the layout above changes, newer snapshots are refused
+
+var castagnoliTable = crc32.MakeTable(crc32.Castagnoli)
+
+// Errors returned when a snapshot cannot be read
+var (
+	ErrNotSnapshot        = errors.New("not a result snapshot")
+	ErrUnsupportedVersion = errors.New("unsupported snapshot version")
+	ErrChecksumMismatch   = errors.New("snapshot checksum does not match, the file is corrupt")
+	ErrTruncated          = errors.New("snapshot is truncated")
+)
+
+// Dialect - How the measurements were read. Temperatures are held as integers in units of 10^-Scale degrees, so two
+// snapshots can only be combined when their dialects match.
+type Dialect struct {
+	Separator    byte
+	DecimalPoint byte
+	Scale        uint8
+}
+
+// DefaultDialect - `station;12.3`, held in tenths of a degree
+func DefaultDialect() Dialect {
+	return Dialect{Separator: utilities.SemicolonHex, DecimalPoint: utilities.DecimalHex, Scale: 1}
+}
+
+// String - e.g. `separator ';', decimal point '.', scale 1`
+func (dialect Dialect) String() string {
+	return fmt.Sprintf("separator %q, decimal point %q, scale %v", dialect.Separator, dialect.DecimalPoint, dialect.Scale)
+}
+
+// Section - A block of extra statistics, identified by its tag
+type Section struct {
+	Tag  uint64
+	Data []byte
+}
+
+// Snapshot - The aggregate state of a run, with the exact integer totals rather than rounded means
+type Snapshot struct {
+	Version   uint16
+	Dialect   Dialect
+	OutputMap map[string]utilities.OutputValues
+	Sections  []Section
+}
+
+// New - Snapshot of the output map in the default dialect
+func New(outputMap map[string]utilities.OutputValues) Snapshot {
+	return Snapshot{Version: SnapshotVersion, Dialect: DefaultDialect(), OutputMap: outputMap}
+}
+
+// Encode - Lays the snapshot out in its binary form, with the stations sorted by name so the same results always give
+// the same bytes.
+func Encode(snapshot Snapshot) []byte {
+
+	stations := make([]string, 0, len(snapshot.OutputMap))
+	for station := range snapshot.OutputMap {
+		stations = append(stations, station)
+	}
+	slices.Sort(stations)
+
+	var data = make([]byte, 0, 16+len(stations)*32)
+	data = append(data, Magic...)
+	data = binary.LittleEndian.AppendUint16(data, SnapshotVersion)
+	data = append(data, snapshot.Dialect.Separator, snapshot.Dialect.DecimalPoint, snapshot.Dialect.Scale)
+
+	data = binary.AppendUvarint(data, uint64(len(stations)))
+	for _, station := range stations {
+		outputValues := snapshot.OutputMap[station]
+
+		data = binary.AppendUvarint(data, uint64(len(station)))
+		data = append(data, station...)
+		data = binary.AppendVarint(data, int64(outputValues.Min))
+		data = binary.AppendVarint(data, int64(outputValues.Max))
+		data = binary.AppendVarint(data, int64(outputValues.Total))
+		data = binary.AppendUvarint(data, uint64(outputValues.Count))
+	}
+
+	data = binary.AppendUvarint(data, uint64(len(snapshot.Sections)))
+	for _, section := range snapshot.Sections {
+		data = binary.AppendUvarint(data, section.Tag)
+		data = binary.AppendUvarint(data, uint64(len(section.Data)))
+		data = append(data, section.Data...)
+	}
+
+	return binary.LittleEndian.AppendUint32(data, crc32.Checksum(data, castagnoliTable))
+}
+
+// Decode - Reads a snapshot back out of its binary form. The checksum is verified before anything else is read.
+func Decode(data []byte) (Snapshot, error) {
+
+	var snapshot Snapshot
+
+	if len(data) < len(Magic)+2 || !bytes.Equal(data[:len(Magic)], []byte(Magic)) {
+		return snapshot, ErrNotSnapshot
+	}
+	snapshot.Version = binary.LittleEndian.Uint16(data[len(Magic):])
+	if snapshot.Version > SnapshotVersion {
+		return snapshot, fmt.Errorf("%w %v, expected %v or older", ErrUnsupportedVersion, snapshot.Version, SnapshotVersion)
+	}
+
+	if len(data) < len(Magic)+2+3+4 {
+		return snapshot, ErrTruncated
+	}
+	body, checksum := data[:len(data)-4], binary.LittleEndian.Uint32(data[len(data)-4:])
+	if crc32.Checksum(body, castagnoliTable) != checksum {
+		return snapshot, ErrChecksumMismatch
+	}
+
+	reader := snapshotReader{data: body, position: len(Magic) + 2}
+	snapshot.Dialect = Dialect{Separator: reader.readByte(), DecimalPoint: reader.readByte(), Scale: reader.readByte()}
+
+	stationCount := reader.uvarint()
+	snapshot.OutputMap = make(map[string]utilities.OutputValues, min(stationCount, uint64(len(body))))
+	for range stationCount {
+		station := string(reader.readBytes(reader.uvarint()))
+		snapshot.OutputMap[station] = utilities.OutputValues{
+			Min:   int(reader.varint()),
+			Max:   int(reader.varint()),
+			Total: int(reader.varint()),
+			Count: int(reader.uvarint()),
+		}
+		if reader.err != nil {
+			return snapshot, reader.err
+		}
+	}
+
+	sectionCount := reader.uvarint()
+	for range sectionCount {
+		tag := reader.uvarint()
+		sectionData := reader.readBytes(reader.uvarint())
+		if reader.err != nil {
+			return snapshot, reader.err
+		}
+		snapshot.Sections = append(snapshot.Sections, Section{Tag: tag, Data: slices.Clone(sectionData)})
+	}
+
+	if reader.err == nil && reader.position != len(body) {
+		return snapshot, fmt.Errorf("snapshot has %v unexpected bytes after the last section", len(body)-reader.position)
+	}
+
+	return snapshot, reader.err
+}
+
+// IsSnapshot - Whether the file begins with the snapshot header. Only the magic and version are read, so a large
+// measurements file can be told apart without loading it.
+func IsSnapshot(path string) (bool, error) {
+
+	file, err := os.Open(path)
+	if err != nil {
+		return false, err
+	}
+	defer file.Close()
+
+	var header = make([]byte, len(Magic)+2)
+	if _, err = io.ReadFull(file, header); errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+
+	return bytes.Equal(header[:len(Magic)], []byte(Magic)), nil
+}
+
+// Load - Reads and decodes the snapshot file
+func Load(path string) (Snapshot, error) {
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Snapshot{}, err
+	}
+
+	snapshot, err := Decode(data)
+	if err != nil {
+		return snapshot, fmt.Errorf("%v: %w", path, err)
+	}
+
+	return snapshot, nil
+}
+
+// Save - Writes the snapshot over any previous file
+func Save(path string, snapshot Snapshot) error {
+	return utilities.WriteFileAtomic(path, Encode(snapshot))
+}
+
+// FindSection - The data of the first section with the tag, or false when the snapshot has none
+func (snapshot Snapshot) FindSection(tag uint64) ([]byte, bool) {
+	for _, section := range snapshot.Sections {
+		if section.Tag == tag {
+			return section.Data, true
+		}
+	}
+	return nil, false
+}
+
+// snapshotReader - Walks through the snapshot body. The first read past the end sets `err`, and every read after that
+// returns zero values, so the caller only has to check once per record.
+type snapshotReader struct {
+	data     []byte
+	position int
+	err      error
+}
+
+func (reader *snapshotReader) readByte() byte {
+	if reader.err != nil || reader.position >= len(reader.data) {
+		reader.err = ErrTruncated
+		return 0
+	}
+	reader.position++
+	return reader.data[reader.position-1]
+}
+
+func (reader *snapshotReader) readBytes(length uint64) []byte {
+	if reader.err != nil || length > uint64(len(reader.data)-reader.position) {
+		reader.err = ErrTruncated
+		return nil
+	}
+	reader.position += int(length)
+	return reader.data[reader.position-int(length) : reader.position]
+}
+
+func (reader *snapshotReader) uvarint() uint64 {
+	if reader.err != nil {
+		return 0
+	}
+	value, size := binary.Uvarint(reader.data[reader.position:])
+	if size <= 0 {
+		reader.err = ErrTruncated
+		return 0
+	}
+	reader.position += size
+	return value
+}
+
+func (reader *snapshotReader) varint() int64 {
+	if reader.err != nil {
+		return 0
+	}
+	value, size := binary.Varint(reader.data[reader.position:])
+	if size <= 0 {
+		reader.err = ErrTruncated
+		return 0
+	}
+	reader.position += size
+	return value
+}
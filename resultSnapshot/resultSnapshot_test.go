@@ -0,0 +1,175 @@
+package resultsnapshot
+
+import (
+	"billionRowChallenge/utilities"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+// testSnapshot - A snapshot holding negative values, a multi-byte station name, and two sections
+func testSnapshot() Snapshot {
+
+	snapshot := New(map[string]utilities.OutputValues{
+		"Abha":      {Min: -999, Max: 999, Total: 1234, Count: 7},
+		"Zürich":    {Min: -12, Max: -3, Total: -45, Count: 6},
+		"Ouagadoug": {Min: 301, Max: 301, Total: 301, Count: 1},
+	})
+	snapshot.Sections = []Section{
+		{Tag: 7, Data: []byte{1, 2, 3}},
+		{Tag: 9, Data: []byte{}},
+	}
+
+	return snapshot
+}
+
+func TestEncodeDecodeRoundTrip(t *testing.T) {
+
+	snapshot := testSnapshot()
+
+	decoded, err := Decode(Encode(snapshot))
+	if err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+
+	if decoded.Version != SnapshotVersion || decoded.Dialect != DefaultDialect() {
+		t.Errorf("header = version %v, %v, expected version %v, %v", decoded.Version, decoded.Dialect, SnapshotVersion, DefaultDialect())
+	}
+	if !maps.Equal(decoded.OutputMap, snapshot.OutputMap) {
+		t.Errorf("stations = %v, expected %v", decoded.OutputMap, snapshot.OutputMap)
+	}
+	if !slices.EqualFunc(decoded.Sections, snapshot.Sections, func(first Section, second Section) bool {
+		return first.Tag == second.Tag && bytes.Equal(first.Data, second.Data)
+	}) {
+		t.Errorf("sections = %v, expected %v", decoded.Sections, snapshot.Sections)
+	}
+}
+
+func TestEncodeIsDeterministic(t *testing.T) {
+
+	// Maps iterate in a random order, so encode a few times over
+	first := Encode(testSnapshot())
+	for range 10 {
+		if !bytes.Equal(Encode(testSnapshot()), first) {
+			t.Fatal("encoding the same snapshot gave different bytes")
+		}
+	}
+}
+
+func TestDecodeRejectsCorruption(t *testing.T) {
+
+	data := Encode(testSnapshot())
+
+	// The checksum covers the header too, so a flipped bit anywhere is caught
+	for position := range data {
+		corrupt := slices.Clone(data)
+		corrupt[position] ^= 0x10
+
+		if _, err := Decode(corrupt); err == nil {
+			t.Errorf("flipping a bit of byte %v was not noticed", position)
+		}
+	}
+}
+
+func TestDecodeRejectsTruncation(t *testing.T) {
+
+	data := Encode(testSnapshot())
+
+	for length := range len(data) {
+		if _, err := Decode(data[:length]); err == nil {
+			t.Errorf("snapshot cut to %v of %v bytes was accepted", length, len(data))
+		}
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+
+	newerVersion := Encode(testSnapshot())
+	binary.LittleEndian.PutUint16(newerVersion[len(Magic):], SnapshotVersion+1)
+
+	flippedChecksum := Encode(testSnapshot())
+	flippedChecksum[len(flippedChecksum)-1] ^= 0xff
+
+	tests := []struct {
+		name     string
+		data     []byte
+		expected error
+	}{
+		{"empty", nil, ErrNotSnapshot},
+		{"measurements", []byte("Abha;12.3\nAccra;-4.5\n"), ErrNotSnapshot},
+		{"magic only", []byte(Magic), ErrNotSnapshot},
+		{"header only", Encode(testSnapshot())[:len(Magic)+2], ErrTruncated},
+		{"newer version", newerVersion, ErrUnsupportedVersion},
+		{"checksum", flippedChecksum, ErrChecksumMismatch},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := Decode(test.data); !errors.Is(err, test.expected) {
+				t.Errorf("Decode = %v, expected %v", err, test.expected)
+			}
+		})
+	}
+}
+
+func TestSaveLoad(t *testing.T) {
+
+	path := filepath.Join(t.TempDir(), "results.snap")
+	snapshot := testSnapshot()
+
+	if err := Save(path, snapshot); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	loaded, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if !maps.Equal(loaded.OutputMap, snapshot.OutputMap) {
+		t.Errorf("stations = %v, expected %v", loaded.OutputMap, snapshot.OutputMap)
+	}
+
+	if data, ok := loaded.FindSection(7); !ok || !bytes.Equal(data, []byte{1, 2, 3}) {
+		t.Errorf("FindSection(7) = %v, %v, expected [1 2 3], true", data, ok)
+	}
+	if _, ok := loaded.FindSection(8); ok {
+		t.Error("FindSection(8) found a section that was never saved")
+	}
+}
+
+func TestIsSnapshot(t *testing.T) {
+
+	directory := t.TempDir()
+
+	files := []struct {
+		name     string
+		data     []byte
+		expected bool
+	}{
+		{"results.snap", Encode(testSnapshot()), true},
+		{"measurements.csv", []byte("Abha;12.3\nAccra;-4.5\n"), false},
+		{"short.csv", []byte("A;1"), false},
+		{"empty.csv", nil, false},
+	}
+
+	for _, file := range files {
+		path := filepath.Join(directory, file.name)
+		if err := os.WriteFile(path, file.data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+
+		isSnapshot, err := IsSnapshot(path)
+		if err != nil || isSnapshot != file.expected {
+			t.Errorf("IsSnapshot(%v) = %v, %v, expected %v", file.name, isSnapshot, err, file.expected)
+		}
+	}
+
+	if _, err := IsSnapshot(filepath.Join(directory, "missing")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("IsSnapshot of a missing file = %v, expected it not to exist", err)
+	}
+}
@@ -0,0 +1,280 @@
+package rowindex
+
+import (
+	"billionRowChallenge/utilities"
+	"bytes"
+	"cmp"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+)
+
+const IndexVersion = 2 // Bumped whenever the layout of the index file changes
+
+const DefaultBlockSize int64 = 64 * 1024 * 1024 // Default amount of bytes between each recorded split
+
+// Block - A run of complete rows within the file. The block begins at `Offset`, directly after a newline, and runs up to
+// the offset of the next block (or `IndexedEnd` for the final block).
+type Block struct {
+	Offset   int64 `json:"offset"`
+	FirstRow int64 `json:"firstRow"` // Rows within every earlier block, so a row can be found with a binary search
+	RowCount int64 `json:"rowCount"`
+}
+
+// RowIndex - Sidecar index of newline aligned split offsets within a measurements file. Lets later runs plan their
+// sections, or jump to a specific row, without scanning the file for row boundaries.
+type RowIndex struct {
+	Version     int                       `json:"version"`
+	BlockSize   int64                     `json:"blockSize"`
+	Fingerprint utilities.FileFingerprint `json:"fingerprint"`
+	IndexedEnd  int64                     `json:"indexedEnd"`
+	Blocks      []Block                   `json:"blocks"`
+}
+
+// IndexPath - Default location of the index, sitting right beside the measurements file
+func IndexPath(filename string) string {
+	return filename + ".idx"
+}
+
+// Build - Scans the whole file and records a split after the first newline once each block holds at least
+// `blockSize` bytes, along with the number of rows within every block.
+func Build(file *os.File, blockSize int64) (RowIndex, error) {
+	return Update(file, RowIndex{Version: IndexVersion, BlockSize: blockSize})
+}
+
+// Update - Brings an existing index up to date with bytes appended to the file. Only the final block, which may
+// not have reached the block size yet, and everything after it are scanned again.
+func Update(file *os.File, index RowIndex) (RowIndex, error) {
+
+	fileInfo, err := file.Stat()
+	if err != nil {
+		return index, err
+	}
+
+	// Drop the final block and restart the scan from its offset
+	var position int64
+	var firstRow int64
+	if len(index.Blocks) > 0 {
+		position = index.Blocks[len(index.Blocks)-1].Offset
+		firstRow = index.Blocks[len(index.Blocks)-1].FirstRow
+		index.Blocks = index.Blocks[:len(index.Blocks)-1]
+	}
+
+	var readBuffer = make([]byte, utilities.SectionBufferSize)
+	var currentBlock = Block{Offset: position, FirstRow: firstRow}
+	var indexedEnd = position
+
+	reader := io.NewSectionReader(file, position, fileInfo.Size()-position)
+
+	for {
+		n, err := reader.Read(readBuffer)
+		remainingBytes := readBuffer[:n]
+
+		for len(remainingBytes) > 0 {
+
+			// Once the block is large enough, close it off at the very next newline
+			if position >= currentBlock.Offset+index.BlockSize-1 {
+				newLineIndex := bytes.IndexByte(remainingBytes, utilities.NewLineHex)
+				if newLineIndex < 0 {
+					position += int64(len(remainingBytes))
+					break
+				}
+
+				position += int64(newLineIndex) + 1
+				remainingBytes = remainingBytes[newLineIndex+1:]
+
+				currentBlock.RowCount++
+				indexedEnd = position
+
+				index.Blocks = append(index.Blocks, currentBlock)
+				currentBlock = Block{Offset: position, FirstRow: currentBlock.FirstRow + currentBlock.RowCount}
+				continue
+			}
+
+			// Otherwise count the rows up to the point the block is large enough
+			countedBytes := remainingBytes[:min(int64(len(remainingBytes)), currentBlock.Offset+index.BlockSize-1-position)]
+			if rowCount := bytes.Count(countedBytes, []byte{utilities.NewLineHex}); rowCount > 0 {
+				currentBlock.RowCount += int64(rowCount)
+				indexedEnd = position + int64(bytes.LastIndexByte(countedBytes, utilities.NewLineHex)) + 1
+			}
+
+			position += int64(len(countedBytes))
+			remainingBytes = remainingBytes[len(countedBytes):]
+		}
+
+		if errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return index, err
+		}
+	}
+
+	// Keep the final, smaller block as long as it holds complete rows
+	if currentBlock.RowCount > 0 {
+		index.Blocks = append(index.Blocks, currentBlock)
+	}
+
+	index.Version = IndexVersion
+	index.IndexedEnd = indexedEnd
+	index.Fingerprint, err = utilities.FingerprintFile(file, fileInfo, min(utilities.PrefixHashSize, indexedEnd))
+
+	return index, err
+}
+
+// Check - Makes sure the index still describes the file. Returns an empty reason when the index can be used,
+// otherwise the reason the index needs to be rebuilt.
+func (index RowIndex) Check(file *os.File) (string, error) {
+
+	if index.Version != IndexVersion {
+		return fmt.Sprintf("index is version %v, expected %v", index.Version, IndexVersion), nil
+	}
+
+	fileInfo, err := file.Stat()
+	if err != nil {
+		return "", err
+	}
+
+	return utilities.CheckFingerprint(file, fileInfo, index.Fingerprint, index.IndexedEnd)
+}
+
+// TotalRows - Number of complete rows covered by the index
+func (index RowIndex) TotalRows() int64 {
+
+	if len(index.Blocks) == 0 {
+		return 0
+	}
+	finalBlock := index.Blocks[len(index.Blocks)-1]
+	return finalBlock.FirstRow + finalBlock.RowCount
+}
+
+// BlockEnd - Offset directly after the final row of the block
+func (index RowIndex) BlockEnd(blockIndex int) int64 {
+	if blockIndex+1 < len(index.Blocks) {
+		return index.Blocks[blockIndex+1].Offset
+	}
+	return index.IndexedEnd
+}
+
+// PlanSections - Groups the blocks into roughly equal sections without touching the file. Any bytes appended after the
+// indexed range, up to `end`, become one final section. Returns the boundaries of the sections, beginning with zero
+// and finishing with `end`.
+func (index RowIndex) PlanSections(end int64, numberOfSections int) []int64 {
+
+	var boundaries = []int64{0}
+
+	for sectionIndex := 1; sectionIndex < numberOfSections; sectionIndex++ {
+		targetOffset := index.IndexedEnd * int64(sectionIndex) / int64(numberOfSections)
+
+		// Pick the first block that begins at or after the target
+		blockIndex, _ := slices.BinarySearchFunc(index.Blocks, targetOffset, func(block Block, target int64) int {
+			return cmp.Compare(block.Offset, target)
+		})
+		if blockIndex >= len(index.Blocks) {
+			break
+		}
+
+		if blockOffset := index.Blocks[blockIndex].Offset; blockOffset > boundaries[len(boundaries)-1] {
+			boundaries = append(boundaries, blockOffset)
+		}
+	}
+
+	if index.IndexedEnd > boundaries[len(boundaries)-1] && index.IndexedEnd < end {
+		boundaries = append(boundaries, index.IndexedEnd)
+	}
+
+	return append(boundaries, end)
+}
+
+// LocateRow - Finds the block holding the zero based row number with a binary search over the first row of each block.
+// Returns the offset of that block and the number of rows that still need skipping within the block, or false when the
+// row sits past the indexed range.
+func (index RowIndex) LocateRow(row int64) (int64, int64, bool) {
+
+	if row < 0 || row >= index.TotalRows() {
+		return 0, 0, false
+	}
+
+	// The first block beginning past the row, so the row sits within the block before it
+	blockIndex, _ := slices.BinarySearchFunc(index.Blocks, row+1, func(block Block, target int64) int {
+		return cmp.Compare(block.FirstRow, target)
+	})
+	block := index.Blocks[blockIndex-1]
+
+	return block.Offset, row - block.FirstRow, true
+}
+
+// CopyRows - Writes `rowCount` rows, beginning at the zero based row number, out to the writer. Jumps straight to the
+// block holding the first row, so only the rows within that one block need to be skipped over.
+func (index RowIndex) CopyRows(file io.ReaderAt, firstRow int64, rowCount int64, writer io.Writer) error {
+
+	blockOffset, rowsToSkip, ok := index.LocateRow(firstRow)
+	if !ok {
+		return fmt.Errorf("row %v is past the %v indexed rows", firstRow, index.TotalRows())
+	}
+
+	var readBuffer = make([]byte, 64*1024)
+	var position = blockOffset
+
+	for rowCount > 0 && position < index.IndexedEnd {
+		n, err := file.ReadAt(readBuffer[:min(int64(len(readBuffer)), index.IndexedEnd-position)], position)
+		if n == 0 && err != nil {
+			return err
+		}
+		remainingBytes := readBuffer[:n]
+		position += int64(n)
+
+		for len(remainingBytes) > 0 && rowCount > 0 {
+			newLineIndex := bytes.IndexByte(remainingBytes, utilities.NewLineHex)
+
+			// The row carries on into the next read
+			if newLineIndex < 0 {
+				if rowsToSkip == 0 {
+					if _, err := writer.Write(remainingBytes); err != nil {
+						return err
+					}
+				}
+				break
+			}
+
+			if rowsToSkip > 0 {
+				rowsToSkip--
+			} else {
+				if _, err := writer.Write(remainingBytes[:newLineIndex+1]); err != nil {
+					return err
+				}
+				rowCount--
+			}
+			remainingBytes = remainingBytes[newLineIndex+1:]
+		}
+	}
+
+	return nil
+}
+
+// Load - Reads a previously saved index
+func Load(indexPath string) (RowIndex, error) {
+
+	var index RowIndex
+
+	indexBytes, err := os.ReadFile(indexPath)
+	if err != nil {
+		return index, err
+	}
+
+	err = json.Unmarshal(indexBytes, &index)
+	return index, err
+}
+
+// Save - Writes the index over any previously saved index
+func Save(indexPath string, index RowIndex) error {
+
+	indexBytes, err := json.Marshal(index)
+	if err != nil {
+		return err
+	}
+
+	return utilities.WriteFileAtomic(indexPath, indexBytes)
+}
@@ -0,0 +1,308 @@
+package rowindex
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"testing"
+)
+
+const testBlockSize = 4096 // Small enough for a test file to span many blocks, and for rows to straddle every read
+
+// testRows - Rows of varying length, some with multi-byte station names
+func testRows(first int, count int) []byte {
+
+	var rows []byte
+	for row := first; row < first+count; row++ {
+		rows = fmt.Appendf(rows, "Station %v%v;%.1f\n", row%97, string([]rune("åéü")[row%3]), float64(row%1999-999)/10)
+	}
+	return rows
+}
+
+// rowStarts - The offset of every complete row, found the slow way
+func rowStarts(data []byte) []int64 {
+
+	var starts []int64
+	for position := 0; ; {
+		newLineIndex := bytes.IndexByte(data[position:], '\n')
+		if newLineIndex < 0 {
+			return starts
+		}
+		starts = append(starts, int64(position))
+		position += newLineIndex + 1
+	}
+}
+
+// writeTestFile - Writes the data into a new file within the test's directory and opens it
+func writeTestFile(t *testing.T, data []byte) *os.File {
+
+	path := filepath.Join(t.TempDir(), "measurements.txt")
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { file.Close() })
+
+	return file
+}
+
+func TestBuild(t *testing.T) {
+
+	// A partial final row is left out of the index
+	data := append(testRows(0, 10_000), "Abha;1"...)
+	file := writeTestFile(t, data)
+	starts := rowStarts(data)
+
+	index, err := Build(file, testBlockSize)
+	if err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+
+	if index.TotalRows() != int64(len(starts)) {
+		t.Errorf("TotalRows = %v, expected %v", index.TotalRows(), len(starts))
+	}
+	if expected := int64(bytes.LastIndexByte(data, '\n') + 1); index.IndexedEnd != expected {
+		t.Errorf("IndexedEnd = %v, expected %v", index.IndexedEnd, expected)
+	}
+	if len(index.Blocks) < 2 {
+		t.Fatalf("only %v blocks for %v bytes", len(index.Blocks), len(data))
+	}
+
+	for blockIndex, block := range index.Blocks {
+		if !slices.Contains(starts, block.Offset) || starts[block.FirstRow] != block.Offset {
+			t.Errorf("block %v begins at %v, which is not the start of row %v", blockIndex, block.Offset, block.FirstRow)
+		}
+
+		end := index.BlockEnd(blockIndex)
+		if rowCount := int64(bytes.Count(data[block.Offset:end], []byte{'\n'})); rowCount != block.RowCount {
+			t.Errorf("block %v holds %v rows, expected %v", blockIndex, block.RowCount, rowCount)
+		}
+		if blockIndex+1 < len(index.Blocks) && end-block.Offset < testBlockSize {
+			t.Errorf("block %v is %v bytes, smaller than the block size", blockIndex, end-block.Offset)
+		}
+	}
+}
+
+func TestUpdateEqualsBuild(t *testing.T) {
+
+	// Append a partial row, then the rest of it along with more rows, updating the index after each
+	appends := [][]byte{testRows(0, 5_000), []byte("Abha;1"), append([]byte("2.3\n"), testRows(5_000, 3_000)...)}
+
+	var data []byte
+	var index = RowIndex{Version: IndexVersion, BlockSize: testBlockSize}
+
+	for appendIndex, appended := range appends {
+		data = append(data, appended...)
+		file := writeTestFile(t, data)
+
+		var err error
+		if index, err = Update(file, index); err != nil {
+			t.Fatalf("Update: %v", err)
+		}
+
+		built, err := Build(file, testBlockSize)
+		if err != nil {
+			t.Fatalf("Build: %v", err)
+		}
+		if !reflect.DeepEqual(index, built) {
+			t.Errorf("after append %v, the updated index differs from one built from scratch", appendIndex+1)
+		}
+	}
+}
+
+func TestLocateRow(t *testing.T) {
+
+	data := testRows(0, 10_000)
+	file := writeTestFile(t, data)
+	starts := rowStarts(data)
+
+	index, err := Build(file, testBlockSize)
+	if err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+
+	for row, start := range starts {
+		offset, rowsToSkip, ok := index.LocateRow(int64(row))
+		if !ok {
+			t.Fatalf("LocateRow(%v) found nothing", row)
+		}
+
+		// Skipping the rows from the block's offset has to land on the row
+		position := offset
+		for range rowsToSkip {
+			position += int64(bytes.IndexByte(data[position:], '\n')) + 1
+		}
+		if position != start {
+			t.Fatalf("LocateRow(%v) leads to %v, expected %v", row, position, start)
+		}
+	}
+
+	for _, row := range []int64{-1, int64(len(starts)), int64(len(starts)) + 100} {
+		if _, _, ok := index.LocateRow(row); ok {
+			t.Errorf("LocateRow(%v) found a row past the indexed range", row)
+		}
+	}
+}
+
+func TestCopyRows(t *testing.T) {
+
+	// Enough rows for the copy to cross several of its reads
+	data := testRows(0, 20_000)
+	file := writeTestFile(t, data)
+	starts := append(rowStarts(data), int64(len(data)))
+
+	index, err := Build(file, testBlockSize)
+	if err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+
+	tests := []struct {
+		firstRow int64
+		rowCount int64
+	}{
+		{0, 1}, {0, 20_000}, {123, 456}, {9_999, 7_000}, {19_999, 1}, {19_990, 100},
+	}
+
+	for _, test := range tests {
+		t.Run(fmt.Sprintf("%v+%v", test.firstRow, test.rowCount), func(t *testing.T) {
+			var copied bytes.Buffer
+			if err := index.CopyRows(file, test.firstRow, test.rowCount, &copied); err != nil {
+				t.Fatalf("CopyRows: %v", err)
+			}
+
+			lastRow := min(test.firstRow+test.rowCount, int64(len(starts)-1))
+			if expected := data[starts[test.firstRow]:starts[lastRow]]; !bytes.Equal(copied.Bytes(), expected) {
+				t.Errorf("copied %v bytes, expected %v", copied.Len(), len(expected))
+			}
+		})
+	}
+
+	if err := index.CopyRows(file, 20_000, 1, &bytes.Buffer{}); err == nil {
+		t.Error("CopyRows past the final row succeeded")
+	}
+}
+
+func TestPlanSections(t *testing.T) {
+
+	data := testRows(0, 10_000)
+	file := writeTestFile(t, data)
+
+	index, err := Build(file, testBlockSize)
+	if err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+
+	var blockOffsets []int64
+	for _, block := range index.Blocks {
+		blockOffsets = append(blockOffsets, block.Offset)
+	}
+
+	// The file as indexed, with bytes appended after the index, and with more sections than blocks
+	tests := []struct {
+		end              int64
+		numberOfSections int
+	}{
+		{index.IndexedEnd, 1}, {index.IndexedEnd, 4}, {index.IndexedEnd + 5_000, 4}, {index.IndexedEnd, 10_000},
+	}
+
+	for _, test := range tests {
+		t.Run(fmt.Sprintf("%v/%v", test.end, test.numberOfSections), func(t *testing.T) {
+			boundaries := index.PlanSections(test.end, test.numberOfSections)
+
+			if boundaries[0] != 0 || boundaries[len(boundaries)-1] != test.end {
+				t.Fatalf("boundaries %v do not run from 0 to %v", boundaries, test.end)
+			}
+			if !slices.IsSorted(boundaries) || len(slices.Compact(slices.Clone(boundaries))) != len(boundaries) {
+				t.Errorf("boundaries %v are not strictly increasing", boundaries)
+			}
+			if len(boundaries)-1 > test.numberOfSections+1 {
+				t.Errorf("%v sections planned, expected at most %v", len(boundaries)-1, test.numberOfSections+1)
+			}
+
+			for _, boundary := range boundaries[1 : len(boundaries)-1] {
+				if !slices.Contains(blockOffsets, boundary) && boundary != index.IndexedEnd {
+					t.Errorf("boundary %v is not the start of a block", boundary)
+				}
+			}
+		})
+	}
+}
+
+func TestCheck(t *testing.T) {
+
+	data := testRows(0, 1_000)
+	path := filepath.Join(t.TempDir(), "measurements.txt")
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	index, err := Build(file, testBlockSize)
+	file.Close()
+	if err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+
+	// Appending rows keeps the index usable, rewriting an indexed row does not
+	changes := []struct {
+		name   string
+		data   []byte
+		usable bool
+	}{
+		{"unchanged", data, true},
+		{"appended", append(slices.Clone(data), testRows(1_000, 10)...), true},
+		{"rewritten", append([]byte("Accra"), data[4:]...), false},
+		{"truncated", data[:len(data)/2], false},
+	}
+
+	for _, change := range changes {
+		t.Run(change.name, func(t *testing.T) {
+			file := writeTestFile(t, change.data)
+
+			reason, err := index.Check(file)
+			if err != nil {
+				t.Fatalf("Check: %v", err)
+			}
+			if (reason == "") != change.usable {
+				t.Errorf("Check = %q, expected the index to be usable: %v", reason, change.usable)
+			}
+		})
+	}
+
+	outdated := index
+	outdated.Version--
+	if reason, _ := outdated.Check(writeTestFile(t, data)); reason == "" {
+		t.Error("Check accepted an index of an older version")
+	}
+}
+
+func TestSaveLoad(t *testing.T) {
+
+	index, err := Build(writeTestFile(t, testRows(0, 1_000)), testBlockSize)
+	if err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+
+	indexPath := IndexPath(filepath.Join(t.TempDir(), "measurements.txt"))
+	if err := Save(indexPath, index); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	loaded, err := Load(indexPath)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if !reflect.DeepEqual(loaded, index) {
+		t.Errorf("loaded %+v, expected %+v", loaded, index)
+	}
+}
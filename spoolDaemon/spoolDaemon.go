@@ -0,0 +1,346 @@
+package spooldaemon
+
+import (
+	multireader "billionRowChallenge/multiReader"
+	"billionRowChallenge/utilities"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+const ResultVersion = 1 // Bumped whenever the layout of the running result changes
+
+// Directories kept within the spool root. New files are dropped into `incoming`, claimed by renaming them into
+// `processing`, and finally moved into `done` or `failed` alongside their report.
+const (
+	IncomingDirectory   = "incoming"
+	ProcessingDirectory = "processing"
+	DoneDirectory       = "done"
+	FailedDirectory     = "failed"
+	ResultFilename      = "result.json"
+)
+
+// RunningResult - The persistent result that every ingested file is merged into. `Applied` holds the claim IDs of files
+// that have been merged but may still be sitting within `processing`, which is what keeps a crash between merging a file
+// and moving it out of `processing` from ever counting that file twice.
+type RunningResult struct {
+	Version        int                               `json:"version"`
+	FilesProcessed int                               `json:"filesProcessed"`
+	Applied        []string                          `json:"applied"`
+	OutputMap      map[string]utilities.OutputValues `json:"outputMap"`
+}
+
+// FileReport - Written beside every file moved into `done` or `failed`
+type FileReport struct {
+	File       string    `json:"file"`
+	ClaimID    string    `json:"claimId"`
+	Status     string    `json:"status"`
+	Error      string    `json:"error,omitempty"`
+	Bytes      int64     `json:"bytes"`
+	Rows       int       `json:"rows"`
+	Stations   int       `json:"stations"`
+	Recovered  bool      `json:"recovered,omitempty"`
+	StartedAt  time.Time `json:"startedAt"`
+	FinishedAt time.Time `json:"finishedAt"`
+}
+
+// Spool - A spool directory watched for new measurement files. Only one daemon should watch a spool root at a time.
+type Spool struct {
+	Root       string        // Directory holding the incoming, processing, done, and failed directories
+	SettleTime time.Duration // Files modified more recently than this are left alone, as they may still be written
+}
+
+// Prepare - Creates any missing spool directories
+func (spool Spool) Prepare() error {
+	for _, directory := range []string{IncomingDirectory, ProcessingDirectory, DoneDirectory, FailedDirectory} {
+		if err := os.MkdirAll(filepath.Join(spool.Root, directory), 0o755); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Watch - Recovers anything left over from a crash, then polls the incoming directory every interval until the
+// context is cancelled.
+func (spool Spool) Watch(ctx context.Context, interval time.Duration) error {
+
+	if err := spool.Recover(); err != nil {
+		return err
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := spool.ProcessIncoming(); err != nil {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// Recover - Finishes off any files left within `processing` by a previous run. Files whose claim was already merged
+// are only moved into `done`, everything else is aggregated again as it never reached the running result.
+func (spool Spool) Recover() error {
+
+	result, err := spool.LoadResult()
+	if err != nil {
+		return err
+	}
+
+	entries, err := os.ReadDir(filepath.Join(spool.Root, ProcessingDirectory))
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		claimID := entry.Name()
+
+		if !slices.Contains(result.Applied, claimID) {
+			if err = spool.ingest(claimID); err != nil {
+				return err
+			}
+			continue
+		}
+
+		// Keep the original report when the crash happened after it was written
+		reportPath := filepath.Join(spool.Root, DoneDirectory, claimID+".report.json")
+		if _, err = os.Stat(reportPath); errors.Is(err, fs.ErrNotExist) {
+			now := time.Now()
+			err = writeReport(reportPath, FileReport{
+				File:       originalName(claimID),
+				ClaimID:    claimID,
+				Status:     "done",
+				Recovered:  true,
+				StartedAt:  now,
+				FinishedAt: now,
+			})
+		}
+		if err != nil {
+			return err
+		}
+
+		if err = os.Rename(filepath.Join(spool.Root, ProcessingDirectory, claimID), filepath.Join(spool.Root, DoneDirectory, claimID)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ProcessIncoming - Claims and ingests every settled file within the incoming directory, oldest name first.
+// Returns the number of files that were ingested.
+func (spool Spool) ProcessIncoming() (int, error) {
+
+	entries, err := os.ReadDir(filepath.Join(spool.Root, IncomingDirectory))
+	if err != nil {
+		return 0, err
+	}
+
+	var filesIngested int
+
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() || isTemporaryName(entry.Name()) {
+			continue
+		}
+
+		fileInfo, err := entry.Info()
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		} else if err != nil {
+			return filesIngested, err
+		}
+		if time.Since(fileInfo.ModTime()) < spool.SettleTime {
+			continue
+		}
+
+		claimID, err := spool.claim(entry.Name())
+		if errors.Is(err, fs.ErrNotExist) {
+			continue // Someone else got to the file first
+		} else if err != nil {
+			return filesIngested, err
+		}
+
+		if err = spool.ingest(claimID); err != nil {
+			return filesIngested, err
+		}
+		filesIngested++
+	}
+
+	return filesIngested, nil
+}
+
+// claim - Atomically moves the file out of the incoming directory. The claim ID keeps the original name, prefixed by
+// the claim time, so the same name can be dropped into the spool again later without clashing.
+func (spool Spool) claim(name string) (string, error) {
+
+	claimID := time.Now().UTC().Format("20060102T150405.000000000") + "-" + name
+
+	err := os.Rename(filepath.Join(spool.Root, IncomingDirectory, name), filepath.Join(spool.Root, ProcessingDirectory, claimID))
+	return claimID, err
+}
+
+// ingest - Aggregates a claimed file and merges it into the running result. The running result is saved, together
+// with the claim ID, before the file leaves `processing`. A file that fails to aggregate is moved into `failed`
+// without touching the running result.
+func (spool Spool) ingest(claimID string) error {
+
+	report := FileReport{
+		File:      originalName(claimID),
+		ClaimID:   claimID,
+		StartedAt: time.Now(),
+	}
+
+	outputMap, bytesRead, err := aggregateClaimedFile(filepath.Join(spool.Root, ProcessingDirectory, claimID))
+	report.Bytes = bytesRead
+
+	if err != nil {
+		report.Status = "failed"
+		report.Error = err.Error()
+		report.FinishedAt = time.Now()
+
+		fmt.Fprintf(os.Stderr, "Failed %v: %v\n", report.File, err)
+		return spool.finish(claimID, FailedDirectory, report)
+	}
+
+	for _, outputValues := range outputMap {
+		report.Rows += outputValues.Count
+	}
+	report.Stations = len(outputMap)
+
+	result, err := spool.LoadResult()
+	if err != nil {
+		return err
+	}
+
+	// Only keep the claims that are still sitting within `processing`, anything else has already been moved on
+	var applied []string
+	for _, appliedClaimID := range result.Applied {
+		if _, err = os.Stat(filepath.Join(spool.Root, ProcessingDirectory, appliedClaimID)); err == nil {
+			applied = append(applied, appliedClaimID)
+		}
+	}
+
+	utilities.MergeOutputMaps(result.OutputMap, outputMap)
+	result.Applied = append(applied, claimID)
+	result.FilesProcessed++
+
+	if err = spool.saveResult(result); err != nil {
+		return err
+	}
+
+	report.Status = "done"
+	report.FinishedAt = time.Now()
+
+	fmt.Fprintf(os.Stderr, "Ingested %v: %v rows across %v stations\n", report.File, report.Rows, report.Stations)
+	return spool.finish(claimID, DoneDirectory, report)
+}
+
+// finish - Writes the report and moves the claimed file into its final directory
+func (spool Spool) finish(claimID string, directory string, report FileReport) error {
+
+	if err := writeReport(filepath.Join(spool.Root, directory, claimID+".report.json"), report); err != nil {
+		return err
+	}
+
+	return os.Rename(filepath.Join(spool.Root, ProcessingDirectory, claimID), filepath.Join(spool.Root, directory, claimID))
+}
+
+// aggregateClaimedFile - Runs the file through the section reader. Spooled files are expected to be complete, so a
+// trailing unterminated row fails the whole file instead of being silently dropped.
+func aggregateClaimedFile(filename string) (map[string]utilities.OutputValues, int64, error) {
+
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer file.Close()
+
+	fileInfo, err := file.Stat()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	outputMap, processedOffset, err := multireader.AggregateCompleteRows(file, 0, fileInfo.Size())
+	if err != nil {
+		return nil, processedOffset, err
+	}
+	if processedOffset != fileInfo.Size() {
+		return nil, processedOffset, fmt.Errorf("file ends with an unterminated row at byte %v", processedOffset)
+	}
+
+	return outputMap, processedOffset, nil
+}
+
+// LoadResult - Reads the running result, starting a fresh one when none has been saved yet
+func (spool Spool) LoadResult() (RunningResult, error) {
+
+	var result = RunningResult{Version: ResultVersion}
+
+	resultBytes, err := os.ReadFile(filepath.Join(spool.Root, ResultFilename))
+	if errors.Is(err, fs.ErrNotExist) {
+		result.OutputMap = make(map[string]utilities.OutputValues)
+		return result, nil
+	} else if err != nil {
+		return result, err
+	}
+
+	if err = json.Unmarshal(resultBytes, &result); err != nil {
+		return result, err
+	}
+	if result.Version != ResultVersion {
+		return result, fmt.Errorf("running result is version %v, expected %v", result.Version, ResultVersion)
+	}
+	if result.OutputMap == nil {
+		result.OutputMap = make(map[string]utilities.OutputValues)
+	}
+
+	return result, nil
+}
+
+// saveResult - Writes the running result over the previous one
+func (spool Spool) saveResult(result RunningResult) error {
+
+	resultBytes, err := json.Marshal(result)
+	if err != nil {
+		return err
+	}
+
+	return utilities.WriteFileAtomic(filepath.Join(spool.Root, ResultFilename), resultBytes)
+}
+
+// writeReport - Writes a per-file report
+func writeReport(reportPath string, report FileReport) error {
+
+	reportBytes, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return utilities.WriteFileAtomic(reportPath, reportBytes)
+}
+
+// originalName - Strips the claim time back off the claim ID
+func originalName(claimID string) string {
+	if _, name, ok := strings.Cut(claimID, "-"); ok {
+		return name
+	}
+	return claimID
+}
+
+// isTemporaryName - Hidden and partially written files are left alone until they are renamed into place
+func isTemporaryName(name string) bool {
+	return strings.HasPrefix(name, ".") || strings.HasSuffix(name, ".tmp") || strings.HasSuffix(name, ".part")
+}
@@ -0,0 +1,245 @@
+package spooldaemon
+
+import (
+	"billionRowChallenge/utilities"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func testRows(first int, count int) []byte {
+
+	var rows []byte
+	for row := first; row < first+count; row++ {
+		rows = fmt.Appendf(rows, "Station %v;%.1f\n", row%37, float64(row*7919%1999-999)/10)
+	}
+	return rows
+}
+
+// aggregateRows - The results of every row added one at a time, to check the running result against
+func aggregateRows(first int, count int) map[string]utilities.OutputValues {
+
+	outputMap := make(map[string]utilities.OutputValues)
+	for row := first; row < first+count; row++ {
+		utilities.AddTemperature(outputMap, fmt.Sprintf("Station %v", row%37), row*7919%1999-999)
+	}
+	return outputMap
+}
+
+// newSpool - A prepared spool within the test's directory
+func newSpool(t *testing.T) Spool {
+
+	spool := Spool{Root: t.TempDir()}
+	if err := spool.Prepare(); err != nil {
+		t.Fatalf("Prepare: %v", err)
+	}
+	return spool
+}
+
+func writeSpoolFile(t *testing.T, spool Spool, directory string, name string, data []byte) {
+	if err := os.WriteFile(filepath.Join(spool.Root, directory, name), data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func readReport(t *testing.T, spool Spool, directory string, claimID string) FileReport {
+
+	var report FileReport
+
+	reportBytes, err := os.ReadFile(filepath.Join(spool.Root, directory, claimID+".report.json"))
+	if err != nil {
+		t.Fatalf("report of %v: %v", claimID, err)
+	}
+	if err = json.Unmarshal(reportBytes, &report); err != nil {
+		t.Fatalf("report of %v: %v", claimID, err)
+	}
+
+	return report
+}
+
+// directoryNames - The names within one of the spool directories
+func directoryNames(t *testing.T, spool Spool, directory string) []string {
+
+	entries, err := os.ReadDir(filepath.Join(spool.Root, directory))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	return names
+}
+
+func TestProcessIncoming(t *testing.T) {
+
+	spool := newSpool(t)
+
+	writeSpoolFile(t, spool, IncomingDirectory, "first.csv", testRows(0, 1_000))
+	writeSpoolFile(t, spool, IncomingDirectory, "second-half.csv", testRows(1_000, 500))
+	writeSpoolFile(t, spool, IncomingDirectory, "broken.csv", append(testRows(1_500, 10), "Abha;1"...))
+	for _, name := range []string{".hidden.csv", "writing.csv.tmp", "writing.csv.part"} {
+		writeSpoolFile(t, spool, IncomingDirectory, name, testRows(0, 10))
+	}
+
+	filesIngested, err := spool.ProcessIncoming()
+	if err != nil {
+		t.Fatalf("ProcessIncoming: %v", err)
+	}
+	if filesIngested != 3 {
+		t.Errorf("ingested %v files, expected 3", filesIngested)
+	}
+
+	result, err := spool.LoadResult()
+	if err != nil {
+		t.Fatalf("LoadResult: %v", err)
+	}
+	if result.FilesProcessed != 2 {
+		t.Errorf("%v files processed, expected 2", result.FilesProcessed)
+	}
+	if !maps.Equal(result.OutputMap, aggregateRows(0, 1_500)) {
+		t.Error("running result differs from the rows of the ingested files")
+	}
+
+	// Half written files stay where they are, and nothing is left mid claim
+	if names := directoryNames(t, spool, IncomingDirectory); len(names) != 3 {
+		t.Errorf("incoming holds %v, expected only the temporary files", names)
+	}
+	if names := directoryNames(t, spool, ProcessingDirectory); len(names) != 0 {
+		t.Errorf("processing still holds %v", names)
+	}
+
+	failed := directoryNames(t, spool, FailedDirectory)
+	if len(failed) != 2 {
+		t.Fatalf("failed holds %v, expected the broken file and its report", failed)
+	}
+	if report := readReport(t, spool, FailedDirectory, failed[0]); report.File != "broken.csv" || report.Error == "" {
+		t.Errorf("report of the broken file = %+v", report)
+	}
+
+	for _, name := range directoryNames(t, spool, DoneDirectory) {
+		if filepath.Ext(name) != ".csv" {
+			continue
+		}
+		report := readReport(t, spool, DoneDirectory, name)
+		if report.Status != "done" || report.ClaimID != name || report.Rows == 0 {
+			t.Errorf("report of %v = %+v", name, report)
+		}
+	}
+}
+
+func TestProcessIncomingWaitsToSettle(t *testing.T) {
+
+	spool := newSpool(t)
+	spool.SettleTime = time.Hour
+
+	writeSpoolFile(t, spool, IncomingDirectory, "fresh.csv", testRows(0, 10))
+
+	if filesIngested, err := spool.ProcessIncoming(); err != nil || filesIngested != 0 {
+		t.Errorf("ProcessIncoming = %v, %v, expected the fresh file to be left alone", filesIngested, err)
+	}
+}
+
+func TestRecoverUnmergedClaim(t *testing.T) {
+
+	spool := newSpool(t)
+
+	// A crash after the file was claimed, before it reached the running result
+	claimID := "20261019T101500.000000000-claimed.csv"
+	writeSpoolFile(t, spool, ProcessingDirectory, claimID, testRows(0, 200))
+
+	if err := spool.Recover(); err != nil {
+		t.Fatalf("Recover: %v", err)
+	}
+
+	result, err := spool.LoadResult()
+	if err != nil {
+		t.Fatalf("LoadResult: %v", err)
+	}
+	if result.FilesProcessed != 1 || !maps.Equal(result.OutputMap, aggregateRows(0, 200)) {
+		t.Errorf("running result after recovery = %+v", result)
+	}
+	if report := readReport(t, spool, DoneDirectory, claimID); report.File != "claimed.csv" || report.Recovered {
+		t.Errorf("report = %+v, expected a normal ingest of claimed.csv", report)
+	}
+}
+
+func TestRecoverMergedClaim(t *testing.T) {
+
+	spool := newSpool(t)
+
+	// A crash after the running result was saved, before the file left `processing`
+	claimID := "20261019T101500.000000000-merged.csv"
+	writeSpoolFile(t, spool, ProcessingDirectory, claimID, testRows(0, 200))
+	saved := RunningResult{
+		Version:        ResultVersion,
+		FilesProcessed: 1,
+		Applied:        []string{claimID},
+		OutputMap:      aggregateRows(0, 200),
+	}
+	if err := spool.saveResult(saved); err != nil {
+		t.Fatal(err)
+	}
+
+	// Recovering twice over must never count the file again
+	for range 2 {
+		if err := spool.Recover(); err != nil {
+			t.Fatalf("Recover: %v", err)
+		}
+	}
+
+	result, err := spool.LoadResult()
+	if err != nil {
+		t.Fatalf("LoadResult: %v", err)
+	}
+	if result.FilesProcessed != 1 || !maps.Equal(result.OutputMap, saved.OutputMap) {
+		t.Errorf("running result after recovery = %+v, expected it unchanged", result)
+	}
+	if names := directoryNames(t, spool, ProcessingDirectory); len(names) != 0 {
+		t.Errorf("processing still holds %v", names)
+	}
+	if report := readReport(t, spool, DoneDirectory, claimID); !report.Recovered || report.File != "merged.csv" {
+		t.Errorf("report = %+v, expected a recovered report of merged.csv", report)
+	}
+
+	// The next ingest drops the claim that has since moved on
+	writeSpoolFile(t, spool, IncomingDirectory, "next.csv", testRows(200, 100))
+	if _, err = spool.ProcessIncoming(); err != nil {
+		t.Fatalf("ProcessIncoming: %v", err)
+	}
+	if result, err = spool.LoadResult(); err != nil || len(result.Applied) != 1 || result.Applied[0] == claimID {
+		t.Errorf("applied claims = %v, %v, expected only the newest", result.Applied, err)
+	}
+}
+
+func TestLoadResultRejectsOtherVersions(t *testing.T) {
+
+	spool := newSpool(t)
+	if err := spool.saveResult(RunningResult{Version: ResultVersion + 1}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := spool.LoadResult(); err == nil {
+		t.Error("LoadResult accepted a result of a newer version")
+	}
+}
+
+func TestOriginalName(t *testing.T) {
+
+	tests := map[string]string{
+		"20261019T101500.000000000-measurements.csv": "measurements.csv",
+		"20261019T101500.000000000-with-dashes.csv":  "with-dashes.csv",
+		"unclaimed.csv": "unclaimed.csv",
+	}
+
+	for claimID, expected := range tests {
+		if name := originalName(claimID); name != expected {
+			t.Errorf("originalName(%q) = %q, expected %q", claimID, name, expected)
+		}
+	}
+}
@@ -0,0 +1,138 @@
+package spreadstatistics
+
+import (
+	resultsnapshot "billionRowChallenge/resultSnapshot"
+	"billionRowChallenge/utilities"
+	"encoding/binary"
+	"errors"
+	"slices"
+)
+
+// SnapshotSectionTag - Tag of the snapshot section holding the spread of every station
+const SnapshotSectionTag = 2
+
+var errSectionTruncated = errors.New("spread section is truncated")
+
+func init() {
+	resultsnapshot.SectionMergers[SnapshotSectionTag] = mergeSections
+}
+
+// Spread - The running totals needed for the variance of every station, collected alongside the output values
+type Spread map[string]*utilities.SpreadValues
+
+// NewCollector - An empty spread, ready to be handed to the section reader
+func NewCollector() utilities.Collector {
+	return Spread{}
+}
+
+// Add - Adds a single reading at the station
+func (spread Spread) Add(station string, temperature int) {
+
+	spreadValues, ok := spread[station]
+	if !ok {
+		spreadValues = &utilities.SpreadValues{}
+		spread[station] = spreadValues
+	}
+	spreadValues.Add(temperature)
+}
+
+// Merge - Folds the spread collected by another section into this one
+func (spread Spread) Merge(other utilities.Collector) {
+	for station, otherValues := range other.(Spread) {
+		if spreadValues, ok := spread[station]; ok {
+			spreadValues.Merge(*otherValues)
+		} else {
+			spread[station] = otherValues
+		}
+	}
+}
+
+// Section - The spread laid out as a snapshot section: the station count, then for every station sorted by name, its
+// name, count, total, and the high and low halves of its sum of squares
+func (spread Spread) Section() resultsnapshot.Section {
+
+	stations := make([]string, 0, len(spread))
+	for station := range spread {
+		stations = append(stations, station)
+	}
+	slices.Sort(stations)
+
+	var data []byte
+	data = binary.AppendUvarint(data, uint64(len(stations)))
+	for _, station := range stations {
+		spreadValues := spread[station]
+
+		data = binary.AppendUvarint(data, uint64(len(station)))
+		data = append(data, station...)
+		data = binary.AppendUvarint(data, uint64(spreadValues.Count))
+		data = binary.AppendVarint(data, int64(spreadValues.Total))
+		data = binary.AppendUvarint(data, spreadValues.SumOfSquares.High)
+		data = binary.AppendUvarint(data, spreadValues.SumOfSquares.Low)
+	}
+
+	return resultsnapshot.Section{Tag: SnapshotSectionTag, Data: data}
+}
+
+// FromSection - Reads the spread back out of a snapshot section
+func FromSection(data []byte) (Spread, error) {
+
+	var position int
+	var failed bool
+	readUvarint := func() uint64 {
+		value, size := binary.Uvarint(data[position:])
+		if size <= 0 {
+			failed = true
+			return 0
+		}
+		position += size
+		return value
+	}
+
+	stationCount := readUvarint()
+	var spread = make(Spread, min(stationCount, uint64(len(data))))
+
+	for range stationCount {
+		nameLength := readUvarint()
+		if failed || nameLength > uint64(len(data)-position) {
+			return nil, errSectionTruncated
+		}
+		station := string(data[position : position+int(nameLength)])
+		position += int(nameLength)
+
+		spreadValues := &utilities.SpreadValues{Count: int(readUvarint())}
+		total, size := binary.Varint(data[position:])
+		if failed || size <= 0 {
+			return nil, errSectionTruncated
+		}
+		position += size
+		spreadValues.Total = int(total)
+		spreadValues.SumOfSquares.High = readUvarint()
+		spreadValues.SumOfSquares.Low = readUvarint()
+		if failed {
+			return nil, errSectionTruncated
+		}
+
+		spread[station] = spreadValues
+	}
+
+	if failed {
+		return nil, errSectionTruncated
+	}
+	return spread, nil
+}
+
+// mergeSections - Combines the spread sections of two snapshots being merged
+func mergeSections(first []byte, second []byte) ([]byte, error) {
+
+	firstSpread, err := FromSection(first)
+	if err != nil {
+		return nil, err
+	}
+	secondSpread, err := FromSection(second)
+	if err != nil {
+		return nil, err
+	}
+
+	firstSpread.Merge(secondSpread)
+	return firstSpread.Section().Data, nil
+}
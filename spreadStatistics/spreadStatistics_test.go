@@ -0,0 +1,169 @@
+package spreadstatistics
+
+import (
+	resultsnapshot "billionRowChallenge/resultSnapshot"
+	"billionRowChallenge/utilities"
+	"bytes"
+	"maps"
+	"math"
+	"math/big"
+	"math/rand/v2"
+	"testing"
+)
+
+// testReadings - Readings of a few stations, in tenths of a degree
+func testReadings() map[string][]int {
+
+	random := rand.New(rand.NewPCG(5, 6))
+	readings := map[string][]int{"Constant": {123, 123, 123}, "Single": {-999}}
+
+	for range 10_000 {
+		readings["Wide"] = append(readings["Wide"], random.IntN(1999)-999)
+	}
+	for range 10_000 {
+		// A large mean with a small spread, where a single pass in floating point loses the variance
+		readings["Narrow"] = append(readings["Narrow"], 990+random.IntN(10))
+	}
+
+	return readings
+}
+
+// naiveVariance - Population variance in squared degrees, worked out in two passes over the readings
+func naiveVariance(readings []int) float64 {
+
+	var total float64
+	for _, reading := range readings {
+		total += float64(reading) / 10
+	}
+	mean := total / float64(len(readings))
+
+	var squares float64
+	for _, reading := range readings {
+		squares += (float64(reading)/10 - mean) * (float64(reading)/10 - mean)
+	}
+	return squares / float64(len(readings))
+}
+
+// equalSpread - Whether both hold the same running totals for the same stations
+func equalSpread(first Spread, second Spread) bool {
+	return maps.EqualFunc(first, second, func(firstValues *utilities.SpreadValues, secondValues *utilities.SpreadValues) bool {
+		return *firstValues == *secondValues
+	})
+}
+
+func TestVariance(t *testing.T) {
+
+	for station, readings := range testReadings() {
+		var spreadValues utilities.SpreadValues
+		for _, reading := range readings {
+			spreadValues.Add(reading)
+		}
+
+		expected := naiveVariance(readings)
+		if variance := spreadValues.Variance(); math.Abs(variance-expected) > 1e-9*max(expected, 1) {
+			t.Errorf("%v variance = %v, expected %v", station, variance, expected)
+		}
+		if deviation := spreadValues.StandardDeviation(); math.Abs(deviation-math.Sqrt(expected)) > 1e-9*max(expected, 1) {
+			t.Errorf("%v standard deviation = %v, expected %v", station, deviation, math.Sqrt(expected))
+		}
+	}
+
+	var empty utilities.SpreadValues
+	if variance := empty.Variance(); variance != 0 {
+		t.Errorf("variance of nothing = %v, expected 0", variance)
+	}
+}
+
+func TestSumOfSquaresCarries(t *testing.T) {
+
+	sumOfSquares := utilities.SumOfSquares{Low: math.MaxUint64 - 1}
+	sumOfSquares.Add(2)
+	sumOfSquares.Merge(utilities.SumOfSquares{High: 1, Low: math.MaxUint64})
+
+	// (2⁶⁴ - 2) + 4 + (2⁶⁴ + 2⁶⁴ - 1) = 3·2⁶⁴ + 1
+	expected := new(big.Int).Lsh(big.NewInt(3), 64)
+	expected.Add(expected, big.NewInt(1))
+	if sumOfSquares.Int().Cmp(expected) != 0 {
+		t.Errorf("sum of squares = %v, expected %v", sumOfSquares.Int(), expected)
+	}
+}
+
+func TestMergeEqualsSinglePass(t *testing.T) {
+
+	readings := testReadings()
+
+	whole := Spread{}
+	partials := []Spread{{}, {}, {}}
+	for station, stationReadings := range readings {
+		for index, reading := range stationReadings {
+			whole.Add(station, reading)
+			partials[index%len(partials)].Add(station, reading)
+		}
+	}
+
+	merged := NewCollector()
+	for _, partial := range partials {
+		merged.Merge(partial)
+	}
+	if !equalSpread(merged.(Spread), whole) {
+		t.Error("merged partials differ from a single pass")
+	}
+}
+
+func TestSectionRoundTrip(t *testing.T) {
+
+	spread := Spread{}
+	for station, readings := range testReadings() {
+		for _, reading := range readings {
+			spread.Add(station, reading)
+		}
+	}
+	spread["Huge"] = &utilities.SpreadValues{Count: 3, Total: -7, SumOfSquares: utilities.SumOfSquares{High: 2, Low: 5}}
+
+	section := spread.Section()
+	decoded, err := FromSection(section.Data)
+	if err != nil {
+		t.Fatalf("FromSection: %v", err)
+	}
+	if !equalSpread(decoded, spread) {
+		t.Error("decoded spread differs from the spread encoded")
+	}
+
+	for length := range len(section.Data) {
+		if _, err := FromSection(section.Data[:length]); err == nil {
+			t.Fatalf("section cut to %v of %v bytes was accepted", length, len(section.Data))
+		}
+	}
+}
+
+func TestSnapshotMergeEqualsSinglePass(t *testing.T) {
+
+	whole, firstHalf, secondHalf := Spread{}, Spread{}, Spread{}
+	firstMap, secondMap := map[string]utilities.OutputValues{}, map[string]utilities.OutputValues{}
+	for station, readings := range testReadings() {
+		for index, reading := range readings {
+			whole.Add(station, reading)
+			if index < len(readings)/2 {
+				firstHalf.Add(station, reading)
+				utilities.AddTemperature(firstMap, station, reading)
+			} else {
+				secondHalf.Add(station, reading)
+				utilities.AddTemperature(secondMap, station, reading)
+			}
+		}
+	}
+
+	first, second := resultsnapshot.New(firstMap), resultsnapshot.New(secondMap)
+	first.Sections = []resultsnapshot.Section{firstHalf.Section()}
+	second.Sections = []resultsnapshot.Section{secondHalf.Section()}
+
+	merged, err := resultsnapshot.Merge(first, second)
+	if err != nil {
+		t.Fatalf("Merge: %v", err)
+	}
+
+	data, _ := merged.FindSection(SnapshotSectionTag)
+	if !bytes.Equal(data, whole.Section().Data) {
+		t.Error("merged section differs from the section of a single pass")
+	}
+}
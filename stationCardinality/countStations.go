@@ -0,0 +1,58 @@
+package stationcardinality
+
+import (
+	multireader "billionRowChallenge/multiReader"
+	"io"
+	"sync"
+)
+
+// CountStations - Estimates the distinct stations between the boundaries, scanning each section within its own routine
+// into its own sketch before merging them. Returns the merged sketch along with the number of rows scanned.
+func CountStations(file io.ReaderAt, boundaries []int64, precision int) (*HyperLogLog, int64, error) {
+
+	merged, err := NewHyperLogLog(precision)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var waitGroup sync.WaitGroup
+	var sectionSketches = make([]*HyperLogLog, len(boundaries)-1)
+	var sectionRows = make([]int64, len(boundaries)-1)
+	var sectionErrors = make([]error, len(boundaries)-1)
+
+	for sectionIndex := range len(boundaries) - 1 {
+		waitGroup.Add(1)
+
+		go func() {
+			defer waitGroup.Done()
+
+			// The precision was already checked by the merged sketch, so this cannot fail
+			sketch, _ := NewHyperLogLog(precision)
+			sectionSketches[sectionIndex] = sketch
+			_, sectionErrors[sectionIndex] = multireader.ScanSectionStations(
+				file,
+				boundaries[sectionIndex],
+				boundaries[sectionIndex+1]-boundaries[sectionIndex],
+				func(station []byte) {
+					sketch.AddBytes(station)
+					sectionRows[sectionIndex]++
+				},
+			)
+		}()
+	}
+
+	waitGroup.Wait()
+
+	var rows int64
+	for sectionIndex, sketch := range sectionSketches {
+		if sectionErrors[sectionIndex] != nil {
+			return nil, 0, sectionErrors[sectionIndex]
+		}
+		if err := merged.Merge(sketch); err != nil {
+			return nil, 0, err
+		}
+		rows += sectionRows[sectionIndex]
+	}
+
+	return merged, rows, nil
+}
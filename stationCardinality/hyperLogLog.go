@@ -0,0 +1,126 @@
+package stationcardinality
+
+import (
+	"fmt"
+	"math"
+	"math/bits"
+)
+
+// Limits on the precision, the number of bits picking a register. Each register is a byte, so the sketch takes
+// `2^precision` bytes whatever the number of stations.
+const (
+	MinPrecision     = 4
+	MaxPrecision     = 18
+	DefaultPrecision = 14 // 16KB, with a standard error of around 0.8%
+)
+
+// FNV-1a constants, hashing the station names without allocating
+const (
+	fnvOffset uint64 = 0xcbf29ce484222325
+	fnvPrime  uint64 = 0x100000001b3
+)
+
+// HyperLogLog - Estimates the number of distinct station names within a fixed amount of memory. Sketches built with the
+// same precision can be merged, so every section can count on its own.
+type HyperLogLog struct {
+	precision uint8
+	registers []uint8 // Longest run of leading zeros seen, plus one, for the hashes landing on each register
+}
+
+// NewHyperLogLog - An empty sketch with `2^precision` registers
+func NewHyperLogLog(precision int) (*HyperLogLog, error) {
+
+	if precision < MinPrecision || precision > MaxPrecision {
+		return nil, fmt.Errorf("precision %v must be between %v and %v", precision, MinPrecision, MaxPrecision)
+	}
+
+	return &HyperLogLog{precision: uint8(precision), registers: make([]uint8, 1<<precision)}, nil
+}
+
+// AddBytes - Counts a station name
+func (hyperLogLog *HyperLogLog) AddBytes(station []byte) {
+
+	hash := fnvOffset
+	for _, character := range station {
+		hash ^= uint64(character)
+		hash *= fnvPrime
+	}
+	hash = mix(hash)
+
+	register := hash >> (64 - hyperLogLog.precision)
+	rank := uint8(bits.LeadingZeros64(hash<<hyperLogLog.precision|1<<(hyperLogLog.precision-1))) + 1
+
+	if rank > hyperLogLog.registers[register] {
+		hyperLogLog.registers[register] = rank
+	}
+}
+
+// Add - Counts a station name
+func (hyperLogLog *HyperLogLog) Add(station string) {
+	hyperLogLog.AddBytes([]byte(station))
+}
+
+// mix - The finaliser of MurmurHash3, spreading FNV's weak low bits over the whole hash
+func mix(hash uint64) uint64 {
+
+	hash ^= hash >> 33
+	hash *= 0xff51afd7ed558ccd
+	hash ^= hash >> 33
+	hash *= 0xc4ceb9fe1a85ec53
+	hash ^= hash >> 33
+
+	return hash
+}
+
+// Merge - Folds another sketch into this one, leaving this one as if it had seen every name both had. Both must share
+// the same precision.
+func (hyperLogLog *HyperLogLog) Merge(other *HyperLogLog) error {
+
+	if other.precision != hyperLogLog.precision {
+		return fmt.Errorf("cannot merge a precision %v sketch into a precision %v one", other.precision, hyperLogLog.precision)
+	}
+
+	for index, rank := range other.registers {
+		hyperLogLog.registers[index] = max(hyperLogLog.registers[index], rank)
+	}
+
+	return nil
+}
+
+// Estimate - The estimated number of distinct names. Small counts, where registers are still empty, fall back to
+// linear counting, which is close to exact for a few thousand stations.
+func (hyperLogLog *HyperLogLog) Estimate() float64 {
+
+	registerCount := float64(len(hyperLogLog.registers))
+
+	var harmonicSum float64
+	var emptyRegisters int
+	for _, rank := range hyperLogLog.registers {
+		harmonicSum += math.Ldexp(1, -int(rank))
+		if rank == 0 {
+			emptyRegisters++
+		}
+	}
+
+	alpha := 0.7213 / (1 + 1.079/registerCount)
+	switch len(hyperLogLog.registers) {
+	case 16:
+		alpha = 0.673
+	case 32:
+		alpha = 0.697
+	case 64:
+		alpha = 0.709
+	}
+
+	estimate := alpha * registerCount * registerCount / harmonicSum
+	if estimate <= 2.5*registerCount && emptyRegisters > 0 {
+		return registerCount * math.Log(registerCount/float64(emptyRegisters))
+	}
+
+	return estimate
+}
+
+// StandardError - The relative standard error of the estimate at this precision
+func (hyperLogLog *HyperLogLog) StandardError() float64 {
+	return 1.04 / math.Sqrt(float64(len(hyperLogLog.registers)))
+}
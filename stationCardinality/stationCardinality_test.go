@@ -0,0 +1,105 @@
+package stationcardinality
+
+import (
+	multireader "billionRowChallenge/multiReader"
+	"bytes"
+	"fmt"
+	"math"
+	"slices"
+	"testing"
+)
+
+func TestNewHyperLogLog(t *testing.T) {
+
+	for _, precision := range []int{MinPrecision - 1, MaxPrecision + 1, -1} {
+		if _, err := NewHyperLogLog(precision); err == nil {
+			t.Errorf("NewHyperLogLog(%v) succeeded", precision)
+		}
+	}
+	if sketch, err := NewHyperLogLog(DefaultPrecision); err != nil || sketch.Estimate() != 0 {
+		t.Errorf("empty sketch estimates %v, %v", sketch.Estimate(), err)
+	}
+}
+
+func TestEstimateWithinError(t *testing.T) {
+
+	for _, precision := range []int{MinPrecision, 10, DefaultPrecision} {
+		for _, distinct := range []int{10, 1_000, 50_000, 500_000} {
+			sketch, _ := NewHyperLogLog(precision)
+			for station := range distinct {
+				// Every name read a few times, which must not change the estimate
+				name := fmt.Sprintf("Station %v", station)
+				for range 1 + station%3 {
+					sketch.Add(name)
+				}
+			}
+
+			// Four standard errors, and linear counting is near exact while most registers are still empty
+			bound := 4 * sketch.StandardError() * float64(distinct)
+			if estimate := sketch.Estimate(); math.Abs(estimate-float64(distinct)) > max(bound, 1) {
+				t.Errorf("precision %v estimates %v for %v stations, expected within %.0f", precision, estimate, distinct, bound)
+			}
+		}
+	}
+}
+
+func TestMergeEqualsSinglePass(t *testing.T) {
+
+	whole, _ := NewHyperLogLog(12)
+	var partials = make([]*HyperLogLog, 5)
+	for index := range partials {
+		partials[index], _ = NewHyperLogLog(12)
+	}
+	for station := range 20_000 {
+		name := fmt.Sprintf("Station %v", station)
+		whole.Add(name)
+		partials[station*station%len(partials)].Add(name)
+		partials[station%len(partials)].Add(name) // Counted twice over, as a station read within two sections is
+	}
+
+	merged, _ := NewHyperLogLog(12)
+	for _, partial := range partials {
+		if err := merged.Merge(partial); err != nil {
+			t.Fatalf("Merge: %v", err)
+		}
+	}
+	if !slices.Equal(merged.registers, whole.registers) {
+		t.Error("merged partials differ from a single pass")
+	}
+
+	other, _ := NewHyperLogLog(13)
+	if err := merged.Merge(other); err == nil {
+		t.Error("merging sketches of different precisions succeeded")
+	}
+}
+
+func TestCountStations(t *testing.T) {
+
+	var data []byte
+	whole, _ := NewHyperLogLog(DefaultPrecision)
+	for row := range 30_000 {
+		name := fmt.Sprintf("Station %v", row*7919%4_000)
+		data = fmt.Appendf(data, "%v;%.1f\n", name, float64(row%1999-999)/10)
+		whole.Add(name)
+	}
+
+	reader := bytes.NewReader(data)
+	for _, sectionCount := range []int{1, 4, 32} {
+		boundaries, err := multireader.PlanSections(reader, 0, int64(len(data)), sectionCount)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		sketch, rows, err := CountStations(reader, boundaries, DefaultPrecision)
+		if err != nil {
+			t.Fatalf("CountStations: %v", err)
+		}
+		if rows != 30_000 || !slices.Equal(sketch.registers, whole.registers) {
+			t.Errorf("%v sections scanned %v rows into a different sketch to a single pass", sectionCount, rows)
+		}
+	}
+
+	if _, _, err := CountStations(reader, []int64{0, int64(len(data))}, MaxPrecision+1); err == nil {
+		t.Error("CountStations accepted a precision out of range")
+	}
+}
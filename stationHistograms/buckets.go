@@ -0,0 +1,164 @@
+package stationhistograms
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Limits on the buckets a spec can ask for
+const (
+	MaxBuckets       = 2000
+	defaultWidthFrom = -1000 // Tenths, so fixed width buckets cover every valid reading by default
+	defaultWidthTo   = 1000
+	defaultLogStart  = 10
+	logLimit         = 1000    // Log-scale edges stop once they pass 100 degrees either way
+	maxLogSteps      = 100_000 // Growth steps a log-scale factor may take to reach the limit
+)
+
+// lookupMin - Readings from here through -lookupMin are bucketed with a table rather than a search
+const lookupMin = -999
+
+// Buckets - The edges between histogram buckets, in tenths of a degree. Bucket 0 holds everything below the first edge,
+// bucket i holds `[Edges[i-1], Edges[i])`, and the final bucket holds everything from the last edge upward.
+type Buckets struct {
+	Edges  []int
+	lookup []int32 // Bucket of every reading within the challenge range
+}
+
+// NewBuckets - Buckets between the given edges, which must be strictly increasing
+func NewBuckets(edges []int) (*Buckets, error) {
+
+	if len(edges) == 0 {
+		return nil, errors.New("a histogram needs at least one edge")
+	}
+	if len(edges)+1 > MaxBuckets {
+		return nil, fmt.Errorf("%v buckets is more than the %v allowed", len(edges)+1, MaxBuckets)
+	}
+	for index := 1; index < len(edges); index++ {
+		if edges[index] <= edges[index-1] {
+			return nil, errors.New("histogram edges must be strictly increasing")
+		}
+	}
+
+	buckets := &Buckets{Edges: edges, lookup: make([]int32, -2*lookupMin+1)}
+	for index := range buckets.lookup {
+		buckets.lookup[index] = int32(buckets.search(index + lookupMin))
+	}
+
+	return buckets, nil
+}
+
+// ParseSpec - Builds the buckets out of a spec, with every number in degrees:
+//   - `width:5` is buckets 5 degrees wide from -100 to 100, and `width:5,-50,50` sets the range
+//   - `edges:-10,0,10,20` is explicit edges
+//   - `log:2` is buckets growing by a factor of 2 away from zero in both directions, beginning at 1 degree, and
+//     `log:2,0.5` sets where they begin
+func ParseSpec(spec string) (*Buckets, error) {
+
+	kind, arguments, _ := strings.Cut(spec, ":")
+
+	// Degrees as given, only rounded to tenths where they stand for a temperature, so a factor keeps its precision
+	var degrees []float64
+	var values []int
+	for _, argument := range strings.Split(arguments, ",") {
+		if argument == "" {
+			continue
+		}
+		value, err := strconv.ParseFloat(strings.TrimSpace(argument), 64)
+		if err != nil || math.IsInf(value, 0) || math.IsNaN(value) {
+			return nil, fmt.Errorf("histogram spec %q holds %q, which is not a number", spec, argument)
+		}
+		degrees = append(degrees, value)
+		values = append(values, int(math.Round(value*10)))
+	}
+
+	switch kind {
+	case "width":
+		if len(values) != 1 && len(values) != 3 {
+			return nil, fmt.Errorf("histogram spec %q expects width:W or width:W,FROM,TO", spec)
+		}
+		width, from, to := values[0], defaultWidthFrom, defaultWidthTo
+		if len(values) == 3 {
+			from, to = values[1], values[2]
+		}
+		if width <= 0 || to <= from {
+			return nil, fmt.Errorf("histogram spec %q needs a positive width and FROM below TO", spec)
+		}
+		if (to-from)/width+1 > MaxBuckets {
+			return nil, fmt.Errorf("histogram spec %q makes more than the %v buckets allowed", spec, MaxBuckets)
+		}
+
+		var edges []int
+		for edge := from; edge <= to; edge += width {
+			edges = append(edges, edge)
+		}
+		return NewBuckets(edges)
+
+	case "edges":
+		return NewBuckets(values)
+
+	case "log":
+		if len(values) != 1 && len(values) != 2 {
+			return nil, fmt.Errorf("histogram spec %q expects log:FACTOR or log:FACTOR,START", spec)
+		}
+		factor := degrees[0]
+		start := defaultLogStart
+		if len(values) == 2 {
+			start = values[1]
+		}
+		if factor <= 1 || start <= 0 {
+			return nil, fmt.Errorf("histogram spec %q needs a factor above 1 and a positive start", spec)
+		}
+		if math.Log(float64(logLimit)/float64(start))/math.Log(factor) > maxLogSteps {
+			return nil, fmt.Errorf("histogram spec %q has a factor too close to 1 to reach 100 degrees", spec)
+		}
+
+		// Edges away from zero, rounded to tenths, skipping any that round onto the previous one
+		var magnitudes []int
+		for magnitude := float64(start); ; magnitude *= factor {
+			edge := int(math.Round(magnitude))
+			if len(magnitudes) == 0 || edge > magnitudes[len(magnitudes)-1] {
+				magnitudes = append(magnitudes, edge)
+			}
+			if edge >= logLimit {
+				break
+			}
+		}
+
+		var edges = make([]int, 0, 2*len(magnitudes)+1)
+		for index := len(magnitudes) - 1; index >= 0; index-- {
+			edges = append(edges, -magnitudes[index])
+		}
+		edges = append(edges, 0)
+		edges = append(edges, magnitudes...)
+		return NewBuckets(edges)
+	}
+
+	return nil, fmt.Errorf("unknown histogram spec %q, expected width:, edges:, or log:", spec)
+}
+
+// Count - Number of buckets
+func (buckets *Buckets) Count() int {
+	return len(buckets.Edges) + 1
+}
+
+// Index - The bucket the reading falls into
+func (buckets *Buckets) Index(tenths int) int {
+	if offset := tenths - lookupMin; offset >= 0 && offset < len(buckets.lookup) {
+		return int(buckets.lookup[offset])
+	}
+	return buckets.search(tenths)
+}
+
+// search - Number of edges at or below the reading
+func (buckets *Buckets) search(tenths int) int {
+	index, found := slices.BinarySearch(buckets.Edges, tenths)
+	if found {
+		return index + 1
+	}
+	return index
+}
@@ -0,0 +1,164 @@
+package stationhistograms
+
+import (
+	resultsnapshot "billionRowChallenge/resultSnapshot"
+	"billionRowChallenge/utilities"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"slices"
+)
+
+// SnapshotSectionTag - Tag of the snapshot section holding the histograms
+const SnapshotSectionTag = 3
+
+var errSectionTruncated = errors.New("histogram section is truncated")
+
+func init() {
+	resultsnapshot.SectionMergers[SnapshotSectionTag] = mergeSections
+}
+
+// Histograms - A histogram of every station's readings, all sharing the same buckets
+type Histograms struct {
+	Buckets  *Buckets
+	Stations map[string][]int // Count of readings within each bucket
+}
+
+// NewCollector - Makes empty histograms over the buckets, ready to be handed to the section reader
+func NewCollector(buckets *Buckets) func() utilities.Collector {
+	return func() utilities.Collector {
+		return Histograms{Buckets: buckets, Stations: make(map[string][]int)}
+	}
+}
+
+// Add - Counts a single reading within the station's histogram
+func (histograms Histograms) Add(station string, temperature int) {
+
+	counts, ok := histograms.Stations[station]
+	if !ok {
+		counts = make([]int, histograms.Buckets.Count())
+		histograms.Stations[station] = counts
+	}
+	counts[histograms.Buckets.Index(temperature)]++
+}
+
+// Merge - Folds the histograms collected by another section into these. Both must share the same buckets.
+func (histograms Histograms) Merge(other utilities.Collector) {
+	for station, otherCounts := range other.(Histograms).Stations {
+		counts, ok := histograms.Stations[station]
+		if !ok {
+			histograms.Stations[station] = otherCounts
+			continue
+		}
+		for index, count := range otherCounts {
+			counts[index] += count
+		}
+	}
+}
+
+// Section - The histograms laid out as a snapshot section: the edge count and each edge, then the station count, and
+// for every station sorted by name, its name followed by the count within each bucket
+func (histograms Histograms) Section() resultsnapshot.Section {
+
+	stations := make([]string, 0, len(histograms.Stations))
+	for station := range histograms.Stations {
+		stations = append(stations, station)
+	}
+	slices.Sort(stations)
+
+	var data []byte
+	data = binary.AppendUvarint(data, uint64(len(histograms.Buckets.Edges)))
+	for _, edge := range histograms.Buckets.Edges {
+		data = binary.AppendVarint(data, int64(edge))
+	}
+
+	data = binary.AppendUvarint(data, uint64(len(stations)))
+	for _, station := range stations {
+		data = binary.AppendUvarint(data, uint64(len(station)))
+		data = append(data, station...)
+		for _, count := range histograms.Stations[station] {
+			data = binary.AppendUvarint(data, uint64(count))
+		}
+	}
+
+	return resultsnapshot.Section{Tag: SnapshotSectionTag, Data: data}
+}
+
+// FromSection - Reads the histograms back out of a snapshot section
+func FromSection(data []byte) (Histograms, error) {
+
+	var position int
+	var failed bool
+	readUvarint := func() uint64 {
+		value, size := binary.Uvarint(data[position:])
+		if size <= 0 {
+			failed = true
+			return 0
+		}
+		position += size
+		return value
+	}
+
+	edgeCount := readUvarint()
+	if failed || edgeCount > uint64(len(data)) {
+		return Histograms{}, errSectionTruncated
+	}
+	var edges = make([]int, edgeCount)
+	for index := range edges {
+		edge, size := binary.Varint(data[position:])
+		if size <= 0 {
+			return Histograms{}, errSectionTruncated
+		}
+		position += size
+		edges[index] = int(edge)
+	}
+
+	buckets, err := NewBuckets(edges)
+	if err != nil {
+		return Histograms{}, err
+	}
+	histograms := Histograms{Buckets: buckets, Stations: make(map[string][]int)}
+
+	stationCount := readUvarint()
+	for range stationCount {
+		nameLength := readUvarint()
+		if failed || nameLength > uint64(len(data)-position) {
+			return Histograms{}, errSectionTruncated
+		}
+		station := string(data[position : position+int(nameLength)])
+		position += int(nameLength)
+
+		counts := make([]int, buckets.Count())
+		for index := range counts {
+			counts[index] = int(readUvarint())
+		}
+		if failed {
+			return Histograms{}, errSectionTruncated
+		}
+		histograms.Stations[station] = counts
+	}
+
+	if failed {
+		return Histograms{}, errSectionTruncated
+	}
+	return histograms, nil
+}
+
+// mergeSections - Combines the histogram sections of two snapshots being merged, which must share the same buckets
+func mergeSections(first []byte, second []byte) ([]byte, error) {
+
+	firstHistograms, err := FromSection(first)
+	if err != nil {
+		return nil, err
+	}
+	secondHistograms, err := FromSection(second)
+	if err != nil {
+		return nil, err
+	}
+	if !slices.Equal(firstHistograms.Buckets.Edges, secondHistograms.Buckets.Edges) {
+		return nil, fmt.Errorf("%w: the histograms have different buckets", resultsnapshot.ErrIncompatible)
+	}
+
+	firstHistograms.Merge(secondHistograms)
+	return firstHistograms.Section().Data, nil
+}
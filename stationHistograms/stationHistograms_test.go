@@ -0,0 +1,177 @@
+package stationhistograms
+
+import (
+	resultsnapshot "billionRowChallenge/resultSnapshot"
+	"billionRowChallenge/utilities"
+	"errors"
+	"maps"
+	"math/rand/v2"
+	"slices"
+	"testing"
+)
+
+// testHistograms - Histograms over the buckets of a few stations, with the same readings dealt into partials
+func testHistograms(t *testing.T, buckets *Buckets, partialCount int) (Histograms, []Histograms) {
+
+	t.Helper()
+	random := rand.New(rand.NewPCG(7, 8))
+	collector := NewCollector(buckets)
+
+	whole := collector().(Histograms)
+	var partials = make([]Histograms, partialCount)
+	for index := range partials {
+		partials[index] = collector().(Histograms)
+	}
+
+	for index := range 30_000 {
+		station := []string{"Abha", "Zürich", "Accra"}[random.IntN(3)]
+		reading := random.IntN(2401) - 1200
+		whole.Add(station, reading)
+		partials[index%partialCount].Add(station, reading)
+	}
+
+	return whole, partials
+}
+
+func TestParseSpec(t *testing.T) {
+
+	tests := []struct {
+		spec  string
+		edges []int
+	}{
+		{"width:5", nil},
+		{"width:5,-10,10", []int{-100, -50, 0, 50, 100}},
+		{"width:2.5,0,6", []int{0, 25, 50}},
+		{"edges:-10,0,10.5", []int{-100, 0, 105}},
+		{"edges: -1.25, 3", []int{-13, 30}},
+		{"log:10", []int{-1000, -100, -10, 0, 10, 100, 1000}},
+		{"log:10,0.5", []int{-5000, -500, -50, -5, 0, 5, 50, 500, 5000}},
+		{"log:2,25", []int{-1000, -500, -250, 0, 250, 500, 1000}},
+	}
+
+	for _, test := range tests {
+		buckets, err := ParseSpec(test.spec)
+		if err != nil {
+			t.Errorf("ParseSpec(%q): %v", test.spec, err)
+			continue
+		}
+		if test.edges != nil && !slices.Equal(buckets.Edges, test.edges) {
+			t.Errorf("ParseSpec(%q) edges = %v, expected %v", test.spec, buckets.Edges, test.edges)
+		}
+		if !slices.IsSorted(buckets.Edges) || buckets.Count() != len(buckets.Edges)+1 {
+			t.Errorf("ParseSpec(%q) edges %v are out of order", test.spec, buckets.Edges)
+		}
+	}
+
+	// The default range covers every valid reading
+	buckets, _ := ParseSpec("width:5")
+	if buckets.Edges[0] != -1000 || buckets.Edges[len(buckets.Edges)-1] != 1000 || buckets.Count() != 42 {
+		t.Errorf("width:5 edges = %v", buckets.Edges)
+	}
+}
+
+func TestParseSpecErrors(t *testing.T) {
+
+	for _, spec := range []string{
+		"", "width", "width:", "width:0", "width:-5", "width:5,10,-10", "width:5,1", "width:0.01,-1000,1000",
+		"edges:", "edges:1,1", "edges:2,1", "edges:x", "edges:NaN", "edges:Inf",
+		"log:1", "log:0.5", "log:2,0", "log:2,-1", "log:1.0000001", "log:2,1,1",
+		"buckets:1",
+	} {
+		if buckets, err := ParseSpec(spec); err == nil {
+			t.Errorf("ParseSpec(%q) = %v, expected an error", spec, buckets.Edges)
+		}
+	}
+}
+
+func TestIndexMatchesSearch(t *testing.T) {
+
+	for _, spec := range []string{"width:5", "edges:0", "edges:-99.9,99.9", "log:2,0.1", "edges:-500,500"} {
+		buckets, err := ParseSpec(spec)
+		if err != nil {
+			t.Fatalf("ParseSpec(%q): %v", spec, err)
+		}
+
+		for tenths := -5_000; tenths <= 5_000; tenths++ {
+			// Number of edges at or below the reading, counted one by one
+			var expected int
+			for _, edge := range buckets.Edges {
+				if edge <= tenths {
+					expected++
+				}
+			}
+			if index := buckets.Index(tenths); index != expected {
+				t.Fatalf("%v: Index(%v) = %v, expected %v", spec, tenths, index, expected)
+			}
+		}
+	}
+}
+
+func TestMergeEqualsSinglePass(t *testing.T) {
+
+	buckets, _ := ParseSpec("width:7.5")
+	whole, partials := testHistograms(t, buckets, 4)
+
+	merged := NewCollector(buckets)()
+	for _, partial := range partials {
+		merged.Merge(partial)
+	}
+	if !maps.EqualFunc(merged.(Histograms).Stations, whole.Stations, slices.Equal) {
+		t.Error("merged partials differ from a single pass")
+	}
+}
+
+func TestSectionRoundTrip(t *testing.T) {
+
+	buckets, _ := ParseSpec("log:2")
+	histograms, _ := testHistograms(t, buckets, 1)
+
+	section := histograms.Section()
+	decoded, err := FromSection(section.Data)
+	if err != nil {
+		t.Fatalf("FromSection: %v", err)
+	}
+	if !slices.Equal(decoded.Buckets.Edges, buckets.Edges) {
+		t.Errorf("decoded edges = %v, expected %v", decoded.Buckets.Edges, buckets.Edges)
+	}
+	if !maps.EqualFunc(decoded.Stations, histograms.Stations, slices.Equal) {
+		t.Error("decoded counts differ from the counts encoded")
+	}
+
+	for length := range len(section.Data) {
+		if _, err := FromSection(section.Data[:length]); err == nil {
+			t.Fatalf("section cut to %v of %v bytes was accepted", length, len(section.Data))
+		}
+	}
+}
+
+func TestSnapshotMerge(t *testing.T) {
+
+	buckets, _ := ParseSpec("edges:-20,0,20")
+	whole, partials := testHistograms(t, buckets, 2)
+
+	var snapshots []resultsnapshot.Snapshot
+	for _, partial := range partials {
+		snapshot := resultsnapshot.New(map[string]utilities.OutputValues{})
+		snapshot.Sections = []resultsnapshot.Section{partial.Section()}
+		snapshots = append(snapshots, snapshot)
+	}
+
+	merged, err := resultsnapshot.Merge(snapshots[0], snapshots[1])
+	if err != nil {
+		t.Fatalf("Merge: %v", err)
+	}
+	data, _ := merged.FindSection(SnapshotSectionTag)
+	decoded, err := FromSection(data)
+	if err != nil || !maps.EqualFunc(decoded.Stations, whole.Stations, slices.Equal) {
+		t.Errorf("merged section differs from a single pass: %v", err)
+	}
+
+	// Histograms over other buckets cannot be added together
+	otherBuckets, _ := ParseSpec("edges:-20,0,25")
+	other, _ := testHistograms(t, otherBuckets, 1)
+	snapshots[1].Sections = []resultsnapshot.Section{other.Section()}
+	if _, err = resultsnapshot.Merge(snapshots[0], snapshots[1]); !errors.Is(err, resultsnapshot.ErrIncompatible) {
+		t.Errorf("merging different buckets = %v, expected %v", err, resultsnapshot.ErrIncompatible)
+	}
+}
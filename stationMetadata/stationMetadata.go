@@ -0,0 +1,198 @@
+package stationmetadata
+
+import (
+	"billionRowChallenge/utilities"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Rollup levels, from the finest to the coarsest
+const (
+	LevelStation = "station"
+	LevelRegion  = "region"
+	LevelCountry = "country"
+)
+
+// Levels - Levels the results can be rolled up to
+var Levels = []string{LevelStation, LevelRegion, LevelCountry}
+
+// Group names used when a station cannot be placed
+const (
+	UnknownCountry = "(unknown)"   // Stations missing from the metadata
+	NoRegion       = "(no region)" // Stations whose metadata leaves the region empty
+)
+
+// Station - What the metadata knows about a single station
+type Station struct {
+	Name           string
+	Country        string
+	Region         string
+	Latitude       float64
+	Longitude      float64
+	HasCoordinates bool
+}
+
+// Metadata - Every station within the metadata file, keyed by name
+type Metadata map[string]Station
+
+// columnNames - Header names accepted for each column, compared without case
+var columnNames = map[string][]string{
+	"station":   {"station", "name"},
+	"country":   {"country"},
+	"region":    {"region"},
+	"latitude":  {"latitude", "lat"},
+	"longitude": {"longitude", "lon", "lng"},
+}
+
+// Load - Reads the metadata file, a csv with a header row naming its columns. The station and country columns are
+// required, the region and coordinates are optional, and any other columns are ignored.
+func Load(path string) (Metadata, error) {
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	metadata, err := Read(file)
+	if err != nil {
+		return nil, fmt.Errorf("%v: %w", path, err)
+	}
+	return metadata, nil
+}
+
+// Read - Reads the metadata csv out of the reader, the same as `Load`
+func Read(reader io.Reader) (Metadata, error) {
+
+	csvReader := csv.NewReader(reader)
+	csvReader.FieldsPerRecord = -1
+
+	header, err := csvReader.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, errors.New("metadata is empty")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var columns = map[string]int{}
+	for index, name := range header {
+		name = strings.ToLower(strings.TrimSpace(name))
+		for column, accepted := range columnNames {
+			if _, taken := columns[column]; !taken && slices.Contains(accepted, name) {
+				columns[column] = index
+			}
+		}
+	}
+	for _, required := range []string{"station", "country"} {
+		if _, ok := columns[required]; !ok {
+			return nil, fmt.Errorf("metadata header has no %v column", required)
+		}
+	}
+
+	field := func(record []string, column string) string {
+		if index, ok := columns[column]; ok && index < len(record) {
+			return strings.TrimSpace(record[index])
+		}
+		return ""
+	}
+
+	var metadata = Metadata{}
+	for {
+		record, err := csvReader.Read()
+		if errors.Is(err, io.EOF) {
+			return metadata, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		line, _ := csvReader.FieldPos(0)
+		station := Station{
+			Name:    field(record, "station"),
+			Country: field(record, "country"),
+			Region:  field(record, "region"),
+		}
+		if station.Name == "" || station.Country == "" {
+			return nil, fmt.Errorf("line %v is missing its station or country", line)
+		}
+		if _, duplicate := metadata[station.Name]; duplicate {
+			return nil, fmt.Errorf("line %v repeats station %q", line, station.Name)
+		}
+
+		latitude, longitude := field(record, "latitude"), field(record, "longitude")
+		if latitude != "" || longitude != "" {
+			if station.Latitude, err = strconv.ParseFloat(latitude, 64); err != nil || station.Latitude < -90 || station.Latitude > 90 {
+				return nil, fmt.Errorf("line %v has latitude %q, expected -90 to 90", line, latitude)
+			}
+			if station.Longitude, err = strconv.ParseFloat(longitude, 64); err != nil || station.Longitude < -180 || station.Longitude > 180 {
+				return nil, fmt.Errorf("line %v has longitude %q, expected -180 to 180", line, longitude)
+			}
+			station.HasCoordinates = true
+		}
+
+		metadata[station.Name] = station
+	}
+}
+
+// Group - The rollup group a station falls in at the level. Regions are named along with their country, as the same
+// region name can be used within different countries.
+func (metadata Metadata) Group(station string, level string) string {
+
+	stationMetadata, ok := metadata[station]
+
+	switch level {
+	case LevelCountry:
+		if !ok {
+			return UnknownCountry
+		}
+		return stationMetadata.Country
+	case LevelRegion:
+		if !ok {
+			return UnknownCountry + "/" + NoRegion
+		}
+		region := stationMetadata.Region
+		if region == "" {
+			region = NoRegion
+		}
+		return stationMetadata.Country + "/" + region
+	}
+
+	return station
+}
+
+// Rollup - Combines the station results into a result per group at the level. Groups are combined the same as
+// sections are, taking the min of the mins, the max of the maxes, and summing the totals and counts, so the mean of a
+// group weighs every reading equally rather than averaging the station means, and rolling up regions into countries
+// gives the same result as rolling up the stations directly. Stations missing from the metadata are kept within the
+// unknown group, so the groups always account for every reading, and are returned sorted.
+func (metadata Metadata) Rollup(outputMap map[string]utilities.OutputValues, level string) (map[string]utilities.OutputValues, []string, error) {
+
+	if !slices.Contains(Levels, level) {
+		return nil, nil, fmt.Errorf("unknown rollup level %q, expected one of %v", level, strings.Join(Levels, ", "))
+	}
+
+	var rollup = make(map[string]utilities.OutputValues)
+	var missing []string
+
+	for station, outputValues := range outputMap {
+		if _, ok := metadata[station]; !ok {
+			missing = append(missing, station)
+		}
+
+		group := metadata.Group(station, level)
+		if existing, ok := rollup[group]; ok {
+			outputValues = utilities.MergeOutputValues(existing, outputValues)
+		}
+		rollup[group] = outputValues
+	}
+
+	slices.Sort(missing)
+	return rollup, missing, nil
+}
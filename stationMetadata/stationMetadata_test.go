@@ -0,0 +1,153 @@
+package stationmetadata
+
+import (
+	"billionRowChallenge/utilities"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+const testMetadata = `Name, Lat ,LNG,Country,Region,Elevation
+Abha,18.2,42.5,Saudi Arabia,Asir,2270
+Jeddah,21.5,39.2,Saudi Arabia,Makkah,12
+Mecca,,,Saudi Arabia,Makkah,
+Zürich,47.4,8.5,Switzerland,,408
+"Washington, D.C.",38.9,-77.0,United States,District of Columbia,
+`
+
+func TestRead(t *testing.T) {
+
+	metadata, err := Read(strings.NewReader(testMetadata))
+	if err != nil {
+		t.Fatalf("Read: %v", err)
+	}
+
+	expected := Metadata{
+		"Abha":             {Name: "Abha", Country: "Saudi Arabia", Region: "Asir", Latitude: 18.2, Longitude: 42.5, HasCoordinates: true},
+		"Jeddah":           {Name: "Jeddah", Country: "Saudi Arabia", Region: "Makkah", Latitude: 21.5, Longitude: 39.2, HasCoordinates: true},
+		"Mecca":            {Name: "Mecca", Country: "Saudi Arabia", Region: "Makkah"},
+		"Zürich":           {Name: "Zürich", Country: "Switzerland", Latitude: 47.4, Longitude: 8.5, HasCoordinates: true},
+		"Washington, D.C.": {Name: "Washington, D.C.", Country: "United States", Region: "District of Columbia", Latitude: 38.9, Longitude: -77, HasCoordinates: true},
+	}
+	if !maps.Equal(metadata, expected) {
+		t.Errorf("metadata = %v, expected %v", metadata, expected)
+	}
+}
+
+func TestReadErrors(t *testing.T) {
+
+	tests := map[string]string{
+		"empty":             "",
+		"no country column": "station,region\nAbha,Asir\n",
+		"no station column": "country\nSaudi Arabia\n",
+		"missing country":   "station,country\nAbha,\n",
+		"repeated station":  "station,country\nAbha,Saudi Arabia\nAbha,Yemen\n",
+		"bad latitude":      "station,country,lat,lon\nAbha,Saudi Arabia,north,42.5\n",
+		"latitude range":    "station,country,lat,lon\nAbha,Saudi Arabia,91,42.5\n",
+		"longitude range":   "station,country,lat,lon\nAbha,Saudi Arabia,18.2,181\n",
+		"half coordinates":  "station,country,lat,lon\nAbha,Saudi Arabia,18.2,\n",
+		"broken csv":        "station,country\n\"Abha,Saudi Arabia\n",
+	}
+
+	for name, data := range tests {
+		if metadata, err := Read(strings.NewReader(data)); err == nil {
+			t.Errorf("%v: Read = %v, expected an error", name, metadata)
+		}
+	}
+}
+
+func TestLoad(t *testing.T) {
+
+	path := filepath.Join(t.TempDir(), "stations.csv")
+	if err := os.WriteFile(path, []byte(testMetadata), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if metadata, err := Load(path); err != nil || len(metadata) != 5 {
+		t.Errorf("Load = %v, %v", metadata, err)
+	}
+
+	if err := os.WriteFile(path, []byte("station\nAbha\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(path); err == nil || !strings.Contains(err.Error(), path) {
+		t.Errorf("Load error %v does not name the file", err)
+	}
+}
+
+func TestGroup(t *testing.T) {
+
+	metadata, _ := Read(strings.NewReader(testMetadata))
+
+	tests := []struct {
+		station  string
+		level    string
+		expected string
+	}{
+		{"Abha", LevelStation, "Abha"},
+		{"Abha", LevelRegion, "Saudi Arabia/Asir"},
+		{"Abha", LevelCountry, "Saudi Arabia"},
+		{"Zürich", LevelRegion, "Switzerland/" + NoRegion},
+		{"Accra", LevelStation, "Accra"},
+		{"Accra", LevelRegion, UnknownCountry + "/" + NoRegion},
+		{"Accra", LevelCountry, UnknownCountry},
+	}
+
+	for _, test := range tests {
+		if group := metadata.Group(test.station, test.level); group != test.expected {
+			t.Errorf("Group(%q, %v) = %q, expected %q", test.station, test.level, group, test.expected)
+		}
+	}
+}
+
+func TestRollup(t *testing.T) {
+
+	metadata, _ := Read(strings.NewReader(testMetadata))
+	outputMap := map[string]utilities.OutputValues{
+		"Abha":   {Min: -10, Max: 300, Total: 600, Count: 4},
+		"Jeddah": {Min: 100, Max: 450, Total: 900, Count: 3},
+		"Mecca":  {Min: 150, Max: 480, Total: 310, Count: 1},
+		"Zürich": {Min: -150, Max: 300, Total: 100, Count: 2},
+		"Accra":  {Min: 200, Max: 350, Total: 270, Count: 1},
+		"Zagreb": {Min: -50, Max: 380, Total: 80, Count: 1},
+	}
+
+	countries, missing, err := metadata.Rollup(outputMap, LevelCountry)
+	if err != nil {
+		t.Fatalf("Rollup: %v", err)
+	}
+	expected := map[string]utilities.OutputValues{
+		"Saudi Arabia": {Min: -10, Max: 480, Total: 1810, Count: 8},
+		"Switzerland":  {Min: -150, Max: 300, Total: 100, Count: 2},
+		UnknownCountry: {Min: -50, Max: 380, Total: 350, Count: 2},
+	}
+	if !maps.Equal(countries, expected) {
+		t.Errorf("countries = %v, expected %v", countries, expected)
+	}
+	if !slices.Equal(missing, []string{"Accra", "Zagreb"}) {
+		t.Errorf("missing = %v, expected Accra and Zagreb", missing)
+	}
+
+	// Rolling the regions up into their countries gives the same as rolling up the stations directly
+	regions, _, _ := metadata.Rollup(outputMap, LevelRegion)
+	fromRegions := map[string]utilities.OutputValues{}
+	for region, outputValues := range regions {
+		country, _, _ := strings.Cut(region, "/")
+		if existing, ok := fromRegions[country]; ok {
+			outputValues = utilities.MergeOutputValues(existing, outputValues)
+		}
+		fromRegions[country] = outputValues
+	}
+	if !maps.Equal(fromRegions, countries) {
+		t.Errorf("regions rolled up into countries = %v, expected %v", fromRegions, countries)
+	}
+
+	if stations, _, _ := metadata.Rollup(outputMap, LevelStation); !maps.Equal(stations, outputMap) {
+		t.Errorf("station rollup = %v, expected the results unchanged", stations)
+	}
+	if _, _, err := metadata.Rollup(outputMap, "continent"); err == nil {
+		t.Error("Rollup accepted an unknown level")
+	}
+}
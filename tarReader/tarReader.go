@@ -0,0 +1,94 @@
+package tarreader
+
+import (
+	"archive/tar"
+	multireader "billionRowChallenge/multiReader"
+	"billionRowChallenge/utilities"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+)
+
+const DefaultMemberPattern = "*.csv" // Members are matched on their base name
+
+var gzipMagic = []byte{0x1f, 0x8b}
+
+// MemberResult - The aggregate for a single member within the archive
+type MemberResult struct {
+	Name      string
+	Size      int64
+	OutputMap map[string]utilities.OutputValues
+}
+
+// ArchiveResult - Per member results, in the order they appear within the archive, along with everything combined
+type ArchiveResult struct {
+	Members   []MemberResult
+	OutputMap map[string]utilities.OutputValues
+}
+
+// AggregateArchive - Walks every member of a `.tar` archive, optionally gzip compressed, and aggregates each regular
+// file whose base name matches the pattern. An empty pattern matches every regular file. The archive is read a single
+// time, straight through, without extracting anything to disk.
+func AggregateArchive(reader io.Reader, pattern string) (ArchiveResult, error) {
+
+	var result = ArchiveResult{OutputMap: make(map[string]utilities.OutputValues)}
+
+	archiveReader, err := decompressIfNeeded(reader)
+	if err != nil {
+		return result, err
+	}
+
+	tarReader := tar.NewReader(archiveReader)
+
+	for {
+		header, err := tarReader.Next()
+		if errors.Is(err, io.EOF) {
+			return result, nil
+		} else if err != nil {
+			return result, err
+		}
+
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+		if pattern != "" {
+			if matched, err := path.Match(pattern, path.Base(header.Name)); err != nil {
+				return result, err
+			} else if !matched {
+				continue
+			}
+		}
+
+		memberMap, _, err := multireader.AggregateStream(tarReader)
+		if err != nil {
+			return result, fmt.Errorf("%v: %w", header.Name, err)
+		}
+
+		result.Members = append(result.Members, MemberResult{
+			Name:      header.Name,
+			Size:      header.Size,
+			OutputMap: memberMap,
+		})
+		utilities.MergeOutputMaps(result.OutputMap, memberMap)
+	}
+}
+
+// decompressIfNeeded - Peeks at the leading bytes and wraps the reader with gzip when the archive is compressed
+func decompressIfNeeded(reader io.Reader) (io.Reader, error) {
+
+	bufferedReader := bufio.NewReader(reader)
+
+	leadingBytes, err := bufferedReader.Peek(len(gzipMagic))
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+
+	if bytes.Equal(leadingBytes, gzipMagic) {
+		return gzip.NewReader(bufferedReader)
+	}
+	return bufferedReader, nil
+}
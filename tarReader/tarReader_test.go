@@ -0,0 +1,132 @@
+package tarreader
+
+import (
+	"archive/tar"
+	"billionRowChallenge/parsers"
+	"billionRowChallenge/utilities"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"maps"
+	"testing"
+)
+
+type testMember struct {
+	name     string
+	typeflag byte
+	data     string
+}
+
+// testArchive - Lays the members out as a tar archive, gzip compressed when asked
+func testArchive(t *testing.T, members []testMember, compressed bool) []byte {
+
+	var archive bytes.Buffer
+	var tarWriter *tar.Writer
+	var gzipWriter *gzip.Writer
+	if compressed {
+		gzipWriter = gzip.NewWriter(&archive)
+		tarWriter = tar.NewWriter(gzipWriter)
+	} else {
+		tarWriter = tar.NewWriter(&archive)
+	}
+
+	for _, member := range members {
+		header := &tar.Header{Name: member.name, Typeflag: member.typeflag, Mode: 0o644, Size: int64(len(member.data))}
+		if err := tarWriter.WriteHeader(header); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tarWriter.Write([]byte(member.data)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := tarWriter.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if gzipWriter != nil {
+		if err := gzipWriter.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return archive.Bytes()
+}
+
+func TestAggregateArchive(t *testing.T) {
+
+	var january, february string
+	for row := range 1_000 {
+		january += fmt.Sprintf("Station %v;%.1f\n", row%13, float64(row%199-99)/10)
+		february += fmt.Sprintf("Station %v;%.1f\n", row%17, float64(row%299-149)/10)
+	}
+
+	members := []testMember{
+		{"data/", tar.TypeDir, ""},
+		{"data/january.csv", tar.TypeReg, january},
+		{"data/README.md", tar.TypeReg, "Not measurements\n"},
+		{"data/february.csv", tar.TypeReg, february + "Abha;1.5"}, // The final row of a member needs no newline
+		{"data/latest.csv", tar.TypeSymlink, ""},
+	}
+
+	expectedMember := func(rows string) map[string]utilities.OutputValues {
+		outputMap := make(map[string]utilities.OutputValues)
+		parsers.ParseRows([]byte(rows), outputMap)
+		return outputMap
+	}
+	januaryMap, februaryMap := expectedMember(january), expectedMember(february+"Abha;1.5\n")
+	combined := maps.Clone(januaryMap)
+	utilities.MergeOutputMaps(combined, februaryMap)
+
+	for _, compressed := range []bool{false, true} {
+		t.Run(fmt.Sprintf("compressed %v", compressed), func(t *testing.T) {
+			result, err := AggregateArchive(bytes.NewReader(testArchive(t, members, compressed)), DefaultMemberPattern)
+			if err != nil {
+				t.Fatalf("AggregateArchive: %v", err)
+			}
+
+			if len(result.Members) != 2 || result.Members[0].Name != "data/january.csv" || result.Members[1].Name != "data/february.csv" {
+				t.Fatalf("members = %v, expected january.csv then february.csv", result.Members)
+			}
+			if !maps.Equal(result.Members[0].OutputMap, januaryMap) || !maps.Equal(result.Members[1].OutputMap, februaryMap) {
+				t.Error("member results differ from the rows within them")
+			}
+			if result.Members[0].Size != int64(len(january)) {
+				t.Errorf("january.csv is %v bytes, expected %v", result.Members[0].Size, len(january))
+			}
+			if !maps.Equal(result.OutputMap, combined) {
+				t.Error("combined result differs from the members merged together")
+			}
+		})
+	}
+
+	// An empty pattern takes every regular file, so the readme is read as rows, none of which are valid
+	result, err := AggregateArchive(bytes.NewReader(testArchive(t, members, false)), "")
+	if err != nil {
+		t.Fatalf("AggregateArchive: %v", err)
+	}
+	if len(result.Members) != 3 || len(result.Members[1].OutputMap) != 0 {
+		t.Errorf("members = %v, expected all three regular files", result.Members)
+	}
+}
+
+func TestAggregateArchiveErrors(t *testing.T) {
+
+	archive := testArchive(t, []testMember{{"january.csv", tar.TypeReg, "Abha;1.0\n"}}, true)
+
+	tests := []struct {
+		name    string
+		data    []byte
+		pattern string
+	}{
+		{"truncated", archive[:len(archive)/2], DefaultMemberPattern},
+		{"bad pattern", archive, "["},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := AggregateArchive(bytes.NewReader(test.data), test.pattern); err == nil {
+				t.Error("AggregateArchive succeeded")
+			}
+		})
+	}
+}
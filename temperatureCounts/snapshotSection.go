@@ -0,0 +1,117 @@
+package temperaturecounts
+
+import (
+	resultsnapshot "billionRowChallenge/resultSnapshot"
+	"encoding/binary"
+	"errors"
+	"math"
+	"slices"
+)
+
+// SnapshotSectionTag - Tag of the snapshot section holding the counts
+const SnapshotSectionTag = 1
+
+var errSectionTruncated = errors.New("temperature counts section is truncated")
+var errCountOverflow = errors.New("temperature counts section holds more readings than a station can count")
+
+func init() {
+	resultsnapshot.SectionMergers[SnapshotSectionTag] = mergeSections
+}
+
+// Section - The counts laid out as a snapshot section: the station count, then for every station sorted by name, its
+// name, the number of distinct values, and each value with its count
+func (counts Counts) Section() resultsnapshot.Section {
+
+	stations := make([]string, 0, len(counts))
+	for station := range counts {
+		stations = append(stations, station)
+	}
+	slices.Sort(stations)
+
+	var data []byte
+	data = binary.AppendUvarint(data, uint64(len(stations)))
+	for _, station := range stations {
+		stationCounts := counts[station]
+
+		data = binary.AppendUvarint(data, uint64(len(station)))
+		data = append(data, station...)
+		data = binary.AppendUvarint(data, uint64(stationCounts.Distinct()))
+		stationCounts.Each(func(tenths int, count int) {
+			data = binary.AppendVarint(data, int64(tenths))
+			data = binary.AppendUvarint(data, uint64(count))
+		})
+	}
+
+	return resultsnapshot.Section{Tag: SnapshotSectionTag, Data: data}
+}
+
+// FromSection - Reads the counts back out of a snapshot section
+func FromSection(data []byte) (Counts, error) {
+
+	var position int
+	readUvarint := func() (uint64, error) {
+		value, size := binary.Uvarint(data[position:])
+		if size <= 0 {
+			return 0, errSectionTruncated
+		}
+		position += size
+		return value, nil
+	}
+
+	stationCount, err := readUvarint()
+	if err != nil {
+		return nil, err
+	}
+
+	var counts = make(Counts, min(stationCount, uint64(len(data))))
+	for range stationCount {
+		nameLength, err := readUvarint()
+		if err != nil || nameLength > uint64(len(data)-position) {
+			return nil, errSectionTruncated
+		}
+		station := string(data[position : position+int(nameLength)])
+		position += int(nameLength)
+
+		distinct, err := readUvarint()
+		if err != nil {
+			return nil, err
+		}
+
+		stationCounts := &StationCounts{}
+		for range distinct {
+			tenths, size := binary.Varint(data[position:])
+			if size <= 0 {
+				return nil, errSectionTruncated
+			}
+			position += size
+
+			count, err := readUvarint()
+			if err != nil {
+				return nil, err
+			}
+			if count > uint64(math.MaxInt-stationCounts.Total()) {
+				return nil, errCountOverflow
+			}
+			stationCounts.addCount(int(tenths), count)
+		}
+		counts[station] = stationCounts
+	}
+
+	return counts, nil
+}
+
+// mergeSections - Combines the counts sections of two snapshots being merged
+func mergeSections(first []byte, second []byte) ([]byte, error) {
+
+	firstCounts, err := FromSection(first)
+	if err != nil {
+		return nil, err
+	}
+	secondCounts, err := FromSection(second)
+	if err != nil {
+		return nil, err
+	}
+
+	firstCounts.Merge(secondCounts)
+	return firstCounts.Section().Data, nil
+}
@@ -0,0 +1,249 @@
+package temperaturecounts
+
+import (
+	"billionRowChallenge/utilities"
+	"math"
+	"slices"
+)
+
+// Readings fall between -99.9 and 99.9, so there are only 1,999 possible values in tenths
+const (
+	MinTenths  = -999
+	MaxTenths  = 999
+	ValueCount = MaxTenths - MinTenths + 1
+)
+
+// sparseLimit - Distinct values a station holds within its sparse map before it moves over to the dense array. Past
+// this point the 8KB array is smaller than the map.
+const sparseLimit = 256
+
+// StationCounts - How many times each temperature was read at a single station. Rare stations only keep the values
+// they have seen, while busy stations keep a count for every possible value. Readings outside of the challenge range
+// always stay within the sparse map, so the counts are exact whatever the data holds.
+//
+// Each count a section collects into its dense array is 32 bits, which holds over 4 billion readings of the same value
+// at the same station while keeping the array small. Merged stations move over to 64 bit counts, the same as the total,
+// so combining busy sections can never wrap a count.
+type StationCounts struct {
+	total  int
+	dense  []uint32       // Indexed by `tenths - MinTenths`, nil until the station moves over
+	wide   []uint64       // The dense array once widened by a merge, after which `dense` is nil
+	sparse map[int]uint64 // Values outside of the dense array
+}
+
+// Add - Counts a single reading
+func (counts *StationCounts) Add(tenths int) {
+	counts.addCount(tenths, 1)
+}
+
+// addCount - Adds a number of readings of the same value, moving over to the dense array once the station has seen
+// enough distinct values. A 32 bit count that would wrap is widened first.
+func (counts *StationCounts) addCount(tenths int, count uint64) {
+
+	counts.total += int(count)
+
+	if tenths >= MinTenths && tenths <= MaxTenths {
+		if counts.dense != nil && uint64(counts.dense[tenths-MinTenths])+count > math.MaxUint32 {
+			counts.widen()
+		}
+		if counts.dense != nil {
+			counts.dense[tenths-MinTenths] += uint32(count)
+			return
+		}
+		if counts.wide != nil {
+			counts.wide[tenths-MinTenths] += count
+			return
+		}
+	}
+
+	if counts.sparse == nil {
+		counts.sparse = make(map[int]uint64)
+	}
+	counts.sparse[tenths] += count
+
+	if len(counts.sparse) > sparseLimit {
+		counts.makeDense()
+	}
+}
+
+// makeDense - Moves every in-range value out of the sparse map and into the dense array, going straight to the 64 bit
+// array when a count is already too large for 32 bits
+func (counts *StationCounts) makeDense() {
+
+	if counts.dense != nil || counts.wide != nil {
+		return
+	}
+
+	for _, valueCount := range counts.sparse {
+		if valueCount > math.MaxUint32 {
+			counts.widen()
+			return
+		}
+	}
+
+	counts.dense = make([]uint32, ValueCount)
+	for value, valueCount := range counts.sparse {
+		if value >= MinTenths && value <= MaxTenths {
+			counts.dense[value-MinTenths] = uint32(valueCount)
+			delete(counts.sparse, value)
+		}
+	}
+}
+
+// widen - Moves every in-range value over to the 64 bit dense array, out of the 32 bit array and the sparse map
+func (counts *StationCounts) widen() {
+
+	if counts.wide != nil {
+		return
+	}
+
+	counts.wide = make([]uint64, ValueCount)
+	for index, valueCount := range counts.dense {
+		counts.wide[index] = uint64(valueCount)
+	}
+	counts.dense = nil
+
+	for value, valueCount := range counts.sparse {
+		if value >= MinTenths && value <= MaxTenths {
+			counts.wide[value-MinTenths] += valueCount
+			delete(counts.sparse, value)
+		}
+	}
+}
+
+// denseCount - The count held at the index of whichever dense array the station is using
+func (counts *StationCounts) denseCount(index int) uint64 {
+
+	if counts.wide != nil {
+		return counts.wide[index]
+	}
+	return uint64(counts.dense[index])
+}
+
+// Merge - Adds every reading of the other station into this one. Dense arrays are widened and summed straight across.
+func (counts *StationCounts) Merge(other *StationCounts) {
+
+	if other.dense != nil || other.wide != nil {
+		counts.widen()
+		for index := range counts.wide {
+			valueCount := other.denseCount(index)
+			counts.wide[index] += valueCount
+			counts.total += int(valueCount)
+		}
+	}
+
+	for value, valueCount := range other.sparse {
+		counts.addCount(value, valueCount)
+	}
+}
+
+// Total - Number of readings counted
+func (counts *StationCounts) Total() int {
+	return counts.total
+}
+
+// Each - Calls `yield` for every value that was read, lowest first, along with how many times it was read
+func (counts *StationCounts) Each(yield func(tenths int, count int)) {
+
+	var sparseValues = make([]int, 0, len(counts.sparse))
+	for value := range counts.sparse {
+		sparseValues = append(sparseValues, value)
+	}
+	slices.Sort(sparseValues)
+
+	// Only one of the two dense arrays is ever set, so their lengths add up to the one in use
+	var next int // Next sparse value to hand out
+	for index := range len(counts.dense) + len(counts.wide) {
+		valueCount := counts.denseCount(index)
+		for next < len(sparseValues) && sparseValues[next] < index+MinTenths {
+			yield(sparseValues[next], int(counts.sparse[sparseValues[next]]))
+			next++
+		}
+		if valueCount > 0 {
+			yield(index+MinTenths, int(valueCount))
+		}
+	}
+	for ; next < len(sparseValues); next++ {
+		yield(sparseValues[next], int(counts.sparse[sparseValues[next]]))
+	}
+}
+
+// Mode - The most frequently read value along with how many times it was read, the lowest value winning a tie
+func (counts *StationCounts) Mode() (tenths int, count int) {
+
+	counts.Each(func(value int, valueCount int) {
+		if valueCount > count {
+			tenths, count = value, valueCount
+		}
+	})
+
+	return tenths, count
+}
+
+// Distinct - Number of different values read
+func (counts *StationCounts) Distinct() int {
+
+	var distinct int
+	counts.Each(func(tenths int, count int) { distinct++ })
+
+	return distinct
+}
+
+// Percentiles - The exact value at each percentile, using the nearest rank: the smallest value that at least p% of
+// the readings are less than or equal to. Percentiles must be given in ascending order. The 50th percentile is the
+// lower median when the number of readings is even.
+func (counts *StationCounts) Percentiles(percentiles ...float64) []int {
+
+	var values = make([]int, len(percentiles))
+	if counts.total == 0 {
+		return values
+	}
+
+	var ranks = make([]int, len(percentiles))
+	for index, percentile := range percentiles {
+		// Multiplied before dividing, so whole percentiles of whole counts never pick up a rounding error
+		ranks[index] = max(int(math.Ceil(percentile*float64(counts.total)/100)), 1)
+	}
+
+	var seen int
+	var next int
+	counts.Each(func(tenths int, count int) {
+		seen += count
+		for next < len(ranks) && ranks[next] <= seen {
+			values[next] = tenths
+			next++
+		}
+	})
+
+	return values
+}
+
+// Counts - Temperature counts for every station, collected alongside the output values
+type Counts map[string]*StationCounts
+
+// NewCollector - Empty counts, ready to be handed to the section reader
+func NewCollector() utilities.Collector {
+	return Counts{}
+}
+
+// Add - Counts a single reading at the station
+func (counts Counts) Add(station string, temperature int) {
+
+	stationCounts, ok := counts[station]
+	if !ok {
+		stationCounts = &StationCounts{}
+		counts[station] = stationCounts
+	}
+	stationCounts.Add(temperature)
+}
+
+// Merge - Folds the counts collected by another section into these
+func (counts Counts) Merge(other utilities.Collector) {
+	for station, otherCounts := range other.(Counts) {
+		if stationCounts, ok := counts[station]; ok {
+			stationCounts.Merge(otherCounts)
+		} else {
+			counts[station] = otherCounts
+		}
+	}
+}
@@ -0,0 +1,316 @@
+package temperaturecounts
+
+import (
+	resultsnapshot "billionRowChallenge/resultSnapshot"
+	"billionRowChallenge/utilities"
+	"bytes"
+	"encoding/binary"
+	"math"
+	"math/rand/v2"
+	"slices"
+	"testing"
+)
+
+// testReadings - Readings for a few kinds of station: one that stays sparse, one that turns dense, and one with readings
+// outside of the challenge range that must stay sparse either way
+func testReadings() map[string][]int {
+
+	random := rand.New(rand.NewPCG(3, 4))
+	readings := map[string][]int{}
+
+	for range 50 {
+		readings["Sparse"] = append(readings["Sparse"], random.IntN(40)-20)
+	}
+	for range 20_000 {
+		readings["Dense"] = append(readings["Dense"], random.IntN(ValueCount)+MinTenths)
+	}
+	for index := range 5_000 {
+		value := random.IntN(ValueCount) + MinTenths
+		if index%50 == 0 {
+			value = []int{-5000, 1000, 123_456}[index%3]
+		}
+		readings["Out of range"] = append(readings["Out of range"], value)
+	}
+
+	return readings
+}
+
+// pairs - Every value with its count, in the order `Each` hands them out
+func pairs(counts *StationCounts) [][2]int {
+
+	var valueCounts [][2]int
+	counts.Each(func(tenths int, count int) {
+		valueCounts = append(valueCounts, [2]int{tenths, count})
+	})
+	return valueCounts
+}
+
+// expectedPairs - Every value with its count, worked out by sorting the readings
+func expectedPairs(readings []int) [][2]int {
+
+	sorted := slices.Clone(readings)
+	slices.Sort(sorted)
+
+	var valueCounts [][2]int
+	for _, value := range sorted {
+		if last := len(valueCounts) - 1; last >= 0 && valueCounts[last][0] == value {
+			valueCounts[last][1]++
+		} else {
+			valueCounts = append(valueCounts, [2]int{value, 1})
+		}
+	}
+	return valueCounts
+}
+
+// sameCounts - Whether both hold the same readings of the same stations
+func sameCounts(first Counts, second Counts) bool {
+
+	if len(first) != len(second) {
+		return false
+	}
+	for station, stationCounts := range first {
+		other, ok := second[station]
+		if !ok || stationCounts.Total() != other.Total() || !slices.Equal(pairs(stationCounts), pairs(other)) {
+			return false
+		}
+	}
+	return true
+}
+
+func TestEachAndPercentiles(t *testing.T) {
+
+	percentiles := []float64{0, 1, 25, 50, 75, 90, 99, 99.9, 100}
+
+	for station, readings := range testReadings() {
+		t.Run(station, func(t *testing.T) {
+			var counts StationCounts
+			for _, reading := range readings {
+				counts.Add(reading)
+			}
+
+			if !slices.Equal(pairs(&counts), expectedPairs(readings)) {
+				t.Error("Each differs from the sorted readings")
+			}
+			if counts.Total() != len(readings) {
+				t.Errorf("Total = %v, expected %v", counts.Total(), len(readings))
+			}
+
+			// Nearest rank over the sorted readings
+			sorted := slices.Clone(readings)
+			slices.Sort(sorted)
+			values := counts.Percentiles(percentiles...)
+			for index, percentile := range percentiles {
+				rank := max(int(math.Ceil(percentile*float64(len(sorted))/100)), 1)
+				if values[index] != sorted[rank-1] {
+					t.Errorf("p%v = %v, expected %v", percentile, values[index], sorted[rank-1])
+				}
+			}
+		})
+	}
+}
+
+func TestPercentilesOfFewReadings(t *testing.T) {
+
+	var counts StationCounts
+	if values := counts.Percentiles(50); values[0] != 0 {
+		t.Errorf("median of nothing = %v, expected 0", values[0])
+	}
+
+	for _, reading := range []int{30, 10, 20, 40} {
+		counts.Add(reading)
+	}
+	if values := counts.Percentiles(25, 50, 75, 100); !slices.Equal(values, []int{10, 20, 30, 40}) {
+		t.Errorf("quartiles = %v, expected the lower median at 50", values)
+	}
+}
+
+func TestModeAndDistinct(t *testing.T) {
+
+	var counts StationCounts
+	if tenths, count := counts.Mode(); tenths != 0 || count != 0 || counts.Distinct() != 0 {
+		t.Errorf("Mode of nothing = %v, %v, distinct %v", tenths, count, counts.Distinct())
+	}
+
+	for _, reading := range []int{5, -3, 5, -3, 7} {
+		counts.Add(reading)
+	}
+	if tenths, count := counts.Mode(); tenths != -3 || count != 2 {
+		t.Errorf("Mode = %v, %v, expected the lower of the tied values, -3 read twice", tenths, count)
+	}
+
+	for station, readings := range testReadings() {
+		var stationCounts StationCounts
+		for _, reading := range readings {
+			stationCounts.Add(reading)
+		}
+
+		// The first of the most read values in ascending order
+		expected := expectedPairs(readings)
+		var mode [2]int
+		for _, valueCount := range expected {
+			if valueCount[1] > mode[1] {
+				mode = valueCount
+			}
+		}
+
+		if tenths, count := stationCounts.Mode(); tenths != mode[0] || count != mode[1] {
+			t.Errorf("%v: Mode = %v, %v, expected %v, %v", station, tenths, count, mode[0], mode[1])
+		}
+		if distinct := stationCounts.Distinct(); distinct != len(expected) {
+			t.Errorf("%v: Distinct = %v, expected %v", station, distinct, len(expected))
+		}
+	}
+}
+
+func TestMergeEqualsSinglePass(t *testing.T) {
+
+	readings := testReadings()
+
+	whole := Counts{}
+	for station, stationReadings := range readings {
+		for _, reading := range stationReadings {
+			whole.Add(station, reading)
+		}
+	}
+
+	// Dealt out unevenly, so some partials stay sparse while others turn dense
+	for _, partialCount := range []int{2, 3, 16} {
+		var partials = make([]Counts, partialCount)
+		for index := range partials {
+			partials[index] = Counts{}
+		}
+		for station, stationReadings := range readings {
+			for index, reading := range stationReadings {
+				partials[(index*index)%partialCount].Add(station, reading)
+			}
+		}
+
+		merged := NewCollector()
+		for _, partial := range partials {
+			merged.Merge(partial)
+		}
+		if !sameCounts(merged.(Counts), whole) {
+			t.Errorf("%v merged partials differ from a single pass", partialCount)
+		}
+	}
+}
+
+func TestMergeWidensCounts(t *testing.T) {
+
+	// Enough distinct values to move both stations over to the dense array, then one value counted to the 32 bit limit
+	var first, second StationCounts
+	for _, counts := range []*StationCounts{&first, &second} {
+		for tenths := range sparseLimit + 1 {
+			counts.Add(tenths)
+		}
+		counts.addCount(5, math.MaxUint32-1)
+	}
+	if first.dense == nil || first.wide != nil {
+		t.Fatal("a section's counts did not stay within the 32 bit dense array")
+	}
+
+	first.Merge(&second)
+	if first.wide == nil || first.dense != nil {
+		t.Fatal("merged counts were not widened to 64 bits")
+	}
+	if tenths, count := first.Mode(); tenths != 5 || count != 2*math.MaxUint32 {
+		t.Errorf("Mode = %v, %v, expected 5, %v", tenths, count, 2*math.MaxUint32)
+	}
+	if total := first.Total(); total != 2*(sparseLimit+math.MaxUint32) {
+		t.Errorf("Total = %v, expected %v", total, 2*(sparseLimit+math.MaxUint32))
+	}
+
+	// A section count that would wrap is widened rather than lost
+	second.Add(5)
+	if second.wide == nil {
+		t.Error("a count past 32 bits was not widened")
+	}
+	if _, count := second.Mode(); count != math.MaxUint32+1 {
+		t.Errorf("Mode count = %v, expected %v", count, math.MaxUint32+1)
+	}
+}
+
+func TestSectionRoundTrip(t *testing.T) {
+
+	counts := Counts{}
+	for station, readings := range testReadings() {
+		for _, reading := range readings {
+			counts.Add(station, reading)
+		}
+	}
+
+	section := counts.Section()
+	if section.Tag != SnapshotSectionTag {
+		t.Errorf("section tag %v, expected %v", section.Tag, SnapshotSectionTag)
+	}
+
+	decoded, err := FromSection(section.Data)
+	if err != nil {
+		t.Fatalf("FromSection: %v", err)
+	}
+	if !sameCounts(decoded, counts) {
+		t.Error("decoded counts differ from the counts encoded")
+	}
+	if !bytes.Equal(decoded.Section().Data, section.Data) {
+		t.Error("encoding the decoded counts gave different bytes")
+	}
+
+	for length := range len(section.Data) {
+		if _, err := FromSection(section.Data[:length]); err == nil {
+			t.Fatalf("section cut to %v of %v bytes was accepted", length, len(section.Data))
+		}
+	}
+}
+
+func TestFromSectionRejectsOverflow(t *testing.T) {
+
+	// One station holding two values, which between them count more readings than an int can hold
+	var data []byte
+	data = binary.AppendUvarint(data, 1)
+	data = binary.AppendUvarint(data, 4)
+	data = append(data, "Abha"...)
+	data = binary.AppendUvarint(data, 2)
+	for _, tenths := range []int64{1, 2} {
+		data = binary.AppendVarint(data, tenths)
+		data = binary.AppendUvarint(data, math.MaxInt64/2+1)
+	}
+
+	if _, err := FromSection(data); err == nil {
+		t.Error("a section overflowing the total was accepted")
+	}
+}
+
+func TestSnapshotMergeEqualsSinglePass(t *testing.T) {
+
+	readings := testReadings()
+
+	whole := Counts{}
+	firstHalf, secondHalf := Counts{}, Counts{}
+	firstMap, secondMap := map[string]utilities.OutputValues{}, map[string]utilities.OutputValues{}
+	for station, stationReadings := range readings {
+		for index, reading := range stationReadings {
+			whole.Add(station, reading)
+			if index%2 == 0 {
+				firstHalf.Add(station, reading)
+				utilities.AddTemperature(firstMap, station, reading)
+			} else {
+				secondHalf.Add(station, reading)
+				utilities.AddTemperature(secondMap, station, reading)
+			}
+		}
+	}
+
+	first, second := resultsnapshot.New(firstMap), resultsnapshot.New(secondMap)
+	first.Sections = []resultsnapshot.Section{firstHalf.Section()}
+	second.Sections = []resultsnapshot.Section{secondHalf.Section()}
+
+	merged, err := resultsnapshot.Merge(first, second)
+	if err != nil {
+		t.Fatalf("Merge: %v", err)
+	}
+
+	data, _ := merged.FindSection(SnapshotSectionTag)
+	if !bytes.Equal(data, whole.Section().Data) {
+		t.Error("merged section differs from the section of a single pass")
+	}
+}
@@ -0,0 +1,93 @@
+package timebuckets
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	_ "time/tzdata" // Time zones are found by name even on systems without a zone database
+)
+
+// BucketSizes - Sizes the readings can be grouped by
+var BucketSizes = []string{"hour", "day", "month"}
+
+// TimestampFormats - Named formats a timestamp can be read with. Any other Go layout can be given as `layout:<layout>`.
+var TimestampFormats = []string{"rfc3339", "epoch-s", "epoch-ms"}
+
+// Options - How the timestamps are read and grouped
+type Options struct {
+	Bucket     string         // One of `BucketSizes`
+	Location   *time.Location // Time zone the buckets begin within, so a day runs from local midnight to midnight
+	Format     string         // One of `TimestampFormats`, or `layout:` followed by a Go layout
+	TimeColumn int            // Column holding the timestamp, 2 or 3, with the temperature in the other
+}
+
+// DefaultOptions - Daily buckets in UTC, with an RFC3339 timestamp following the temperature
+func DefaultOptions() Options {
+	return Options{Bucket: "day", Location: time.UTC, Format: "rfc3339", TimeColumn: 3}
+}
+
+// Check - Reports the first option that cannot be used
+func (options Options) Check() error {
+
+	switch options.Bucket {
+	case "hour", "day", "month":
+	default:
+		return fmt.Errorf("unknown bucket %q, expected one of %v", options.Bucket, strings.Join(BucketSizes, ", "))
+	}
+
+	switch {
+	case options.Format == "rfc3339", options.Format == "epoch-s", options.Format == "epoch-ms":
+	case strings.HasPrefix(options.Format, "layout:") && len(options.Format) > len("layout:"):
+	default:
+		return fmt.Errorf("unknown timestamp format %q, expected one of %v, or layout:<go layout>", options.Format,
+			strings.Join(TimestampFormats, ", "))
+	}
+
+	if options.TimeColumn != 2 && options.TimeColumn != 3 {
+		return fmt.Errorf("the timestamp must be in column 2 or 3, not %v", options.TimeColumn)
+	}
+	if options.Location == nil {
+		return fmt.Errorf("a time zone is needed")
+	}
+
+	return nil
+}
+
+// parseTimestamp - Reads the timestamp field. Layouts without a zone of their own are read within the options' zone.
+func (options Options) parseTimestamp(field string) (time.Time, error) {
+
+	switch options.Format {
+	case "rfc3339":
+		return time.Parse(time.RFC3339, field)
+	case "epoch-s", "epoch-ms":
+		value, err := strconv.ParseInt(field, 10, 64)
+		if err != nil {
+			return time.Time{}, err
+		}
+		if options.Format == "epoch-ms" {
+			return time.UnixMilli(value), nil
+		}
+		return time.Unix(value, 0), nil
+	}
+
+	return time.ParseInLocation(strings.TrimPrefix(options.Format, "layout:"), field, options.Location)
+}
+
+// bucketStart - The beginning of the bucket holding the instant, going by the wall clock of the options' zone. Hours
+// are found by dropping the minutes and seconds rather than rebuilding the date, so the repeated hour when the clocks
+// go back stays as two separate buckets.
+func (options Options) bucketStart(instant time.Time) time.Time {
+
+	local := instant.In(options.Location)
+
+	switch options.Bucket {
+	case "hour":
+		return local.Add(-time.Duration(local.Minute())*time.Minute - time.Duration(local.Second())*time.Second -
+			time.Duration(local.Nanosecond()))
+	case "day":
+		return time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, options.Location)
+	}
+
+	return time.Date(local.Year(), local.Month(), 1, 0, 0, 0, 0, options.Location)
+}
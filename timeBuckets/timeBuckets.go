@@ -0,0 +1,170 @@
+package timebuckets
+
+import (
+	multireader "billionRowChallenge/multiReader"
+	"billionRowChallenge/output"
+	"billionRowChallenge/parsers"
+	"billionRowChallenge/utilities"
+	"bytes"
+	"io"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Series - The readings of every station grouped by the Unix second each bucket begins at. Each bucket keeps the same
+// min, max, total, and count as the plain results.
+type Series map[string]map[int64]utilities.OutputValues
+
+// add - Adds a single reading into the station's bucket
+func (series Series) add(station string, start int64, temperature int) {
+
+	buckets, ok := series[station]
+	if !ok {
+		buckets = make(map[int64]utilities.OutputValues)
+		series[station] = buckets
+	}
+
+	reading := utilities.OutputValues{Min: temperature, Max: temperature, Total: temperature, Count: 1}
+	if outputValues, ok := buckets[start]; ok {
+		reading = utilities.MergeOutputValues(outputValues, reading)
+	}
+	buckets[start] = reading
+}
+
+// Merge - Folds another series into this one
+func (series Series) Merge(other Series) {
+	for station, otherBuckets := range other {
+		buckets, ok := series[station]
+		if !ok {
+			series[station] = otherBuckets
+			continue
+		}
+		for start, outputValues := range otherBuckets {
+			if existing, ok := buckets[start]; ok {
+				outputValues = utilities.MergeOutputValues(existing, outputValues)
+			}
+			buckets[start] = outputValues
+		}
+	}
+}
+
+// TimeSeries - The series laid out for the output, every station's buckets in time order beginning within the zone
+func (series Series) TimeSeries(location *time.Location) output.TimeSeries {
+
+	var timeSeries = make(output.TimeSeries, len(series))
+	for station, buckets := range series {
+		starts := make([]int64, 0, len(buckets))
+		for start := range buckets {
+			starts = append(starts, start)
+		}
+		slices.Sort(starts)
+
+		var stationBuckets = make([]output.TimeBucket, len(starts))
+		for index, start := range starts {
+			stationBuckets[index] = output.TimeBucket{Start: time.Unix(start, 0).In(location), Values: buckets[start]}
+		}
+		timeSeries[station] = stationBuckets
+	}
+
+	return timeSeries
+}
+
+// parseRows - Adds every complete `station;temperature;timestamp` row, or `station;timestamp;temperature` with the
+// timestamp in column 2, into the series. A trailing `\r` is trimmed, the same as the line server does. Rows missing a
+// field, with a temperature `ParseTemperature` cannot read, or with a timestamp that cannot be read are counted as
+// skipped. Returns the bytes consumed, ending directly after the final newline.
+func (options Options) parseRows(byteData []byte, series Series, skipped *int) int {
+
+	var consumed int
+	for {
+		lineLength := bytes.IndexByte(byteData[consumed:], utilities.NewLineHex)
+		if lineLength < 0 {
+			return consumed
+		}
+		line := bytes.TrimSuffix(byteData[consumed:consumed+lineLength], []byte{utilities.CarriageReturnHex})
+		consumed += lineLength + 1
+
+		station, fields, found := bytes.Cut(line, []byte{utilities.SemicolonHex})
+		if !found {
+			*skipped++
+			continue
+		}
+		temperatureField, timestampField, found := bytes.Cut(fields, []byte{utilities.SemicolonHex})
+		if !found {
+			*skipped++
+			continue
+		}
+		if options.TimeColumn == 2 {
+			temperatureField, timestampField = timestampField, temperatureField
+		}
+
+		decimal := bytes.IndexByte(temperatureField, utilities.DecimalHex)
+		if decimal < 0 || !parsers.ValidTemperature(temperatureField[:decimal], temperatureField[decimal+1:]) {
+			*skipped++
+			continue
+		}
+		instant, err := options.parseTimestamp(string(timestampField))
+		if err != nil {
+			*skipped++
+			continue
+		}
+
+		series.add(
+			string(station),
+			options.bucketStart(instant).Unix(),
+			parsers.ParseTemperature(temperatureField[:decimal], temperatureField[decimal+1]),
+		)
+	}
+}
+
+// Aggregate - Groups every row between the boundaries by station and time bucket, parsing each section within its own
+// routine. Returns the series, the number of rows skipped as malformed, and the offset directly after the last row.
+func Aggregate(file io.ReaderAt, boundaries []int64, options Options) (Series, int, int64, error) {
+
+	if err := options.Check(); err != nil {
+		return nil, 0, 0, err
+	}
+
+	var waitGroup sync.WaitGroup
+	var sectionSeries = make([]Series, len(boundaries)-1)
+	var sectionSkipped = make([]int, len(boundaries)-1)
+	var sectionConsumed = make([]int64, len(boundaries)-1)
+	var sectionErrors = make([]error, len(boundaries)-1)
+
+	for sectionIndex := range len(boundaries) - 1 {
+		waitGroup.Add(1)
+
+		go func() {
+			defer waitGroup.Done()
+
+			sectionSeries[sectionIndex] = make(Series)
+			sectionConsumed[sectionIndex], sectionErrors[sectionIndex] = multireader.ReadSection(
+				file,
+				boundaries[sectionIndex],
+				boundaries[sectionIndex+1]-boundaries[sectionIndex],
+				func(byteData []byte, bufferOffset int64) int {
+					return options.parseRows(byteData, sectionSeries[sectionIndex], &sectionSkipped[sectionIndex])
+				},
+			)
+		}()
+	}
+
+	waitGroup.Wait()
+
+	var series = make(Series)
+	var skipped int
+	for sectionIndex := range sectionSeries {
+		if sectionErrors[sectionIndex] != nil {
+			return nil, 0, boundaries[0], sectionErrors[sectionIndex]
+		}
+		series.Merge(sectionSeries[sectionIndex])
+		skipped += sectionSkipped[sectionIndex]
+	}
+
+	lastSection := len(boundaries) - 2
+	if lastSection < 0 {
+		return series, skipped, boundaries[0], nil
+	}
+	return series, skipped, boundaries[lastSection] + sectionConsumed[lastSection], nil
+}
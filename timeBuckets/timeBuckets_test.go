@@ -0,0 +1,190 @@
+package timebuckets
+
+import (
+	multireader "billionRowChallenge/multiReader"
+	"billionRowChallenge/utilities"
+	"bytes"
+	"fmt"
+	"maps"
+	"testing"
+	"time"
+)
+
+func TestCheck(t *testing.T) {
+
+	if err := DefaultOptions().Check(); err != nil {
+		t.Errorf("default options: %v", err)
+	}
+
+	for name, change := range map[string]func(options *Options){
+		"bucket":      func(options *Options) { options.Bucket = "week" },
+		"format":      func(options *Options) { options.Format = "epoch-us" },
+		"empty":       func(options *Options) { options.Format = "layout:" },
+		"time column": func(options *Options) { options.TimeColumn = 1 },
+		"location":    func(options *Options) { options.Location = nil },
+	} {
+		options := DefaultOptions()
+		change(&options)
+		if err := options.Check(); err == nil {
+			t.Errorf("options with a bad %v were accepted", name)
+		}
+	}
+}
+
+func TestParseTimestamp(t *testing.T) {
+
+	london, err := time.LoadLocation("Europe/London")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := time.Date(2024, 7, 1, 12, 30, 0, 0, time.UTC)
+
+	tests := map[string]string{
+		"rfc3339":                    "2024-07-01T13:30:00+01:00",
+		"epoch-s":                    "1719837000",
+		"epoch-ms":                   "1719837000000",
+		"layout:2006-01-02 15:04:05": "2024-07-01 13:30:00", // Read within London, an hour ahead in the summer
+	}
+	for format, field := range tests {
+		options := Options{Format: format, Location: london}
+		if instant, err := options.parseTimestamp(field); err != nil || !instant.Equal(expected) {
+			t.Errorf("%v read %q as %v, %v, expected %v", format, field, instant, err, expected)
+		}
+	}
+}
+
+func TestBucketStart(t *testing.T) {
+
+	london, _ := time.LoadLocation("Europe/London")
+	newYork, _ := time.LoadLocation("America/New_York")
+
+	tests := []struct {
+		bucket   string
+		location *time.Location
+		instant  time.Time
+		expected time.Time
+	}{
+		{"hour", time.UTC, time.Date(2024, 3, 5, 7, 59, 59, 999, time.UTC), time.Date(2024, 3, 5, 7, 0, 0, 0, time.UTC)},
+		{"day", newYork, time.Date(2024, 3, 5, 3, 0, 0, 0, time.UTC), time.Date(2024, 3, 4, 0, 0, 0, 0, newYork)},
+		{"month", newYork, time.Date(2024, 3, 1, 3, 0, 0, 0, time.UTC), time.Date(2024, 2, 1, 0, 0, 0, 0, newYork)},
+
+		// The clocks go back at 02:00 BST, so 01:30 is read twice an hour apart, each within its own bucket
+		{"hour", london, time.Date(2024, 10, 27, 0, 30, 0, 0, time.UTC), time.Date(2024, 10, 27, 0, 0, 0, 0, time.UTC)},
+		{"hour", london, time.Date(2024, 10, 27, 1, 30, 0, 0, time.UTC), time.Date(2024, 10, 27, 1, 0, 0, 0, time.UTC)},
+
+		// 22:30 UTC is 23:30 BST, still within the day the clocks go forward, which began at midnight GMT
+		{"day", london, time.Date(2024, 3, 31, 22, 30, 0, 0, time.UTC), time.Date(2024, 3, 31, 0, 0, 0, 0, london)},
+	}
+
+	for _, test := range tests {
+		options := Options{Bucket: test.bucket, Location: test.location}
+		if start := options.bucketStart(test.instant); !start.Equal(test.expected) {
+			t.Errorf("%v bucket of %v in %v starts %v, expected %v", test.bucket, test.instant, test.location, start,
+				test.expected.In(test.location))
+		}
+	}
+}
+
+func TestParseRows(t *testing.T) {
+
+	tests := []struct {
+		name       string
+		row        string
+		timeColumn int
+		expected   int // Temperature in tenths, or 0 when the row should be skipped
+	}{
+		{"plain", "Abha;-12.3;1719837000\n", 3, -123},
+		{"carriage return", "Abha;4.5;1719837000\r\n", 3, 45},
+		{"carriage return after the temperature", "Abha;1719837000;4.5\r\n", 2, 45},
+		{"letters for digits", "Abha;ab.c;1719837000\n", 3, 0},
+		{"three whole digits", "Abha;123.4;1719837000\n", 3, 0},
+		{"no whole digits", "Abha;.5;1719837000\n", 3, 0},
+		{"two decimals", "Abha;1.25;1719837000\n", 3, 0},
+		{"missing timestamp", "Abha;1.2\n", 3, 0},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			options := DefaultOptions()
+			options.Format = "epoch-s"
+			options.TimeColumn = test.timeColumn
+
+			series := Series{}
+			var skipped int
+			if consumed := options.parseRows([]byte(test.row), series, &skipped); consumed != len(test.row) {
+				t.Errorf("parseRows consumed %v bytes, expected %v", consumed, len(test.row))
+			}
+
+			if test.expected == 0 {
+				if skipped != 1 || len(series) != 0 {
+					t.Errorf("parseRows kept %v and skipped %v rows, expected the row to be skipped", series, skipped)
+				}
+				return
+			}
+			for _, outputValues := range series["Abha"] {
+				if skipped != 0 || outputValues.Total != test.expected {
+					t.Errorf("parseRows read %v and skipped %v rows, expected %v", outputValues.Total, skipped, test.expected)
+				}
+			}
+			if len(series["Abha"]) != 1 {
+				t.Errorf("parseRows = %v, expected a single bucket", series)
+			}
+		})
+	}
+}
+
+func TestAggregate(t *testing.T) {
+
+	var data []byte
+	expected := Series{}
+	for row := range 5_000 {
+		station := fmt.Sprintf("Station %v", row%7)
+		temperature := row*7919%1999 - 999
+		instant := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC).Add(time.Duration(row) * 17 * time.Minute)
+
+		data = fmt.Appendf(data, "%v;%.1f;%v\n", station, float64(temperature)/10, instant.Format(time.RFC3339))
+		expected.add(station, time.Date(instant.Year(), instant.Month(), instant.Day(), 0, 0, 0, 0, time.UTC).Unix(), temperature)
+	}
+	data = append(data, "No temperature\nAbha;1;2024-01-01T00:00:00Z\nAbha;1.0;yesterday\n"...)
+	data = append(data, "Abha;1.0;2024-01-01T00:00:00Z"...) // Unterminated, and still counted
+	expected.add("Abha", time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC).Unix(), 10)
+
+	reader := bytes.NewReader(data)
+	for _, sectionCount := range []int{1, 5, 64} {
+		boundaries, err := multireader.PlanSections(reader, 0, int64(len(data)), sectionCount)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		series, skipped, consumed, err := Aggregate(reader, boundaries, DefaultOptions())
+		if err != nil {
+			t.Fatalf("Aggregate: %v", err)
+		}
+		if !maps.EqualFunc(series, expected, maps.Equal[map[int64]utilities.OutputValues]) {
+			t.Errorf("%v sections grouped the rows differently to a row at a time", sectionCount)
+		}
+		if skipped != 3 || consumed != int64(len(data)) {
+			t.Errorf("%v sections skipped %v rows and consumed %v bytes", sectionCount, skipped, consumed)
+		}
+	}
+
+	if _, _, _, err := Aggregate(reader, []int64{0, int64(len(data))}, Options{Bucket: "week"}); err == nil {
+		t.Error("Aggregate accepted options that cannot be used")
+	}
+}
+
+func TestTimeSeries(t *testing.T) {
+
+	series := Series{}
+	series.add("Abha", 7200, 10)
+	series.add("Abha", 0, -10)
+	series.add("Abha", 7200, 30)
+
+	timeSeries := series.TimeSeries(time.UTC)["Abha"]
+	if len(timeSeries) != 2 || timeSeries[0].Start.Unix() != 0 || timeSeries[1].Start.Unix() != 7200 {
+		t.Fatalf("buckets = %v, expected 2 in time order", timeSeries)
+	}
+	if values := timeSeries[1].Values; values != (utilities.OutputValues{Min: 10, Max: 30, Total: 40, Count: 2}) {
+		t.Errorf("second bucket = %+v", values)
+	}
+}
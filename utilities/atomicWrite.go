@@ -0,0 +1,31 @@
+package utilities
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// WriteFileAtomic - Writes the bytes to a temporary file beside the target first and then renames it over the target,
+// so a crash part way through never leaves a half written file behind.
+func WriteFileAtomic(path string, data []byte) error {
+
+	temporaryFile, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(temporaryFile.Name())
+
+	if _, err = temporaryFile.Write(data); err != nil {
+		temporaryFile.Close()
+		return err
+	}
+	if err = temporaryFile.Sync(); err != nil {
+		temporaryFile.Close()
+		return err
+	}
+	if err = temporaryFile.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(temporaryFile.Name(), path)
+}
@@ -0,0 +1,27 @@
+package utilities
+
+// Collector - Keeps extra per-station statistics on top of the output values, such as percentiles. Every section of a
+// file is given its own collector, and the collectors are merged together once the sections are done, the same as
+// the output maps.
+type Collector interface {
+	Add(station string, temperature int) // Records a single reading, in tenths of a degree
+	Merge(other Collector)               // Folds in a collector of the same type
+}
+
+// Collectors - Several collectors fed by the same parse. Merges pair the collectors up by position, so both sides
+// must have been made the same way.
+type Collectors []Collector
+
+// Add - Hands the reading to every collector
+func (collectors Collectors) Add(station string, temperature int) {
+	for _, collector := range collectors {
+		collector.Add(station, temperature)
+	}
+}
+
+// Merge - Merges each collector with its partner from the other side
+func (collectors Collectors) Merge(other Collector) {
+	for index, otherCollector := range other.(Collectors) {
+		collectors[index].Merge(otherCollector)
+	}
+}
@@ -0,0 +1,12 @@
+//go:build !unix
+
+package utilities
+
+import (
+	"os"
+)
+
+// FileInode - Inodes are not exposed on this platform, so the fingerprint relies on the size, time, and prefix hash
+func FileInode(fileInfo os.FileInfo) uint64 {
+	return 0
+}
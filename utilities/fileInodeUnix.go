@@ -0,0 +1,16 @@
+//go:build unix
+
+package utilities
+
+import (
+	"os"
+	"syscall"
+)
+
+// FileInode - Pulls the inode number out of the file information
+func FileInode(fileInfo os.FileInfo) uint64 {
+	if stat, ok := fileInfo.Sys().(*syscall.Stat_t); ok {
+		return uint64(stat.Ino)
+	}
+	return 0
+}
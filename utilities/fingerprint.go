@@ -0,0 +1,62 @@
+package utilities
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"os"
+)
+
+const PrefixHashSize int64 = 1024 * 1024 // The amount of leading bytes hashed into a file fingerprint
+
+// FileFingerprint - Identifies a measurements file at the time something was saved about it. The prefix hash decides if
+// previously read bytes can still be trusted, the other fields let an untouched file skip the hashing altogether.
+type FileFingerprint struct {
+	Size       int64  `json:"size"`
+	ModTime    int64  `json:"modTimeUnixNano"`
+	Inode      uint64 `json:"inode"`
+	PrefixSize int64  `json:"prefixSize"`
+	PrefixHash string `json:"prefixHash"`
+}
+
+// FingerprintFile - Builds the fingerprint for the file, hashing the first `prefixSize` bytes
+func FingerprintFile(file *os.File, fileInfo os.FileInfo, prefixSize int64) (FileFingerprint, error) {
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, io.NewSectionReader(file, 0, prefixSize)); err != nil {
+		return FileFingerprint{}, err
+	}
+
+	return FileFingerprint{
+		Size:       fileInfo.Size(),
+		ModTime:    fileInfo.ModTime().UnixNano(),
+		Inode:      FileInode(fileInfo),
+		PrefixSize: prefixSize,
+		PrefixHash: hex.EncodeToString(hash.Sum(nil)),
+	}, nil
+}
+
+// CheckFingerprint - Compares the file against a saved fingerprint. Returns an empty reason when the file still holds
+// at least `requiredSize` bytes and begins with the same hashed prefix, meaning only bytes were appended since.
+// Otherwise returns the reason the saved information can no longer be trusted.
+func CheckFingerprint(file *os.File, fileInfo os.FileInfo, saved FileFingerprint, requiredSize int64) (string, error) {
+
+	if fileInfo.Size() < requiredSize {
+		return "file is smaller than when it was last read", nil
+	}
+
+	// An untouched file can skip re-hashing the prefix
+	if fileInfo.Size() == saved.Size && fileInfo.ModTime().UnixNano() == saved.ModTime && FileInode(fileInfo) == saved.Inode {
+		return "", nil
+	}
+
+	current, err := FingerprintFile(file, fileInfo, saved.PrefixSize)
+	if err != nil {
+		return "", err
+	}
+	if current.PrefixHash != saved.PrefixHash {
+		return "prefix has changed", nil
+	}
+
+	return "", nil
+}
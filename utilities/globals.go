@@ -4,9 +4,15 @@ const BufferSize int64 = 64 // The amount of bytes that are read into the go rou
 
 const NumberOfReaderRoutines = 4 // The amount of go routines that will be created and ready to read data processed through the reader
 
+const SectionBufferSize int64 = 4 * 1024 * 1024 // The amount of bytes read per pass when a whole section of the file is aggregated at once
+
 const NewLineHex = 0xa
 const SemicolonHex = 0x3b
 const DecimalHex = 0x2e
+const NegativeHex = 0x2d
+const ZeroHex = 0x30
+const NineHex = 0x39
+const CarriageReturnHex = 0xd
 const SemiColonIndex = 0
 const DecimalIndex = 1
 const NewLineIndex = 2
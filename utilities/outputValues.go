@@ -0,0 +1,55 @@
+package utilities
+
+// AddTemperature - Adds a single temperature reading (multiplied by 10) into the output map. Creates a new entry
+// when the city has not been seen yet, otherwise updates the min, max, and running totals.
+func AddTemperature(outputMap map[string]OutputValues, city string, temperature int) {
+
+	// Locate any existing record
+	mapEntry, ok := outputMap[city]
+
+	// No entry exists, then create a new entry into the output map
+	if !ok {
+		outputMap[city] = OutputValues{
+			Min:   temperature,
+			Max:   temperature,
+			Total: temperature,
+			Count: 1,
+		}
+		return
+	}
+
+	// Update the values to track the min, max, and total counts
+	if mapEntry.Min > temperature {
+		mapEntry.Min = temperature
+	} else if mapEntry.Max < temperature {
+		mapEntry.Max = temperature
+	}
+	mapEntry.Total += temperature
+	mapEntry.Count++
+
+	outputMap[city] = mapEntry
+}
+
+// MergeOutputValues - Combines two partial results for the same city. Min of the mins, max of the maxes, and the
+// totals and counts are summed.
+func MergeOutputValues(first OutputValues, second OutputValues) OutputValues {
+	return OutputValues{
+		Min:   min(first.Min, second.Min),
+		Max:   max(first.Max, second.Max),
+		Total: first.Total + second.Total,
+		Count: first.Count + second.Count,
+	}
+}
+
+// MergeOutputMaps - Folds every entry of the source map into the destination map. Used to combine the maps
+// built by each routine into a singular output map.
+func MergeOutputMaps(destination map[string]OutputValues, source map[string]OutputValues) {
+	for city, sourceEntry := range source {
+		destinationEntry, ok := destination[city]
+		if !ok {
+			destination[city] = sourceEntry
+			continue
+		}
+		destination[city] = MergeOutputValues(destinationEntry, sourceEntry)
+	}
+}
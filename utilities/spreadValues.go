@@ -0,0 +1,87 @@
+package utilities
+
+import (
+	"math"
+	"math/big"
+	"math/bits"
+)
+
+// SumOfSquares - 128 bit running total of squared readings. A squared reading in tenths is at most 998,001, so this
+// never overflows no matter how many rows are read.
+type SumOfSquares struct {
+	High uint64
+	Low  uint64
+}
+
+// Add - Adds the square of the reading
+func (sumOfSquares *SumOfSquares) Add(temperature int) {
+	square := uint64(temperature * temperature)
+	sumOfSquares.addParts(0, square)
+}
+
+// Merge - Adds the other running total into this one
+func (sumOfSquares *SumOfSquares) Merge(other SumOfSquares) {
+	sumOfSquares.addParts(other.High, other.Low)
+}
+
+func (sumOfSquares *SumOfSquares) addParts(high uint64, low uint64) {
+	var carry uint64
+	sumOfSquares.Low, carry = bits.Add64(sumOfSquares.Low, low, 0)
+	sumOfSquares.High, _ = bits.Add64(sumOfSquares.High, high, carry)
+}
+
+// Int - The running total as a big integer
+func (sumOfSquares SumOfSquares) Int() *big.Int {
+	value := new(big.Int).SetUint64(sumOfSquares.High)
+	value.Lsh(value, 64)
+	return value.Or(value, new(big.Int).SetUint64(sumOfSquares.Low))
+}
+
+// SpreadValues - The running totals needed for the variance of a station's readings. Kept as exact integers in tenths,
+// so merging partial results from each routine is plain addition and gives exactly the same answer as a single pass.
+type SpreadValues struct {
+	Count        int
+	Total        int
+	SumOfSquares SumOfSquares
+}
+
+// Add - Adds a single reading, in tenths of a degree
+func (spreadValues *SpreadValues) Add(temperature int) {
+	spreadValues.Count++
+	spreadValues.Total += temperature
+	spreadValues.SumOfSquares.Add(temperature)
+}
+
+// Merge - Adds the other partial result into this one
+func (spreadValues *SpreadValues) Merge(other SpreadValues) {
+	spreadValues.Count += other.Count
+	spreadValues.Total += other.Total
+	spreadValues.SumOfSquares.Merge(other.SumOfSquares)
+}
+
+// Variance - Population variance in squared degrees. Worked out as `(n * Σx² - (Σx)²) / n²` with big integers, so
+// there is no cancellation error however many readings there are, and only the final division is rounded.
+func (spreadValues SpreadValues) Variance() float64 {
+
+	if spreadValues.Count == 0 {
+		return 0
+	}
+
+	count := big.NewInt(int64(spreadValues.Count))
+	total := big.NewInt(int64(spreadValues.Total))
+
+	numerator := new(big.Int).Mul(count, spreadValues.SumOfSquares.Int())
+	numerator.Sub(numerator, new(big.Int).Mul(total, total))
+
+	// Tenths squared, so a further 100 in the denominator brings it back to degrees
+	denominator := new(big.Int).Mul(count, count)
+	denominator.Mul(denominator, big.NewInt(100))
+
+	variance, _ := new(big.Rat).SetFrac(numerator, denominator).Float64()
+	return variance
+}
+
+// StandardDeviation - Population standard deviation in degrees
+func (spreadValues SpreadValues) StandardDeviation() float64 {
+	return math.Sqrt(spreadValues.Variance())
+}